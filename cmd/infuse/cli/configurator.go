@@ -4,15 +4,147 @@ import (
 	"os"
 
 	"github.com/jucardi/infuse/config"
+	"github.com/jucardi/infuse/templates/gotmpl"
+	"github.com/jucardi/infuse/templates/handlebars"
+	"github.com/jucardi/infuse/templates/helpers"
+	"github.com/jucardi/infuse/templates/jinja"
+	"github.com/jucardi/infuse/util/loader"
+	"github.com/jucardi/infuse/util/log"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
-// FromCommand sets values to the global configuration by obtaining values from the command flags.
+// FromCommand sets values to the global configuration by obtaining values from the project configuration file, the
+// INFUSE_* environment variables and the command flags, in increasing order of precedence.
 func FromCommand(cmd *cobra.Command) {
 	if verbose := os.Getenv("debug"); verbose == "true" {
+		config.Get().Verbose = true
+	}
+
+	if err := loadConfigFile(cmd); err != nil {
+		log.Errorf("%v", err)
+		os.Exit(-1)
+	}
+	if err := config.LoadEnv(); err != nil {
+		log.Errorf("%v", err)
+		os.Exit(-1)
+	}
+	fromFlags(cmd)
+	loader.JSONNumbers = config.Get().JSONNumbers
+
+	if now := config.Get().Now; now != "" {
+		t, err := helpers.ParseTime(now)
+		if err != nil {
+			log.Errorf("invalid value for 'now', %v", err)
+			os.Exit(-1)
+		}
+		helpers.SetNow(t)
+	}
+
+	for _, plugin := range config.Get().Plugins {
+		if err := helpers.RegisterPlugin(plugin); err != nil {
+			log.Errorf("%v", err)
+			os.Exit(-1)
+		}
+	}
+
+	if err := registerScripts(config.Get().Scripts...); err != nil {
+		log.Errorf("%v", err)
+		os.Exit(-1)
+	}
+
+	if config.Get().Verbose {
 		logrus.SetLevel(logrus.DebugLevel)
 		logrus.Debug("Debug level enabled")
-		config.Get().Verbose = true
 	}
 }
+
+// loadConfigFile loads the configuration file provided by the 'config' flag, or the first .infuse.yaml found
+// starting from the current directory.
+func loadConfigFile(cmd *cobra.Command) error {
+	file, _ := cmd.Flags().GetString("config")
+	if file == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		file = config.Find(cwd)
+	}
+	if file == "" {
+		return nil
+	}
+	logrus.Debugf("Using configuration file %s", file)
+	return config.LoadFile(file)
+}
+
+func fromFlags(cmd *cobra.Command) {
+	c := config.Get()
+	flags := cmd.Flags()
+
+	if flags.Changed("type") {
+		c.DefaultType, _ = flags.GetString("type")
+	}
+	if flags.Changed("definition") {
+		c.Definitions, _ = flags.GetStringArray("definition")
+	}
+	if flags.Changed("definitionsRoot") {
+		c.DefinitionsRoot, _ = flags.GetString("definitionsRoot")
+	}
+	if flags.Changed("pattern") {
+		c.Patterns, _ = flags.GetStringArray("pattern")
+	}
+	if flags.Changed("definitionsDir") {
+		c.DefinitionsDirs, _ = flags.GetStringArray("definitionsDir")
+	}
+	if flags.Changed("lib") {
+		c.Libraries, _ = flags.GetStringArray("lib")
+	}
+	if flags.Changed("libCache") {
+		c.LibCache, _ = flags.GetString("libCache")
+	}
+	if flags.Changed("helpers") {
+		c.Helpers, _ = flags.GetStringArray("helpers")
+	}
+	if flags.Changed("script") {
+		c.Scripts, _ = flags.GetStringArray("script")
+	}
+	if flags.Changed("file") {
+		c.Files, _ = flags.GetStringArray("file")
+	}
+	if flags.Changed("delims") {
+		c.Delims, _ = flags.GetString("delims")
+	}
+	if flags.Changed("strict") {
+		c.Strict, _ = flags.GetBool("strict")
+	}
+	if flags.Changed("strictDefinitions") {
+		c.StrictDefinitions, _ = flags.GetBool("strictDefinitions")
+	}
+	if flags.Changed("now") {
+		c.Now, _ = flags.GetString("now")
+	}
+	if flags.Changed("jsonNumbers") {
+		c.JSONNumbers, _ = flags.GetBool("jsonNumbers")
+	}
+}
+
+// registerScripts registers the functions declared in the given script files as helpers of every template type.
+func registerScripts(files ...string) error {
+	var fns []*helpers.ScriptFunction
+	for _, file := range files {
+		loaded, err := helpers.LoadScript(file)
+		if err != nil {
+			return err
+		}
+		fns = append(fns, loaded...)
+	}
+	if len(fns) == 0 {
+		return nil
+	}
+	for _, manager := range []helpers.IHelpersManager{gotmpl.Helpers(), handlebars.Helpers(), jinja.Helpers()} {
+		if err := helpers.RegisterScript(manager, fns...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
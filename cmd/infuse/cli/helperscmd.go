@@ -0,0 +1,217 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jucardi/infuse/config"
+	"github.com/jucardi/infuse/templates"
+	"github.com/jucardi/infuse/templates/helpers"
+	"github.com/jucardi/infuse/util/log"
+	"github.com/spf13/cobra"
+)
+
+const defaultCategory = "Extensions"
+
+var helpersCmd = &cobra.Command{
+	Use:   "helpers [name]",
+	Short: "Lists the helpers available to the templates, or the details of a helper",
+	Long: `Lists the helpers available to the templates, grouped by category, with their
+signature, description, examples and the template types where they are
+available. If a name is provided, only the details of that helper are printed.
+The helpers of the packs enabled with --helpers are listed as well.
+
+The output format can be 'text', 'json' or 'markdown', so the documentation of the
+helpers can be generated from the binary:
+
+    infuse helpers --format markdown > HELPERS.md`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  listHelpers,
+}
+
+// helperDoc is the documentation of a helper, merged from all the template types where it is available
+type helperDoc struct {
+	Name        string             `json:"name"`
+	Category    string             `json:"category"`
+	Description string             `json:"description,omitempty"`
+	Signature   *helpers.Signature `json:"signature,omitempty"`
+	Examples    []string           `json:"examples,omitempty"`
+	Engines     []string           `json:"engines"`
+}
+
+// Usage returns the helper name followed by its signature, e.g: 'upper(string) string'
+func (d *helperDoc) Usage() string {
+	if d.Signature == nil {
+		return d.Name
+	}
+	return d.Name + d.Signature.String()
+}
+
+func listHelpers(cmd *cobra.Command, args []string) {
+	format, _ := cmd.Flags().GetString("format")
+	docs := collectHelpers(templates.Factory().GetAvailableTypes()...)
+
+	if len(args) > 0 {
+		var found []*helperDoc
+		for _, d := range docs {
+			if d.Name == args[0] {
+				found = append(found, d)
+			}
+		}
+		if len(found) == 0 {
+			log.Errorf("Helper '%s' not found", args[0])
+			os.Exit(-1)
+		}
+		docs = found
+	}
+
+	if err := writeHelpers(os.Stdout, format, docs, len(args) > 0); err != nil {
+		log.Errorf("%v", err)
+		os.Exit(-1)
+	}
+}
+
+// collectHelpers returns the documentation of the helpers of the given template types, sorted by category and name.
+func collectHelpers(types ...string) []*helperDoc {
+	sort.Strings(types)
+	byName := map[string]*helperDoc{}
+	var ret []*helperDoc
+
+	for _, t := range types {
+		tmpl, err := templates.Factory().Create(t)
+		if err != nil {
+			continue
+		}
+		if err := tmpl.EnableHelpers(config.Get().Helpers...); err != nil {
+			log.Errorf("%v", err)
+			os.Exit(-1)
+		}
+		for _, h := range tmpl.Helpers() {
+			doc, ok := byName[h.Name]
+			if !ok {
+				doc = &helperDoc{
+					Name:        h.Name,
+					Category:    h.Category,
+					Description: h.Description,
+					Signature:   h.Signature(),
+					Examples:    h.Examples,
+				}
+				if doc.Category == "" {
+					doc.Category = defaultCategory
+				}
+				byName[h.Name] = doc
+				ret = append(ret, doc)
+			}
+			doc.Engines = append(doc.Engines, t)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Category != ret[j].Category {
+			return ret[i].Category < ret[j].Category
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
+func writeHelpers(w io.Writer, format string, docs []*helperDoc, details bool) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if details && len(docs) == 1 {
+			return enc.Encode(docs[0])
+		}
+		return enc.Encode(docs)
+	case "markdown", "md":
+		writeHelpersMarkdown(w, docs)
+	case "text", "":
+		if details {
+			writeHelperDetails(w, docs)
+		} else {
+			writeHelpersText(w, docs, true)
+		}
+	default:
+		return fmt.Errorf("unknown format '%s', supported formats are text, json and markdown", format)
+	}
+	return nil
+}
+
+// writeHelpersText writes the helpers as a table grouped by category, optionally with the template types where they
+// are available.
+func writeHelpersText(w io.Writer, docs []*helperDoc, engines bool) {
+	maxLength := map[string]int{}
+	for _, d := range docs {
+		if l := len(d.Usage()); l > maxLength[d.Category] {
+			maxLength[d.Category] = l
+		}
+	}
+
+	category := ""
+	for _, d := range docs {
+		if d.Category != category {
+			category = d.Category
+			_, _ = fmt.Fprintf(w, "\n  %s\n", category)
+		}
+		line := fmt.Sprintf("   - %s", d.Usage())
+		if d.Description != "" {
+			line += getSpaces(maxLength[d.Category]-len(d.Usage())) + "      > " + d.Description
+		}
+		if engines {
+			line += fmt.Sprintf(" [%s]", strings.Join(d.Engines, ", "))
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+func writeHelperDetails(w io.Writer, docs []*helperDoc) {
+	for _, d := range docs {
+		_, _ = fmt.Fprintf(w, "%s\n", d.Name)
+		_, _ = fmt.Fprintf(w, "  Category:    %s\n", d.Category)
+		_, _ = fmt.Fprintf(w, "  Usage:       %s\n", d.Usage())
+		if d.Signature != nil {
+			_, _ = fmt.Fprintf(w, "  Variadic:    %v\n", d.Signature.Variadic)
+		}
+		_, _ = fmt.Fprintf(w, "  Engines:     %s\n", strings.Join(d.Engines, ", "))
+		if d.Description != "" {
+			_, _ = fmt.Fprintf(w, "  Description: %s\n", d.Description)
+		}
+		if len(d.Examples) > 0 {
+			_, _ = fmt.Fprintf(w, "  Examples:\n")
+			for _, e := range d.Examples {
+				_, _ = fmt.Fprintf(w, "    %s\n", e)
+			}
+		}
+		_, _ = fmt.Fprintln(w)
+	}
+}
+
+func writeHelpersMarkdown(w io.Writer, docs []*helperDoc) {
+	category := ""
+	for _, d := range docs {
+		if d.Category != category {
+			category = d.Category
+			_, _ = fmt.Fprintf(w, "## %s\n\n", category)
+		}
+		_, _ = fmt.Fprintf(w, "### `%s`\n\n", d.Name)
+		if d.Signature != nil {
+			_, _ = fmt.Fprintf(w, "```go\n%s\n```\n\n", d.Usage())
+		}
+		if d.Description != "" {
+			_, _ = fmt.Fprintf(w, "%s\n\n", d.Description)
+		}
+		for _, e := range d.Examples {
+			_, _ = fmt.Fprintf(w, "- Example: `%s`\n", e)
+		}
+		if len(d.Examples) > 0 {
+			_, _ = fmt.Fprintln(w)
+		}
+		_, _ = fmt.Fprintf(w, "Available in: %s\n\n", strings.Join(d.Engines, ", "))
+	}
+}
@@ -2,19 +2,17 @@ package parser
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"path/filepath"
-	"reflect"
 	"strings"
 
+	"github.com/jucardi/infuse/util/loader"
+	"github.com/jucardi/infuse/util/maps"
 	"gopkg.in/yaml.v2"
 )
 
-var zeroVal = reflect.Value{}
-
 type Data map[string]interface{}
 
 func (d Data) LoadContents(contents []byte, file string) error {
@@ -25,7 +23,7 @@ func (d Data) LoadContents(contents []byte, file string) error {
 
 	switch ext {
 	case "json":
-		err = json.Unmarshal(contents, &val)
+		val, err = loader.LoadJSON(contents)
 	case "yml":
 		fallthrough
 	case "yaml":
@@ -38,7 +36,7 @@ func (d Data) LoadContents(contents []byte, file string) error {
 		return fmt.Errorf("failed to unmarshal %s, %s", file, err.Error())
 	}
 
-	merge(d, val)
+	maps.Merge(d, val)
 	return nil
 }
 
@@ -69,37 +67,7 @@ func (d Data) ToMap() map[string]interface{} {
 	return map[string]interface{}(d)
 }
 
-func merge(dest interface{}, source interface{}) {
-	dVal, ok := dest.(reflect.Value)
-	if !ok {
-		dVal = reflect.ValueOf(dest)
-	}
-	sVal, ok := source.(reflect.Value)
-	if !ok {
-		sVal = reflect.ValueOf(source)
-	}
-
-	if sVal.Type().Kind() != reflect.Map || dVal.Type().Kind() != reflect.Map {
-		return
-	}
-
-	for _, k := range sVal.MapKeys() {
-		val := reflect.ValueOf(sVal.MapIndex(k).Interface())
-
-		if val.Kind() == reflect.Map || val.Kind() == reflect.Struct {
-			isZero, target := true, reflect.Value{}
-			if tVal := dVal.MapIndex(k); tVal.IsValid() {
-				target = reflect.ValueOf(dVal.MapIndex(k).Interface())
-				isZero = target == zeroVal
-			}
-
-			if isZero {
-				dVal.SetMapIndex(k, val)
-			} else {
-				merge(target, val)
-			}
-		} else {
-			dVal.SetMapIndex(k, val)
-		}
-	}
+// Clone returns a deep copy of the data, so it can be merged with other sources without modifying the original.
+func (d Data) Clone() Data {
+	return maps.DeepCopy(d.ToMap()).(map[string]interface{})
 }
@@ -0,0 +1,344 @@
+package parser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jucardi/infuse/config"
+	"github.com/jucardi/infuse/util/loader"
+	"gopkg.in/yaml.v2"
+)
+
+// Manifest declares multiple templates to be rendered in a single invocation. The data sources and definitions
+// declared at the manifest level are shared by all the jobs.
+type Manifest struct {
+	// Files are the JSON or YAML files loaded as data for every job
+	Files []string `yaml:"files"`
+
+	// URL is a URL to HTTP GET a JSON or YAML file loaded as data for every job
+	URL string `yaml:"url"`
+
+	// Definitions are the template definitions loaded for every job
+	Definitions []string `yaml:"definitions"`
+
+	// Pattern are the search patterns used to load definitions for every job, unless a job declares its own. Supports
+	// '**' to match any number of directories and exclusions prefixed with '!'
+	Pattern config.StringList `yaml:"pattern"`
+
+	// DefinitionsRoot is the directory used to name the definitions by their relative path. E.g: 'global/mongo.tmpl'
+	DefinitionsRoot string `yaml:"definitionsRoot"`
+
+	// DefinitionsDirs are directories whose templates are loaded recursively as definitions for every job
+	DefinitionsDirs []string `yaml:"definitionsDirs"`
+
+	// Libs are the libraries loaded for every job, as a directory, an archive or a cached library 'name@version'
+	Libs []string `yaml:"libs"`
+
+	// Helpers are the helper packs enabled for every job, e.g: 'sprig'
+	Helpers []string `yaml:"helpers"`
+
+	// IgnoreErrors indicates whether a job parsing a directory should continue when a template fails
+	IgnoreErrors bool `yaml:"ignoreErrors"`
+
+	// Type is the template type used for every job, unless a job declares its own
+	Type string `yaml:"type"`
+
+	// Strict indicates whether templates should fail when a value referenced by the template is missing from the data
+	Strict bool `yaml:"strict"`
+
+	// StrictDefinitions indicates whether jobs should fail when two definitions by the same name are loaded from
+	// different files
+	StrictDefinitions bool `yaml:"strictDefinitions"`
+
+	// Delims are the custom action delimiters used for every job, unless a job declares its own. E.g: '[[,]]'
+	Delims string `yaml:"delims"`
+
+	// Parallel is the maximum number of jobs executed concurrently. Defaults to the number of jobs
+	Parallel int `yaml:"parallel"`
+
+	// Jobs are the templates to be rendered
+	Jobs []*Job `yaml:"jobs"`
+
+	dir string
+}
+
+// Job represents a single template to be rendered as part of a manifest. The files and definitions of a job are
+// loaded in addition to the ones declared in the manifest, job files take precedence over the shared data.
+type Job struct {
+	Name            string            `yaml:"name"`
+	Template        string            `yaml:"template"`
+	Output          string            `yaml:"output"`
+	Files           []string          `yaml:"files"`
+	URL             string            `yaml:"url"`
+	Definitions     []string          `yaml:"definitions"`
+	DefinitionsDirs []string          `yaml:"definitionsDirs"`
+	Libs            []string          `yaml:"libs"`
+	Helpers         []string          `yaml:"helpers"`
+	Pattern         config.StringList `yaml:"pattern"`
+	Type            string            `yaml:"type"`
+	Delims          string            `yaml:"delims"`
+	IgnoreErrors    *bool             `yaml:"ignoreErrors"`
+}
+
+// JobResult contains the outcome of a job execution
+type JobResult struct {
+	Job      *Job
+	Duration time.Duration
+	Err      error
+
+	// stdout is the output of a job without an output file, written to stdout once all the jobs are done.
+	stdout bytes.Buffer
+}
+
+// Report contains the results of all the jobs executed from a manifest
+type Report struct {
+	Results  []*JobResult
+	Duration time.Duration
+}
+
+// LoadManifest reads a manifest file. Relative paths declared in the manifest are resolved from the directory where
+// the manifest is located.
+func LoadManifest(file string) (*Manifest, error) {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s, %v", file, err)
+	}
+
+	manifest := &Manifest{}
+	if err := yaml.UnmarshalStrict(contents, manifest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal manifest %s, %v", file, err)
+	}
+	manifest.dir = filepath.Dir(file)
+
+	if err := manifest.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s, %v", file, err)
+	}
+	return manifest, nil
+}
+
+func (m *Manifest) validate() error {
+	if len(m.Jobs) == 0 {
+		return errors.New("no jobs declared")
+	}
+	for i, job := range m.Jobs {
+		if job.Template == "" {
+			return fmt.Errorf("template path is required, job %d", i+1)
+		}
+		if job.Name == "" {
+			job.Name = job.Template
+		}
+	}
+	return nil
+}
+
+// Run executes all the jobs declared in the manifest. Jobs are independent from each other and are executed
+// concurrently, a failing job does not prevent the other jobs from running. The jobs without an output are written to
+// stdout in the order they are declared once all the jobs are done, so their outputs are not interleaved.
+func (m *Manifest) Run() *Report {
+	start := time.Now()
+	report := &Report{Results: make([]*JobResult, len(m.Jobs))}
+
+	shared, err := m.loadShared()
+
+	workers := m.Parallel
+	if workers <= 0 || workers > len(m.Jobs) {
+		workers = len(m.Jobs)
+	}
+
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				job := m.Jobs[index]
+				if err != nil {
+					report.Results[index] = &JobResult{Job: job, Err: err}
+					continue
+				}
+				report.Results[index] = m.runJob(job, shared)
+			}
+		}()
+	}
+
+	for i := range m.Jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, result := range report.Results {
+		_, _ = result.stdout.WriteTo(os.Stdout)
+	}
+	report.Duration = time.Since(start)
+	return report
+}
+
+func (m *Manifest) loadShared() (Data, error) {
+	data := Data{}
+	for _, file := range m.Files {
+		if err := data.LoadFile(m.resolve(file)); err != nil {
+			return nil, fmt.Errorf("unable to load shared data, %v", err)
+		}
+	}
+	if m.URL != "" {
+		if err := data.LoadURL(m.URL); err != nil {
+			return nil, fmt.Errorf("unable to load shared data, %v", err)
+		}
+	}
+	return data, nil
+}
+
+func (m *Manifest) runJob(job *Job, shared Data) *JobResult {
+	start := time.Now()
+	result := &JobResult{Job: job}
+	req := m.request(job)
+	req.stdout = &result.stdout
+	data := shared.Clone()
+
+	err := req.loadInto(data)
+	if err != nil {
+		err = fmt.Errorf("unable to load data, %v", err)
+	} else if req.Output != "" {
+		if err = os.MkdirAll(filepath.Dir(req.Output), 0755); err != nil {
+			err = fmt.Errorf("unable to create directory for '%s', %v", req.Output, err)
+		}
+	}
+	if err == nil {
+		err = render(data, req)
+	}
+
+	result.Duration = time.Since(start)
+	result.Err = err
+	return result
+}
+
+// request builds the template request of a job, applying the job overrides over the manifest values
+func (m *Manifest) request(job *Job) TemplateRequest {
+	req := TemplateRequest{
+		Path:              m.resolve(job.Template),
+		Output:            m.resolve(job.Output),
+		URL:               job.URL,
+		ContinueOnError:   m.IgnoreErrors,
+		Type:              m.Type,
+		Strict:            m.Strict,
+		StrictDefinitions: m.StrictDefinitions,
+		Delims:            m.Delims,
+		DefinitionsRoot:   m.resolve(m.DefinitionsRoot),
+	}
+
+	for _, file := range job.Files {
+		req.Files = append(req.Files, m.resolve(file))
+	}
+	for _, defs := range [][]string{m.Definitions, job.Definitions} {
+		for _, def := range defs {
+			req.Definitions = append(req.Definitions, m.resolveDefinition(def))
+		}
+	}
+	for _, dirs := range [][]string{m.DefinitionsDirs, job.DefinitionsDirs} {
+		for _, dir := range dirs {
+			req.DefinitionsDirs = append(req.DefinitionsDirs, m.resolve(dir))
+		}
+	}
+	for _, libs := range [][]string{m.Libs, job.Libs} {
+		for _, lib := range libs {
+			req.Libraries = append(req.Libraries, m.resolveLibrary(lib))
+		}
+	}
+	req.Helpers = append(append(req.Helpers, m.Helpers...), job.Helpers...)
+	patterns := job.Pattern
+	if len(patterns) == 0 {
+		patterns = m.Pattern
+	}
+	for _, p := range patterns {
+		req.SearchPatterns = append(req.SearchPatterns, m.resolvePattern(p))
+	}
+	if job.Type != "" {
+		req.Type = job.Type
+	}
+	if job.Delims != "" {
+		req.Delims = job.Delims
+	}
+	if job.IgnoreErrors != nil {
+		req.ContinueOnError = *job.IgnoreErrors
+	}
+	return req
+}
+
+// resolveDefinition resolves the path of a definition argument, keeping its alias if declared as 'name=path'
+func (m *Manifest) resolveDefinition(def string) string {
+	alias, path := loader.DefinitionArg(def)
+	if alias == "" {
+		return m.resolve(path)
+	}
+	return alias + "=" + m.resolve(path)
+}
+
+// resolveLibrary resolves the path of a library, unless it is a reference to a cached library that does not exist as
+// a file
+func (m *Manifest) resolveLibrary(lib string) string {
+	if path := m.resolve(lib); pathExists(path) {
+		return path
+	}
+	return lib
+}
+
+// resolvePattern resolves a search pattern, keeping the '!' prefix of exclusion patterns
+func (m *Manifest) resolvePattern(pattern string) string {
+	if strings.HasPrefix(pattern, "!") {
+		return "!" + m.resolve(strings.TrimPrefix(pattern, "!"))
+	}
+	return m.resolve(pattern)
+}
+
+func (m *Manifest) resolve(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(m.dir, path)
+}
+
+// Failed returns the number of jobs that failed
+func (r *Report) Failed() int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Print writes a summary of the job results to the given writer
+func (r *Report) Print(writer io.Writer) {
+	for _, result := range r.Results {
+		target := result.Job.Output
+		if target == "" {
+			target = "stdout"
+		}
+		status := "OK"
+		if result.Err != nil {
+			status = "FAIL"
+		}
+		_, _ = fmt.Fprintf(writer, "  %-4s  %s: %s -> %s (%v)\n", status, result.Job.Name, result.Job.Template, target, result.Duration.Round(time.Millisecond))
+		if result.Err != nil {
+			_, _ = fmt.Fprintf(writer, "        %v\n", result.Err)
+		}
+	}
+	_, _ = fmt.Fprintf(writer, "\n%d jobs, %d succeeded, %d failed in %v\n", len(r.Results), len(r.Results)-r.Failed(), r.Failed(), r.Duration.Round(time.Millisecond))
+}
+
+func pathExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -8,9 +8,14 @@ import (
 	"github.com/jucardi/go-streams/streams"
 	"github.com/jucardi/infuse/templates"
 	"github.com/jucardi/infuse/util/ioutils"
+	"github.com/jucardi/infuse/util/library"
+	"github.com/jucardi/infuse/util/loader"
+	"github.com/jucardi/infuse/util/maps"
 	"io"
-	"io/ioutil"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 var (
@@ -32,45 +37,88 @@ type TemplateRequest struct {
 	URL             string
 	Output          string
 	Definitions     []string
-	SearchPattern   string
+	SearchPatterns  []string
 	ContinueOnError bool
+	Type            string
+	Strict          bool
+
+	// StrictDefinitions indicates whether loading two definitions by the same name from different files fails
+	StrictDefinitions bool
+
+	// Delims are the custom action delimiters for the template, separated by a comma. E.g: '[[,]]'
+	Delims string
+
+	// DefinitionsRoot is the directory used to name the definitions by their relative path. E.g: 'global/mongo.tmpl'
+	DefinitionsRoot string
+
+	// DefinitionsDirs are directories whose templates are loaded recursively as definitions, named by their path
+	// relative to the directory.
+	DefinitionsDirs []string
+
+	// Libraries are the libraries whose definitions are loaded as 'libname/def', given as a directory, an archive or a
+	// reference to a cached library, 'name' or 'name@version'. Their default values are overridden by the data.
+	Libraries []string
+
+	// Helpers are the helper packs enabled for the template, e.g: 'sprig'
+	Helpers []string
+
+	// Query is a JSONPath expression, or a jq path, evaluated against the data instead of rendering a template. The
+	// matching values are written to the output as a JSON list.
+	Query string
+
+	// FS is the file system the templates and definitions are loaded from, e.g: an embed.FS. Data files and outputs
+	// always use the OS file system. The OS file system is used if nil.
+	FS fs.FS
+
+	// documentsDir is the directory where multi-document renders are written to, set when parsing directories.
+	documentsDir string
+
+	// stdout is where the renders without an output are written to. Stdout is used if nil.
+	stdout io.Writer
 }
 
 func (t TemplateRequest) validate() error {
 	if len(t.Files) == 0 && t.String != "" && t.URL != "" {
 		return errors.New("only one input method allowed, specify either an input filename, a string or a URL")
 	}
-	if t.Path == "" {
+	if t.Path == "" && t.Query == "" {
 		return errors.New("template path is required")
 	}
 	return nil
 }
 
 func (t TemplateRequest) load() (Data, error) {
+	dataObj := Data{}
+	if err := t.loadInto(dataObj); err != nil {
+		return nil, err
+	}
+	return dataObj, nil
+}
+
+// loadInto loads the data sources of the request and merges them into the provided data object
+func (t TemplateRequest) loadInto(dataObj Data) error {
 	if t.Path == "" && t.Filename != "" {
 		t.Path = t.Filename
 	}
 
 	if err := t.validate(); err != nil {
-		return nil, err
+		return err
 	}
 
-	dataObj := Data{}
-
 	if len(t.Files) > 0 {
 		for _, file := range t.Files {
 			if err := dataObj.LoadFile(file); err != nil {
-				return nil, err
+				return err
 			}
 		}
 	}
 
 	if t.URL != "" {
 		if err := dataObj.LoadURL(t.URL); err != nil {
-			return nil, err
+			return err
 		}
 	}
-	return dataObj, nil
+	return nil
 }
 
 // Parse parses the given template with the given information
@@ -82,7 +130,19 @@ func Parse(req TemplateRequest) error {
 		return fmt.Errorf("unable to load data, %v", err)
 	}
 
-	stat, err := os.Stat(req.Path)
+	if req.Query != "" {
+		return query(data, req)
+	}
+	return render(data, req)
+}
+
+// render parses the template file or directory of the request using the given data
+func render(data Data, req TemplateRequest) error {
+	if req.Path == "" && req.Filename != "" {
+		req.Path = req.Filename
+	}
+
+	stat, err := loader.Stat(req.FS, req.Path)
 
 	if err != nil {
 		return err
@@ -127,7 +187,7 @@ func parseDir(data Data, req TemplateRequest) error {
 		}
 	}
 
-	items, err := ioutil.ReadDir(req.Path)
+	items, err := loader.ReadDir(req.FS, req.Path)
 
 	if err != nil {
 		return err
@@ -142,14 +202,25 @@ func parseDir(data Data, req TemplateRequest) error {
 			output = paths.Combine(req.Output, f.Name())
 		}
 		newReq := TemplateRequest{
-			Path:            paths.Combine(req.Path, f.Name()),
-			String:          req.String,
-			Files:           req.Files,
-			URL:             req.URL,
-			Output:          output,
-			Definitions:     req.Definitions,
-			SearchPattern:   req.SearchPattern,
-			ContinueOnError: req.ContinueOnError,
+			Path:              paths.Combine(req.Path, f.Name()),
+			String:            req.String,
+			Files:             req.Files,
+			URL:               req.URL,
+			Output:            output,
+			Definitions:       req.Definitions,
+			SearchPatterns:    req.SearchPatterns,
+			ContinueOnError:   req.ContinueOnError,
+			Type:              req.Type,
+			Strict:            req.Strict,
+			StrictDefinitions: req.StrictDefinitions,
+			Delims:            req.Delims,
+			DefinitionsRoot:   req.DefinitionsRoot,
+			DefinitionsDirs:   req.DefinitionsDirs,
+			Libraries:         req.Libraries,
+			Helpers:           req.Helpers,
+			FS:                req.FS,
+			documentsDir:      req.Output,
+			stdout:            req.stdout,
 		}
 
 		var parser func(Data, TemplateRequest) error
@@ -172,14 +243,46 @@ func parseDir(data Data, req TemplateRequest) error {
 }
 
 func parseFile(data Data, req TemplateRequest) error {
-	template := templates.Factory().New(req.Path)
-	var writer io.WriteCloser
+	template, err := newTemplate(req)
+	if err != nil {
+		return err
+	}
+	template.SetStrict(req.Strict)
+	template.SetStrictDefinitions(req.StrictDefinitions)
+	template.SetDefinitionsRoot(req.DefinitionsRoot)
+	template.SetFS(req.FS)
+	if err := template.EnableHelpers(req.Helpers...); err != nil {
+		return err
+	}
+
+	if req.Delims != "" {
+		left, right, err := splitDelims(req.Delims)
+		if err != nil {
+			return err
+		}
+		if err := template.SetDelims(left, right); err != nil {
+			return err
+		}
+	}
 
 	// Load template
 	if err := template.LoadFileTemplate(req.Path); err != nil {
 		return fmt.Errorf("failed to load template '%s', %v", req.Path, err)
 	}
 
+	// Load libraries
+	var libs []*library.Library
+	for _, ref := range req.Libraries {
+		lib, err := library.Load(ref)
+		if err != nil {
+			return err
+		}
+		if err := template.LoadLibrary(lib); err != nil {
+			return err
+		}
+		libs = append(libs, lib)
+	}
+
 	// Load template definitions.
 	if len(req.Definitions) > 0 {
 		if err := template.LoadFileDefinition(req.Definitions...); err != nil {
@@ -187,28 +290,109 @@ func parseFile(data Data, req TemplateRequest) error {
 		}
 	}
 
-	// Load definitions by search pattern
-	if req.SearchPattern != "" {
-		if err := template.LoadFileDefinitionsByPattern(req.SearchPattern); err != nil {
+	// Load definitions by search patterns
+	if len(req.SearchPatterns) > 0 {
+		if err := template.LoadFileDefinitionsByPatterns(req.SearchPatterns...); err != nil {
 			return fmt.Errorf("failed to load definitions, %v", err)
 		}
 	}
 
-	// Establish the io.Writer to use
-	if req.Output != "" {
-		if w, err := ioutils.NewFileWriter(req.Output); err != nil {
-			return fmt.Errorf("unable to open file '%s', %v", req.Output, err)
-		} else {
-			writer = w
-			defer w.Close()
+	// Load definitions directories
+	for _, dir := range req.DefinitionsDirs {
+		if err := template.LoadFileDefinitionsDir(dir); err != nil {
+			return fmt.Errorf("failed to load definitions from '%s', %v", dir, err)
 		}
-	} else {
-		writer = os.Stdout
 	}
 
-	if err := template.Parse(writer, data.ToMap()); err != nil {
+	buf := ioutils.NewStringWriter()
+	if err := template.Parse(buf, withDefaults(data, libs).ToMap()); err != nil {
 		return fmt.Errorf("failed to parse the template, %v", err)
 	}
 
-	return nil
+	return writeOutput(req, buf.ToString())
+}
+
+// withDefaults returns the data merged over the default values of the libraries. Returns the same data if none of the
+// libraries declares default values.
+func withDefaults(data Data, libs []*library.Library) Data {
+	ret := Data{}
+	for _, lib := range libs {
+		if len(lib.Defaults) > 0 {
+			maps.Merge(ret, maps.DeepCopy(lib.Defaults))
+		}
+	}
+	if len(ret) == 0 {
+		return data
+	}
+	maps.Merge(ret, data.Clone())
+	return ret
+}
+
+// newTemplate creates the template implementation for the type in the request, or the default type if not specified
+func newTemplate(req TemplateRequest) (templates.ITemplate, error) {
+	if req.Type == "" {
+		return templates.Factory().New(req.Path), nil
+	}
+	template, err := templates.Factory().Create(req.Type, req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create template of type '%s', %v", req.Type, err)
+	}
+	return template, nil
+}
+
+// splitDelims splits the left and right delimiters separated by a comma. E.g: '[[,]]'
+func splitDelims(delims string) (string, string, error) {
+	split := strings.Split(delims, ",")
+	if len(split) != 2 || strings.TrimSpace(split[0]) == "" || strings.TrimSpace(split[1]) == "" {
+		return "", "", fmt.Errorf("invalid delimiters '%s', expected the left and right delimiters separated by a comma, e.g. '[[,]]'", delims)
+	}
+	return strings.TrimSpace(split[0]), strings.TrimSpace(split[1]), nil
+}
+
+func writeOutput(req TemplateRequest, contents string) error {
+	if req.Output == "" {
+		stdout := req.stdout
+		if stdout == nil {
+			stdout = os.Stdout
+		}
+		_, err := io.WriteString(stdout, contents)
+		return err
+	}
+
+	if ioutils.HasDocuments(contents) {
+		return writeDocuments(req, contents)
+	}
+
+	writer, err := ioutils.NewFileWriter(req.Output)
+	if err != nil {
+		return fmt.Errorf("unable to open file '%s', %v", req.Output, err)
+	}
+	defer writer.Close()
+
+	_, err = io.WriteString(writer, contents)
+	return err
+}
+
+// writeDocuments splits a multi-document render and writes each document relative to the output directory. When
+// parsing a directory, documents are written relative to the output directory of the parent request, otherwise the
+// output is used as the directory unless it is a file, in which case its parent directory is used. An output which
+// does not exist is a file if it has an extension, e.g: 'out.yaml'.
+func writeDocuments(req TemplateRequest, contents string) error {
+	dir := req.documentsDir
+	if dir == "" {
+		dir = req.Output
+		if stat, err := os.Stat(dir); err == nil && !stat.IsDir() {
+			dir = filepath.Dir(dir)
+		} else if os.IsNotExist(err) && filepath.Ext(dir) != "" {
+			dir = filepath.Dir(dir)
+		} else if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	preamble, docs := ioutils.SplitDocuments(contents)
+	if strings.TrimSpace(preamble) != "" {
+		return fmt.Errorf("unexpected content found before the first file marker in '%s'", req.Path)
+	}
+	return ioutils.WriteDocuments(dir, docs)
 }
@@ -0,0 +1,59 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jucardi/infuse/util/ioutils"
+)
+
+func TestWriteDocumentsOutput(t *testing.T) {
+	contents := ioutils.FileMarker("a.yaml") + "a: 1\n" + ioutils.FileMarker("nested/b.yaml") + "b: 2\n"
+
+	tests := []struct {
+		name   string
+		output string
+		// existing creates the output before writing, as a "file" or a "dir"
+		existing string
+		dir      string
+	}{
+		{"missing directory", "out", "", "out"},
+		{"missing file with extension", "out.yaml", "", "."},
+		{"missing file in missing directory", "gen/out.yaml", "", "gen"},
+		{"existing file", "out", "file", "."},
+		{"existing directory with extension", "out.d", "dir", "out.d"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			req := TemplateRequest{Path: "test.tmpl", Output: filepath.Join(root, tt.output)}
+			switch tt.existing {
+			case "file":
+				if err := os.WriteFile(req.Output, []byte("previous"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			case "dir":
+				if err := os.Mkdir(req.Output, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := writeOutput(req, contents); err != nil {
+				t.Fatalf("failed to write the documents, %v", err)
+			}
+			for path, expected := range map[string]string{"a.yaml": "a: 1\n", "nested/b.yaml": "b: 2\n"} {
+				actual, err := os.ReadFile(filepath.Join(root, tt.dir, path))
+				if err != nil {
+					t.Fatalf("expected the document '%s' in '%s', %v", path, tt.dir, err)
+				}
+				if string(actual) != expected {
+					t.Errorf("document '%s' contains '%s', expected '%s'", path, actual, expected)
+				}
+			}
+			if stat, err := os.Stat(req.Output); tt.existing == "" && filepath.Ext(tt.output) != "" && err == nil && stat.IsDir() {
+				t.Errorf("expected no directory named '%s'", tt.output)
+			}
+		})
+	}
+}
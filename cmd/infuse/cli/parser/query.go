@@ -0,0 +1,46 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jucardi/infuse/util/jsonpath"
+)
+
+// query evaluates the query of the request against the data and writes the matching values as an indented JSON list.
+func query(data Data, req TemplateRequest) error {
+	results, err := jsonpath.Query(req.Query, data.ToMap())
+	if err != nil {
+		return err
+	}
+	contents, err := json.MarshalIndent(jsonValue(results), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal the query results, %v", err)
+	}
+	return writeOutput(req, string(contents)+"\n")
+}
+
+// jsonValue converts the maps decoded from YAML, whose keys are not strings, so the value can be marshalled as JSON.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			ret[fmt.Sprint(k)] = jsonValue(item)
+		}
+		return ret
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			ret[k] = jsonValue(item)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for i, item := range v {
+			ret[i] = jsonValue(item)
+		}
+		return ret
+	}
+	return value
+}
@@ -0,0 +1,193 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jucardi/go-logger-lib/log"
+	"github.com/jucardi/go-streams/streams"
+	"github.com/jucardi/infuse/util/loader"
+	"github.com/jucardi/infuse/util/watcher"
+)
+
+// WatchOptions encapsulates the configuration of the watch mode
+type WatchOptions struct {
+	// Interval is the time between each poll of the watched files
+	Interval time.Duration
+
+	// Debounce is the time to wait for a burst of changes to settle before re-rendering
+	Debounce time.Duration
+}
+
+// Watch parses the template with the given request, and keeps monitoring the template, definitions and data files
+// until the stop channel is closed. When a template inside a template directory changes, only its output is rendered
+// again. Changes in definitions or data files render all the outputs again.
+func Watch(req TemplateRequest, opts WatchOptions, stop <-chan struct{}) {
+	if req.Path == "" && req.Filename != "" {
+		req.Path = req.Filename
+	}
+
+	if err := Parse(req); err != nil {
+		log.Error(err)
+	}
+
+	w := watcher.New(req.watchedPaths()...)
+	w.Ignore = req.isIgnored
+	if opts.Interval > 0 {
+		w.Interval = opts.Interval
+	}
+	if opts.Debounce > 0 {
+		w.Debounce = opts.Debounce
+	}
+
+	log.Infof("watching '%s' for changes", req.Path)
+
+	w.Watch(stop, func(changed []string) {
+		log.Infof("changes detected: %s", strings.Join(changed, ", "))
+		if err := reparse(req, changed); err != nil {
+			log.Error(err)
+		}
+	})
+}
+
+// reparse renders again the outputs affected by the changed files.
+func reparse(req TemplateRequest, changed []string) error {
+	stat, err := os.Stat(req.Path)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return Parse(req)
+	}
+
+	var affected []string
+	for _, file := range changed {
+		rel, ok := relativeTo(req.Path, file)
+		if !ok || req.isDependency(file) {
+			return Parse(req)
+		}
+		affected = append(affected, rel)
+	}
+
+	data, err := req.load()
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range affected {
+		newReq := req
+		newReq.Path = filepath.Join(req.Path, rel)
+
+		if _, err := os.Stat(newReq.Path); os.IsNotExist(err) {
+			continue
+		}
+		if req.Output != "" {
+			newReq.Output = filepath.Join(req.Output, rel)
+			newReq.documentsDir = filepath.Dir(newReq.Output)
+		}
+
+		if err := parseFile(data, newReq); err != nil {
+			if !req.ContinueOnError {
+				return err
+			}
+			log.Error(err)
+		}
+	}
+	return nil
+}
+
+func (t TemplateRequest) watchedPaths() []string {
+	ret := []string{t.Path}
+	for _, p := range t.SearchPatterns {
+		if !strings.HasPrefix(p, "!") {
+			ret = append(ret, loader.PatternBase(p))
+		}
+	}
+	ret = append(ret, t.DefinitionsDirs...)
+	ret = append(ret, t.libraryPaths()...)
+	ret = append(ret, t.Files...)
+	return append(ret, t.definitionFiles()...)
+}
+
+// isDependency indicates whether the given file is a definition or a data file of the request
+func (t TemplateRequest) isDependency(file string) bool {
+	for _, p := range append(t.definitionFiles(), t.Files...) {
+		if samePath(p, file) {
+			return true
+		}
+	}
+	if loader.MatchPatterns(t.SearchPatterns, file) {
+		return true
+	}
+	for _, dir := range append(t.libraryPaths(), t.DefinitionsDirs...) {
+		if _, ok := relativeTo(dir, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// libraryPaths returns the libraries of the request that are directories or archives, excluding the references to
+// cached libraries
+func (t TemplateRequest) libraryPaths() []string {
+	var ret []string
+	for _, lib := range t.Libraries {
+		if _, err := os.Stat(lib); err == nil {
+			ret = append(ret, lib)
+		}
+	}
+	return ret
+}
+
+// definitionFiles returns the paths of the definitions of the request, without their aliases
+func (t TemplateRequest) definitionFiles() []string {
+	var ret []string
+	for _, def := range t.Definitions {
+		_, path := loader.DefinitionArg(def)
+		ret = append(ret, path)
+	}
+	return ret
+}
+
+// isIgnored indicates whether changes to the given path should be ignored, so files in the ignore list and the
+// outputs written inside a watched directory do not trigger a new render.
+func (t TemplateRequest) isIgnored(path string) bool {
+	if streams.From(IgnoreList).Contains(filepath.Base(path)) {
+		return true
+	}
+	if t.Output == "" {
+		return false
+	}
+	if _, inOutput := relativeTo(t.Output, path); !inOutput {
+		return false
+	}
+	if _, inTemplates := relativeTo(t.Path, path); !inTemplates {
+		return true
+	}
+	// Only ignore files inside the template path if the output is a subdirectory of it.
+	rel, ok := relativeTo(t.Path, t.Output)
+	return ok && rel != "."
+}
+
+func relativeTo(dir, path string) (string, bool) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absDir, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+func samePath(a, b string) bool {
+	rel, ok := relativeTo(a, b)
+	return ok && rel == "."
+}
@@ -3,15 +3,17 @@ package cli
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
-	"github.com/jucardi/go-streams/streams"
 	"github.com/jucardi/go-strings/stringx"
 	"github.com/jucardi/infuse/cmd/infuse/cli/parser"
 	"github.com/jucardi/infuse/cmd/infuse/version"
+	"github.com/jucardi/infuse/config"
 	"github.com/jucardi/infuse/templates"
-	"github.com/jucardi/infuse/templates/helpers"
 	"github.com/jucardi/infuse/util/log"
+	"github.com/jucardi/infuse/util/watcher"
 	"github.com/spf13/cobra"
 )
 
@@ -28,7 +30,14 @@ Infuse - the templates CLI parser
     Arch:    %s
 
 Supports:
-    - Go templates
+    - Go templates (go)
+    - Go HTML templates with contextual auto-escaping (gohtml)
+    - Handlebars templates (handlebars)
+    - Jinja2 templates (jinja)
+
+Default options can be set in a .infuse.yaml file, looked up from the current
+directory upwards and then in $XDG_CONFIG_HOME/infuse, or with INFUSE_*
+environment variables. Flags take precedence over both.
 `
 )
 
@@ -39,6 +48,7 @@ var (
 		Use:              "infuse",
 		Short:            "Parses a Golang template",
 		Long:             parsedUsage,
+		Args:             cobra.ArbitraryArgs,
 		PersistentPreRun: initCmd,
 		Run:              parse,
 	}
@@ -46,14 +56,36 @@ var (
 
 // Execute starts the execution of the parse command.
 func Execute() {
+	rootCmd.PersistentFlags().String("config", "", "Path to the configuration file. If not specified, looks for the first .infuse.yaml from the current directory upwards")
+	rootCmd.Flags().StringP("type", "t", config.Get().DefaultType, "The template engine type to use")
+	rootCmd.Flags().Bool("strict", false, "Fails if a value referenced by the template is missing from the data")
+	rootCmd.Flags().Bool("strictDefinitions", false, "Fails if two definitions by the same name are loaded from different files, instead of replacing the first one with a warning")
+	rootCmd.Flags().String("delims", "", "Custom action delimiters for Go templates, separated by a comma. E.g: '[[,]]'")
 	rootCmd.Flags().StringArrayP("file", "f", nil, "INPUT: A JSON or YAML file to use as an input for the data to be parsed")
 	rootCmd.Flags().StringP("string", "s", "", "INPUT: A JSON or YAML string representation")
 	rootCmd.Flags().StringP("url", "u", "", "INPUT: A URL to HTTP GET a JSON or YAML file from. Useful to parse data from config servers")
+	rootCmd.Flags().StringP("query", "q", "", "Prints the values of the data matched by a JSONPath expression, or a jq path if it starts with '.', as JSON instead of rendering a template. E.g: '$.services[?(@.enabled)].name'")
 	rootCmd.Flags().StringP("output", "o", "", "Set output file. If not specified, the resulting template will be printed to Stdout")
-	rootCmd.Flags().StringP("pattern", "p", "", "Uses a search pattern to load definition files to be used in the 'templates' directive.")
-	rootCmd.Flags().StringArrayP("definition", "d", []string{}, "Other templates to be loaded to be used in the 'templates' directive.")
+	rootCmd.Flags().StringArrayP("pattern", "p", nil, "Uses a search pattern to load definition files to be used in the 'templates' directive. Can be used multiple times, supports '**' to match any number of directories and exclusions prefixed with '!'")
+	rootCmd.Flags().StringArrayP("definition", "d", []string{}, "Other templates to be loaded to be used in the 'templates' directive. A name can be given with 'name=path'")
+	rootCmd.Flags().String("definitionsRoot", "", "Names the definitions by their path relative to this directory, e.g. 'global/mongo.tmpl', instead of their file name")
+	rootCmd.Flags().StringArray("lib", nil, "Loads a library, a directory or a tar or zip archive with a library.yaml manifest, whose definitions are available as 'libname/def'. A cached library can be referenced as 'name' or 'name@version'. Can be used multiple times")
+	rootCmd.PersistentFlags().String("libCache", "", "The directory where the libraries extracted from archives are cached. Defaults to 'infuse/libs' inside the user cache directory")
+	rootCmd.PersistentFlags().StringArray("helpers", nil, "Enables an optional helper pack, e.g: 'sprig' for the helpers compatible with the Sprig functions used by Helm. Can be used multiple times")
+	rootCmd.PersistentFlags().String("now", "", "Fixes the time returned by the 'now' helper so the output is reproducible, in RFC3339 format or as a Unix timestamp. E.g: '2024-01-01T00:00:00Z'")
+	rootCmd.PersistentFlags().Bool("jsonNumbers", false, "Decodes the numbers of JSON data as exact decimals instead of floats, preserving the precision of large integers and decimals")
+	rootCmd.PersistentFlags().StringArray("script", nil, "Loads a YAML file of functions whose body is a Go template, or a Starlark file with the .star extension, registered as helpers of every template type. Can be used multiple times")
+	rootCmd.Flags().StringArray("definitionsDir", nil, "Loads all templates in the directory recursively as definitions, named by their path relative to it. Can be used multiple times")
 	rootCmd.Flags().BoolP("listHelpers", "l", false, "Lists all registered helpers")
 	rootCmd.Flags().Bool("ignoreErrors", false, "Ignores errors and continues parsing. Only applies for directories")
+	rootCmd.Flags().BoolP("watch", "w", false, "Watches the template, definitions and data files, and renders the affected outputs again when they change")
+	rootCmd.Flags().Duration("watchInterval", watcher.DefaultInterval, "The polling interval used to detect changes in watch mode")
+
+	runCmd.Flags().IntP("parallel", "j", 0, "Maximum number of jobs rendered concurrently, overrides the value in the manifest")
+	rootCmd.AddCommand(runCmd)
+
+	helpersCmd.Flags().String("format", "text", "The output format: text, json or markdown")
+	rootCmd.AddCommand(helpersCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
@@ -67,7 +99,9 @@ func printUsage(cmd *cobra.Command) {
 
 func initCmd(cmd *cobra.Command, _ []string) {
 	FromCommand(cmd)
-	cmd.Use = fmt.Sprintf(usage, cmd.Use)
+	if !cmd.HasParent() {
+		cmd.Use = fmt.Sprintf(usage, cmd.Use)
+	}
 }
 
 func parse(cmd *cobra.Command, args []string) {
@@ -76,30 +110,52 @@ func parse(cmd *cobra.Command, args []string) {
 		os.Exit(0)
 	}
 
-	if !validate(args) {
+	query, _ := cmd.Flags().GetString("query")
+	if !validate(args, query) {
 		log.Error("Unexpected number of arguments")
 		printUsage(cmd)
 		os.Exit(-1)
 	}
 
-	filename := args[0]
-	input, _ := cmd.Flags().GetStringArray("file")
+	filename := ""
+	if len(args) > 0 {
+		filename = args[0]
+	}
 	str, _ := cmd.Flags().GetString("string")
 	url, _ := cmd.Flags().GetString("url")
 	output, _ := cmd.Flags().GetString("output")
-	definitions, _ := cmd.Flags().GetStringArray("definition")
-	pattern, _ := cmd.Flags().GetString("pattern")
 	ignoreErr, _ := cmd.Flags().GetBool("ignoreErrors")
+	cfg := config.Get()
+
+	if !templates.Factory().Contains(cfg.DefaultType) {
+		log.Errorf("Unknown template type '%s', available types: %s", cfg.DefaultType, strings.Join(templates.Factory().GetAvailableTypes(), ", "))
+		os.Exit(-1)
+	}
 
 	request := parser.TemplateRequest{
-		Path:            filename,
-		String:          str,
-		URL:             url,
-		Files:           input,
-		Definitions:     definitions,
-		SearchPattern:   pattern,
-		Output:          output,
-		ContinueOnError: ignoreErr,
+		Path:              filename,
+		String:            str,
+		URL:               url,
+		Files:             cfg.Files,
+		Definitions:       cfg.Definitions,
+		SearchPatterns:    cfg.Patterns,
+		DefinitionsDirs:   cfg.DefinitionsDirs,
+		Libraries:         cfg.Libraries,
+		Helpers:           cfg.Helpers,
+		Output:            output,
+		ContinueOnError:   ignoreErr,
+		Type:              cfg.DefaultType,
+		Strict:            cfg.Strict,
+		StrictDefinitions: cfg.StrictDefinitions,
+		Delims:            cfg.Delims,
+		DefinitionsRoot:   cfg.DefinitionsRoot,
+		Query:             query,
+	}
+
+	if watch, _ := cmd.Flags().GetBool("watch"); watch {
+		interval, _ := cmd.Flags().GetDuration("watchInterval")
+		parser.Watch(request, parser.WatchOptions{Interval: interval}, interruptSignal())
+		return
 	}
 
 	if err := parser.Parse(request); err != nil {
@@ -109,53 +165,30 @@ func parse(cmd *cobra.Command, args []string) {
 	}
 }
 
-func validate(args []string) bool {
+// interruptSignal returns a channel that is closed when the process receives an interrupt signal.
+func interruptSignal() <-chan struct{} {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+	return stop
+}
+
+func validate(args []string, query string) bool {
+	if query != "" {
+		return len(args) == 0
+	}
 	return len(args) == 1
 }
 
 func printHelpers() {
 	println("Available helpers:")
-	for k, list := range helpersByCategory() {
-		maxLenght := 0
-		fmt.Printf("\n  %s\n", k)
-		for _, h := range list {
-			if len(h.Name) > maxLenght {
-				maxLenght = len(h.Name)
-			}
-		}
-		for _, h := range list {
-			if h.Description == "" {
-				fmt.Printf("   - %s\n", h.Name)
-			} else {
-				fmt.Printf("   - %s%s      > %s\n", h.Name, getSpaces(maxLenght-len(h.Name)), h.Description)
-			}
-		}
-	}
-	println()
+	writeHelpersText(os.Stdout, collectHelpers(config.Get().DefaultType), false)
 }
 
-func helpersByCategory() map[string][]*helpers.Helper {
-	template := templates.Factory().New()
-	ret := map[string][]*helpers.Helper{}
-
-	for _, h := range template.Helpers() {
-		list, _ := ret[h.Category]
-		list = append(list, h)
-		ret[h.Category] = list
-	}
-	for cat, list := range ret {
-		ret[cat] = streams.
-			From(list).
-			OrderBy(func(i interface{}, j interface{}) int {
-				x := i.(*helpers.Helper)
-				y := j.(*helpers.Helper)
-
-				return strings.Compare(x.Name, y.Name)
-			}).
-			ToArray().([]*helpers.Helper)
-	}
-	return ret
-}
 func getSpaces(count int) string {
 	builder := stringx.Builder()
 	for i := 0; i < count; i++ {
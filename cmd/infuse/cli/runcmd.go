@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/jucardi/infuse/cmd/infuse/cli/parser"
+	"github.com/jucardi/infuse/config"
+	"github.com/jucardi/infuse/util/log"
+	"github.com/spf13/cobra"
+)
+
+const defaultManifest = "infuse.yaml"
+
+var runCmd = &cobra.Command{
+	Use:   "run [manifest file]",
+	Short: "Renders all the templates declared in a manifest file",
+	Long: `Renders all the templates declared in a manifest file (infuse.yaml by default).
+
+The manifest declares the data sources and definitions shared by all the jobs,
+and the list of jobs where each job renders a template into an output:
+
+    files: [common.yaml]
+    definitions: [global/mongo.tmpl]
+    pattern: global/*
+    parallel: 4
+    jobs:
+      - name: api
+        template: service.tmpl
+        output: build/api.yml
+        files: [api.yaml]
+
+Relative paths are resolved from the directory of the manifest file.`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  run,
+}
+
+func run(cmd *cobra.Command, args []string) {
+	file := defaultManifest
+	if len(args) > 0 {
+		file = args[0]
+	}
+
+	manifest, err := parser.LoadManifest(file)
+	if err != nil {
+		log.Errorf("%v", err)
+		os.Exit(-1)
+	}
+
+	if parallel, _ := cmd.Flags().GetInt("parallel"); parallel > 0 {
+		manifest.Parallel = parallel
+	}
+	manifest.Strict = manifest.Strict || config.Get().Strict
+	manifest.StrictDefinitions = manifest.StrictDefinitions || config.Get().StrictDefinitions
+	manifest.Helpers = append(config.Get().Helpers, manifest.Helpers...)
+
+	report := manifest.Run()
+	report.Print(os.Stderr)
+
+	if report.Failed() > 0 {
+		os.Exit(-1)
+	}
+}
@@ -3,6 +3,11 @@ package main
 import (
 	"github.com/jucardi/infuse/cmd/infuse/cli"
 	_ "github.com/jucardi/infuse/templates/gotmpl"
+	_ "github.com/jucardi/infuse/templates/handlebars"
+	_ "github.com/jucardi/infuse/templates/jinja"
+
+	// Embeds the time zone database, so 'dateInZone' works on systems without it
+	_ "time/tzdata"
 )
 
 func main() {
@@ -1,17 +1,78 @@
 package config
 
+import (
+	"sync"
+	"time"
+)
+
 // Config encapsulates the configuration for the process.
 type Config struct {
-	Verbose     bool
-	DefaultType string
+	Verbose     bool       `yaml:"verbose"`
+	DefaultType string     `yaml:"type"`
+	Definitions []string   `yaml:"definitions"`
+	Patterns    StringList `yaml:"pattern"`
+	Files       []string   `yaml:"files"`
+	Strict      bool       `yaml:"strict"`
+	Delims      string     `yaml:"delims"`
+
+	StrictDefinitions bool     `yaml:"strictDefinitions"`
+	DefinitionsRoot   string   `yaml:"definitionsRoot"`
+	DefinitionsDirs   []string `yaml:"definitionsDirs"`
+	Libraries         []string `yaml:"libs"`
+	LibCache          string   `yaml:"libCache"`
+	Plugins           []Plugin `yaml:"plugins"`
+	Scripts           []string `yaml:"scripts"`
+	Helpers           []string `yaml:"helpers"`
+	JSONNumbers       bool     `yaml:"jsonNumbers"`
+	Now               string   `yaml:"now"`
+}
+
+// Plugin declares a helper implemented by an external executable, which receives the arguments of the helper as a
+// JSON object through stdin and writes the result as a JSON object to stdout.
+type Plugin struct {
+	// Name is the name of the helper in the templates
+	Name string `yaml:"name"`
+
+	// Command is the executable invoked by the helper. Defaults to 'infuse-helper-<name>', looked up in the PATH
+	Command string `yaml:"command"`
+
+	// Args are the arguments passed to the executable
+	Args []string `yaml:"args"`
+
+	// Timeout is the maximum time the executable can take to respond. E.g: '5s'
+	Timeout time.Duration `yaml:"timeout"`
+
+	// Description is the description of the helper shown by --listHelpers
+	Description string `yaml:"description"`
 }
 
-var instance *Config
+// StringList is a list of strings that can be declared in YAML either as a list or as a single string.
+type StringList []string
+
+// UnmarshalYAML unmarshals either a single string or a list of strings.
+func (l *StringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+var (
+	instance *Config
+	once     sync.Once
+)
 
 // Get gets the configuration instance.
 func Get() *Config {
-	if instance == nil {
+	once.Do(func() {
 		instance = &Config{DefaultType: "go"}
-	}
+	})
 	return instance
 }
@@ -0,0 +1,239 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jucardi/infuse/util/loader"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// FileName is the name of the project configuration file
+	FileName = ".infuse.yaml"
+
+	// EnvPrefix is the prefix of the environment variables that override values of the configuration file
+	EnvPrefix = "INFUSE_"
+)
+
+// Find looks for the configuration file in the given directory and its parents. If not found, looks for it in the
+// 'infuse' directory inside $XDG_CONFIG_HOME (~/.config if not set). Returns an empty string if no file was found.
+func Find(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	for {
+		if file := filepath.Join(dir, FileName); isFile(file) {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		if file := filepath.Join(configHome, "infuse", FileName); isFile(file) {
+			return file
+		}
+	}
+	return ""
+}
+
+// LoadFile loads the given configuration file into the configuration instance. Relative paths in the file are
+// resolved from the directory where the file is located.
+func LoadFile(file string) error {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file %s, %v", file, err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.UnmarshalStrict(contents, cfg); err != nil {
+		return fmt.Errorf("failed to unmarshal configuration file %s, %v", file, err)
+	}
+
+	dir := filepath.Dir(file)
+	c := Get()
+	c.Verbose = c.Verbose || cfg.Verbose
+	c.Strict = cfg.Strict
+	c.StrictDefinitions = cfg.StrictDefinitions
+	if cfg.Delims != "" {
+		c.Delims = cfg.Delims
+	}
+	if cfg.DefaultType != "" {
+		c.DefaultType = cfg.DefaultType
+	}
+	if len(cfg.Patterns) > 0 {
+		c.Patterns = nil
+		for _, p := range cfg.Patterns {
+			c.Patterns = append(c.Patterns, resolvePattern(dir, p))
+		}
+	}
+	for _, lib := range cfg.Libraries {
+		c.Libraries = append(c.Libraries, resolveLibrary(dir, lib))
+	}
+	if cfg.LibCache != "" {
+		c.LibCache = resolve(dir, cfg.LibCache)
+	}
+	for _, plugin := range cfg.Plugins {
+		if strings.ContainsAny(plugin.Command, "/\\") {
+			plugin.Command = resolve(dir, plugin.Command)
+		}
+		c.Plugins = append(c.Plugins, plugin)
+	}
+	c.JSONNumbers = c.JSONNumbers || cfg.JSONNumbers
+	if cfg.Now != "" {
+		c.Now = cfg.Now
+	}
+	if len(cfg.Helpers) > 0 {
+		c.Helpers = cfg.Helpers
+	}
+	for _, script := range cfg.Scripts {
+		c.Scripts = append(c.Scripts, resolve(dir, script))
+	}
+	for _, d := range cfg.DefinitionsDirs {
+		c.DefinitionsDirs = append(c.DefinitionsDirs, resolve(dir, d))
+	}
+	if cfg.DefinitionsRoot != "" {
+		c.DefinitionsRoot = resolve(dir, cfg.DefinitionsRoot)
+	}
+	for _, def := range cfg.Definitions {
+		c.Definitions = append(c.Definitions, resolveDefinition(dir, def))
+	}
+	for _, f := range cfg.Files {
+		c.Files = append(c.Files, resolve(dir, f))
+	}
+	return nil
+}
+
+// LoadEnv overrides the configuration instance with the values set in the INFUSE_* environment variables. Lists are
+// separated by commas. E.g: INFUSE_TYPE=handlebars, INFUSE_DEFINITIONS=a.tmpl,b.tmpl, INFUSE_STRICT=true
+func LoadEnv() error {
+	c := Get()
+	if v, ok := lookupEnv("TYPE"); ok {
+		c.DefaultType = v
+	}
+	if v, ok := lookupEnv("DELIMS"); ok {
+		c.Delims = v
+	}
+	if v, ok := lookupEnv("PATTERN"); ok {
+		c.Patterns = splitList(v)
+	}
+	if v, ok := lookupEnv("DEFINITIONS"); ok {
+		c.Definitions = splitList(v)
+	}
+	if v, ok := lookupEnv("DEFINITIONS_ROOT"); ok {
+		c.DefinitionsRoot = v
+	}
+	if v, ok := lookupEnv("DEFINITIONS_DIRS"); ok {
+		c.DefinitionsDirs = splitList(v)
+	}
+	if v, ok := lookupEnv("LIBS"); ok {
+		c.Libraries = splitList(v)
+	}
+	if v, ok := lookupEnv("LIB_CACHE"); ok {
+		c.LibCache = v
+	}
+	if v, ok := lookupEnv("HELPERS"); ok {
+		c.Helpers = splitList(v)
+	}
+	if v, ok := lookupEnv("SCRIPTS"); ok {
+		c.Scripts = splitList(v)
+	}
+	if v, ok := lookupEnv("NOW"); ok {
+		c.Now = v
+	}
+	if v, ok := lookupEnv("FILES"); ok {
+		c.Files = splitList(v)
+	}
+	if v, ok := lookupEnv("STRICT"); ok {
+		strict, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid value for %sSTRICT, %v", EnvPrefix, err)
+		}
+		c.Strict = strict
+	}
+	if v, ok := lookupEnv("STRICT_DEFINITIONS"); ok {
+		strict, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid value for %sSTRICT_DEFINITIONS, %v", EnvPrefix, err)
+		}
+		c.StrictDefinitions = strict
+	}
+	if v, ok := lookupEnv("JSON_NUMBERS"); ok {
+		jsonNumbers, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid value for %sJSON_NUMBERS, %v", EnvPrefix, err)
+		}
+		c.JSONNumbers = jsonNumbers
+	}
+	return nil
+}
+
+func lookupEnv(name string) (string, bool) {
+	return os.LookupEnv(EnvPrefix + name)
+}
+
+func splitList(value string) (ret []string) {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return
+}
+
+// resolveDefinition resolves the path of a definition, keeping its alias if declared as 'name=path'
+func resolveDefinition(dir, def string) string {
+	alias, path := loader.DefinitionArg(def)
+	if alias == "" {
+		return resolve(dir, path)
+	}
+	return alias + "=" + resolve(dir, path)
+}
+
+// resolveLibrary resolves the path of a library, unless it is a reference to a cached library ('name' or
+// 'name@version') that does not exist as a file.
+func resolveLibrary(dir, lib string) string {
+	if path := resolve(dir, lib); isFile(path) || isDir(path) {
+		return path
+	}
+	return lib
+}
+
+// resolvePattern resolves a search pattern, keeping the '!' prefix of exclusion patterns
+func resolvePattern(dir, pattern string) string {
+	if strings.HasPrefix(pattern, "!") {
+		return "!" + resolve(dir, strings.TrimPrefix(pattern, "!"))
+	}
+	return resolve(dir, pattern)
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func isFile(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && !stat.IsDir()
+}
+
+func isDir(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && stat.IsDir()
+}
@@ -3,9 +3,14 @@ package base
 import (
 	"fmt"
 	"io"
-	"strings"
+	"io/fs"
+	"path/filepath"
+	"sort"
 
+	"github.com/jucardi/infuse/templates/helpers"
+	"github.com/jucardi/infuse/util/library"
 	"github.com/jucardi/infuse/util/loader"
+	"github.com/jucardi/infuse/util/log"
 )
 
 // AbstractTemplate encapsulates the common functionality of an ITemplate implementation
@@ -14,6 +19,25 @@ type AbstractTemplate struct {
 	Definitions map[string]string
 	Template    string
 	NameStr     string
+	Strict      bool
+	LeftDelim   string
+	RightDelim  string
+
+	// DefinitionsRoot is the directory used to name the definitions loaded from files, by their path relative to it.
+	DefinitionsRoot string
+
+	// FS is the file system the template and definition files are loaded from. The OS file system is used if nil.
+	FS fs.FS
+
+	// StrictDefinitions indicates whether loading two definitions by the same name from different files fails, instead
+	// of replacing the first one with a warning.
+	StrictDefinitions bool
+
+	// Packs are the helper packs enabled for the template, e.g: 'sprig'
+	Packs []string
+
+	// sources keeps the file each definition was loaded from, to detect name collisions.
+	sources map[string]string
 }
 
 // Name represents the name of the ITemplate instance. This name will be used internally when creating the go template,
@@ -22,6 +46,78 @@ func (t *AbstractTemplate) Name() string {
 	return t.NameStr
 }
 
+// SetStrict indicates whether the template should fail when a value referenced by the template is missing from the data.
+func (t *AbstractTemplate) SetStrict(strict bool) {
+	t.Strict = strict
+}
+
+// SetStrictDefinitions indicates whether loading two definitions by the same name from different files should fail,
+// instead of replacing the first one with a warning.
+func (t *AbstractTemplate) SetStrictDefinitions(strict bool) {
+	t.StrictDefinitions = strict
+}
+
+// SetDelims sets the action delimiters to the specified strings. An empty delimiter stands for the corresponding
+// default, '{{' or '}}'. Must be set before loading the template and the definitions.
+func (t *AbstractTemplate) SetDelims(left, right string) error {
+	t.LeftDelim = left
+	t.RightDelim = right
+	return nil
+}
+
+// SetDefinitionsRoot sets the directory used to name the definitions loaded from files. Definitions inside the
+// directory are named by their path relative to it, e.g: 'global/mongo.tmpl', instead of their file name.
+func (t *AbstractTemplate) SetDefinitionsRoot(dir string) {
+	t.DefinitionsRoot = dir
+}
+
+// SetFS sets the file system the template and definition files are loaded from, e.g: an embed.FS. The OS file system
+// is used if nil.
+func (t *AbstractTemplate) SetFS(fsys fs.FS) {
+	t.FS = fsys
+}
+
+// EnableHelpers enables the given helper packs for the template, e.g: 'sprig'. The helpers of the packs override the
+// helpers by the same name.
+func (t *AbstractTemplate) EnableHelpers(packs ...string) error {
+	for _, name := range packs {
+		if _, err := helpers.PackHelpers(name); err != nil {
+			return err
+		}
+		if !contains(t.Packs, name) {
+			t.Packs = append(t.Packs, name)
+		}
+	}
+	return nil
+}
+
+// PackHelpers returns the helpers of the packs enabled for the template.
+func (t *AbstractTemplate) PackHelpers() []*helpers.Helper {
+	var ret []*helpers.Helper
+	for _, name := range t.Packs {
+		h, _ := helpers.PackHelpers(name)
+		ret = append(ret, h...)
+	}
+	return ret
+}
+
+// WithPackHelpers returns the given helpers along with the helpers of the enabled packs, removing the helpers
+// overridden by the packs.
+func (t *AbstractTemplate) WithPackHelpers(registered []*helpers.Helper) []*helpers.Helper {
+	packHelpers := t.PackHelpers()
+	overridden := map[string]bool{}
+	for _, h := range packHelpers {
+		overridden[h.Name] = true
+	}
+	var ret []*helpers.Helper
+	for _, h := range registered {
+		if !overridden[h.Name] {
+			ret = append(ret, h)
+		}
+	}
+	return append(ret, packHelpers...)
+}
+
 // ParseMarshaled parses the template using the string representation of a JSON or a YAML
 func (t *AbstractTemplate) ParseMarshaled(writer io.Writer, data []byte) error {
 	val, err := loader.LoadMarshaled(data)
@@ -51,7 +147,7 @@ func (t *AbstractTemplate) ParseYAML(writer io.Writer, data []byte) error {
 
 // LoadFileTemplate loads the given file as the template to be parsed.
 func (t *AbstractTemplate) LoadFileTemplate(filename string) error {
-	tmplStr, err := loader.LoadTemplate(filename)
+	tmplStr, err := loader.LoadTemplateFS(t.FS, filename)
 	if err != nil {
 		return fmt.Errorf("unable to load file '%s', %v", filename, err)
 	}
@@ -60,28 +156,99 @@ func (t *AbstractTemplate) LoadFileTemplate(filename string) error {
 
 // LoadFileDefinitionsByPattern uses a pattern to find the file definitions to be loaded for the template parsing
 func (t *AbstractTemplate) LoadFileDefinitionsByPattern(pattern string) error {
-	result, err := loader.LoadTemplates(pattern)
+	return t.LoadFileDefinitionsByPatterns(pattern)
+}
+
+// LoadFileDefinitionsByPatterns loads the file definitions matching any of the given patterns. Patterns support '**'
+// to match any number of directories, and patterns starting with '!' exclude the files they match.
+func (t *AbstractTemplate) LoadFileDefinitionsByPatterns(patterns ...string) error {
+	result, err := loader.LoadTemplateFilesFS(t.FS, patterns...)
+	if err != nil {
+		return err
+	}
+	return t.loadFileDefinitions(result, func(f string) string { return loader.DefinitionName(t.DefinitionsRoot, f) })
+}
+
+// LoadFileDefinitionsDir loads every file inside the given directory recursively as a definition, named by its path
+// relative to the directory, e.g: 'global/mongo.tmpl'.
+func (t *AbstractTemplate) LoadFileDefinitionsDir(dir string) error {
+	result, err := loader.LoadTemplateDirFS(t.FS, dir)
 	if err != nil {
 		return err
 	}
-	for k, v := range result {
-		t.Definitions[k] = v
+	return t.loadFileDefinitions(result, func(f string) string { return loader.DefinitionName(dir, f) })
+}
+
+// LoadLibrary loads the definitions of the given library, named 'libname/def'.
+func (t *AbstractTemplate) LoadLibrary(lib *library.Library) error {
+	result, err := lib.LoadDefinitions()
+	if err != nil {
+		return fmt.Errorf("failed to load library '%s', %v", lib.Name, err)
+	}
+	return t.loadFileDefinitions(result, lib.DefinitionName)
+}
+
+// loadFileDefinitions loads the given map of path,contents as definitions, named by the provided function
+func (t *AbstractTemplate) loadFileDefinitions(files map[string]string, name func(path string) string) error {
+	var paths []string
+	for k := range files {
+		paths = append(paths, k)
+	}
+	sort.Strings(paths)
+
+	for _, f := range paths {
+		if err := t.loadFileDefinition(name(f), f, files[f]); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// LoadFileDefinition loads a file(s) as definition(s) {{define "filename"}} using the filename as the name for the definition, to be used for 'template' directives
+// LoadFileDefinition loads a file(s) as definition(s) {{define "filename"}} using the filename as the name for the
+// definition, to be used for 'template' directives. The name is the path relative to the definitions root if set, and
+// can be set explicitly with the 'name=path' syntax.
 func (t *AbstractTemplate) LoadFileDefinition(files ...string) error {
-	for _, filepath := range files {
-		split := strings.Split(filepath, "/")
-		split = strings.Split(split[len(split)-1], "\\")
-		filename := split[len(split)-1]
+	for _, arg := range files {
+		name, file := loader.DefinitionArgFS(t.FS, arg)
+		if name == "" {
+			name = loader.DefinitionName(t.DefinitionsRoot, file)
+		}
 
-		if tmplStr, err := loader.LoadTemplate(filepath); err != nil {
+		if tmplStr, err := loader.LoadTemplateFS(t.FS, file); err != nil {
 			return err
-		} else if err := t.LoadDefinition(filename, tmplStr); err != nil {
+		} else if err := t.loadFileDefinition(name, file, tmplStr); err != nil {
 			return err
 		}
 	}
 	return nil
 }
+
+// loadFileDefinition loads a definition read from the given file. If a definition by the same name was loaded from a
+// different file, the new one replaces it with a warning, or fails if strict definitions are set.
+func (t *AbstractTemplate) loadFileDefinition(name, file, tmpl string) error {
+	if t.FS == nil {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+	}
+	if t.sources == nil {
+		t.sources = map[string]string{}
+	}
+	if prev, ok := t.sources[name]; ok && prev != file {
+		if t.StrictDefinitions {
+			return fmt.Errorf("definition '%s' loaded from '%s' collides with the one loaded from '%s'", name, file, prev)
+		}
+		log.Warnf("definition '%s' loaded from '%s' replaces the one loaded from '%s'", name, file, prev)
+	}
+	t.sources[name] = file
+	return t.LoadDefinition(name, tmpl)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
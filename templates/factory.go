@@ -2,6 +2,9 @@ package templates
 
 import (
 	"errors"
+	"sort"
+	"sync"
+
 	"github.com/jucardi/infuse/config"
 )
 
@@ -10,6 +13,7 @@ var (
 	ErrTypeNotFound = errors.New("type not found")
 
 	instance IFactory
+	once     sync.Once
 )
 
 type factory struct {
@@ -18,14 +22,14 @@ type factory struct {
 
 // Factory returns the templates factory
 func Factory() IFactory {
-	if instance == nil {
+	once.Do(func() {
 		instance = &factory{ctors: map[string]func(...string) ITemplate{}}
-	}
+	})
 	return instance
 }
 
 func (f *factory) New(name ...string) ITemplate {
-	if t, err := f.Create(config.Get().DefaultType); err == nil {
+	if t, err := f.Create(config.Get().DefaultType, name...); err == nil {
 		return t
 	}
 	for _, ctor := range f.ctors {
@@ -42,11 +46,12 @@ func (f *factory) Create(typeStr string, name ...string) (ITemplate, error) {
 	return ctor(name...), nil
 }
 
-func (f *factory) Register(name string, constructor func(name ...string) ITemplate) {
-	f.ctors[name] = func(name ...string) ITemplate {
+func (f *factory) Register(typeName string, constructor func(name ...string) ITemplate) {
+	f.ctors[typeName] = func(name ...string) ITemplate {
 		ret := constructor(name...)
 		return &baseTemplate{
 			ITemplate: ret,
+			name:      typeName,
 		}
 	}
 }
@@ -56,6 +61,7 @@ func (f *factory) GetAvailableTypes() []string {
 	for k := range f.ctors {
 		keys = append(keys, k)
 	}
+	sort.Strings(keys)
 	return keys
 }
 
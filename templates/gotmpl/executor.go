@@ -0,0 +1,91 @@
+package gotmpl
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"text/template"
+)
+
+// executor abstracts the text/template and html/template implementations, so the helpers that depend on the template
+// being rendered work the same way for both.
+type executor interface {
+	// define parses the given contents as a new template associated with the one being rendered.
+	define(name, contents string) error
+
+	// contains indicates whether a template by the given name is associated with the one being rendered.
+	contains(name string) bool
+
+	// execute executes the associated template by the given name and returns the result.
+	execute(name string, data interface{}) (string, error)
+
+	// result converts the result of executing a template into the value returned by the helpers.
+	result(str string) interface{}
+}
+
+type textExecutor struct {
+	*template.Template
+}
+
+func (e *textExecutor) define(name, contents string) error {
+	_, err := e.Template.New(name).Parse(contents)
+	return err
+}
+
+func (e *textExecutor) contains(name string) bool {
+	return e.Template.Lookup(name) != nil
+}
+
+func (e *textExecutor) execute(name string, data interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	err := e.Template.ExecuteTemplate(buf, name, data)
+	return buf.String(), err
+}
+
+func (e *textExecutor) result(str string) interface{} {
+	return str
+}
+
+// htmlExecutor keeps a copy of the template set that is never executed, since html/template does not allow parsing
+// new templates once the set has been executed. Templates defined during the render are parsed into the copy, and are
+// executed from a clone of it.
+type htmlExecutor struct {
+	root     *htmltemplate.Template
+	pristine *htmltemplate.Template
+}
+
+func newHTMLExecutor(root *htmltemplate.Template) (*htmlExecutor, error) {
+	pristine, err := root.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return &htmlExecutor{root: root, pristine: pristine}, nil
+}
+
+func (e *htmlExecutor) define(name, contents string) error {
+	_, err := e.pristine.New(name).Parse(contents)
+	return err
+}
+
+func (e *htmlExecutor) contains(name string) bool {
+	return e.pristine.Lookup(name) != nil
+}
+
+func (e *htmlExecutor) execute(name string, data interface{}) (string, error) {
+	buf := &bytes.Buffer{}
+	if e.root.Lookup(name) != nil {
+		err := e.root.ExecuteTemplate(buf, name, data)
+		return buf.String(), err
+	}
+
+	set, err := e.pristine.Clone()
+	if err != nil {
+		return "", err
+	}
+	err = set.ExecuteTemplate(buf, name, data)
+	return buf.String(), err
+}
+
+// result marks the result as safe HTML, since it was already escaped when the nested template was executed.
+func (e *htmlExecutor) result(str string) interface{} {
+	return htmltemplate.HTML(str)
+}
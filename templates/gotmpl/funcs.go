@@ -0,0 +1,66 @@
+package gotmpl
+
+import (
+	"fmt"
+	"regexp"
+	"text/template"
+)
+
+var stringLiteral = regexp.MustCompile(`"([^"]*)"|` + "`([^`]*)`")
+
+// defineFuncFn is the function behind the 'defineFunc' directive. Functions are declared before parsing the template,
+// so it renders nothing.
+func defineFuncFn(_ string, _ ...string) string {
+	return ""
+}
+
+// declaredFuncs returns the functions declared by the template and its definitions with
+// {{ defineFunc "name" "param1" "param2" }}, by name, with the names of their parameters.
+func (t *Template) declaredFuncs() map[string][]string {
+	left, right := t.delims()
+	re := regexp.MustCompile(regexp.QuoteMeta(left) + `-?\s*defineFunc((?:\s+(?:"[^"]*"|` + "`[^`]*`" + `))+)\s*-?` + regexp.QuoteMeta(right))
+
+	ret := map[string][]string{}
+	sources := []string{t.Template}
+	for _, v := range t.Definitions {
+		sources = append(sources, v)
+	}
+	for _, src := range sources {
+		for _, match := range re.FindAllStringSubmatch(src, -1) {
+			var args []string
+			for _, arg := range stringLiteral.FindAllStringSubmatch(match[1], -1) {
+				args = append(args, arg[1]+arg[2])
+			}
+			ret[args[0]] = args[1:]
+		}
+	}
+	return ret
+}
+
+// funcMap returns the helpers of the given context along with the helpers of the enabled packs and the functions
+// declared by the template.
+func (t *Template) funcMap(ctx *helperContext) template.FuncMap {
+	ret := ctx.toMap()
+	for _, h := range t.PackHelpers() {
+		ret[h.Name] = h.Function
+	}
+	for name, params := range t.declaredFuncs() {
+		ret[name] = ctx.declaredFunc(name, params)
+	}
+	return ret
+}
+
+// declaredFunc returns the function declared with 'defineFunc', which executes the template by the same name with the
+// arguments by the names of the declared parameters. The arguments are also available as a list in '.args'.
+func (h *helperContext) declaredFunc(name string, params []string) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) != len(params) {
+			return "", fmt.Errorf("wrong number of arguments for '%s', expected %d but got %d", name, len(params), len(args))
+		}
+		data := map[string]interface{}{"args": args}
+		for i, p := range params {
+			data[p] = args[i]
+		}
+		return h.invoke(name, data)
+	}
+}
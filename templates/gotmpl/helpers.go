@@ -1,41 +1,64 @@
 package gotmpl
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io/fs"
 	"reflect"
+	"sync"
 	"text/template"
 
 	"github.com/jucardi/go-streams/streams"
 	"github.com/jucardi/infuse/templates/helpers"
+	"github.com/jucardi/infuse/util/loader"
 	"github.com/jucardi/infuse/util/log"
 	"github.com/jucardi/infuse/util/maps"
 	"github.com/jucardi/infuse/util/reflectx"
 )
 
-var instance *helperContext
+var (
+	instance *helperContext
+	once     sync.Once
+)
 
+// getHelpers returns the singleton helper context, which is initialized once since templates may be rendered
+// concurrently, e.g: by the jobs of a manifest.
 func getHelpers() *helperContext {
-	if instance == nil {
+	once.Do(func() {
 		instance = &helperContext{
 			IHelpersManager: helpers.New(),
 		}
 		instance.init()
-	}
+	})
 	return instance
 }
 
+// Helpers returns the singleton helpers instance used for Go templates
+func Helpers() helpers.IHelpersManager {
+	return getHelpers()
+}
+
 type helperContext struct {
 	helpers.IHelpersManager
-	*template.Template
-	loaded map[string]*template.Template
+	executor executor
+	fs       fs.FS
+	plugins  *helpers.PluginCache
 }
 
-func (h *helperContext) setTemplate(tmpl *template.Template) {
-	h.Template = tmpl
+// newContext creates a helper context bound to a single render, sharing the registered helpers, so concurrent
+// renders do not share the template used by helpers such as 'include' or 'invoke'. Files included by the template
+// are loaded from the given file system, or the OS file system if nil.
+func (h *helperContext) newContext(fsys fs.FS) *helperContext {
+	return &helperContext{
+		IHelpersManager: h.IHelpersManager,
+		fs:              fsys,
+		plugins:         helpers.NewPluginCache(),
+	}
+}
+
+func (h *helperContext) setExecutor(e executor) {
+	h.executor = e
 }
 
 func (h *helperContext) toMap() template.FuncMap {
@@ -43,9 +66,32 @@ func (h *helperContext) toMap() template.FuncMap {
 	for _, v := range h.Get() {
 		ret[v.Name] = v.Function
 	}
+	for name, fn := range h.templateBound() {
+		if _, ok := ret[name]; ok {
+			ret[name] = fn
+		}
+	}
+	plugins := h.plugins
+	if plugins == nil {
+		plugins = helpers.NewPluginCache()
+	}
+	for _, name := range helpers.PluginNames() {
+		ret[name] = plugins.Func(name)
+	}
 	return ret
 }
 
+// templateBound returns the helpers that depend on the template being rendered, bound to this context.
+func (h *helperContext) templateBound() template.FuncMap {
+	return template.FuncMap{
+		"include":         h.includeFile,
+		"includeAsString": h.includeTemplate,
+		"invoke":          h.invoke,
+		"parse":           h.parse,
+		"parseXpath":      h.parseXpath,
+	}
+}
+
 func (h *helperContext) init() {
 	helpers.RegisterCommon(h)
 	_ = h.Register("default", h.defaultFn, "The first argument should be a default value, and the second argument is a value that will be evaluated. If arg2 is a zero value, returns arg1, otherwise returns arg2")
@@ -64,6 +110,8 @@ func (h *helperContext) init() {
 	_ = h.Register("invoke", h.invoke, `Similar to {{ template [name] [data] }}, invokes a name by the given name with the given data. The difference with 'template' is that 'invoke' can be used with a string value as the name instead of a hardcoded string`)
 	_ = h.Register("parse", h.parse, `Attempts to parse the provided template contents using the provided data object and returns the parsed value. Usage {{ parse [obj] [template contents] }}`)
 	_ = h.Register("parseXpath", h.parseXpath, `Attempts to parse a value inside a data object as a template and returns the parsed value using the same entry object to parse the template. Usage {{ parse [obj] [xpath to value with template] }}`)
+	_ = h.Register("extends", extendsFn, `Declares the layout extended by the template, which must be loaded as a definition. The layout is rendered instead of the template, using the blocks redefined by the template with {{ define }}. Usage: {{ extends "base.tmpl" }}`)
+	_ = h.Register("defineFunc", defineFuncFn, `Declares the template defined by the same name as a function, which receives the declared parameters by name. Usage: {{ defineFunc "fullName" "first" "last" }}{{ define "fullName" }}{{ .first }} {{ .last }}{{ end }}`)
 	_ = h.Register("in", h.in, `Indicates whether a value is contained in an array. Usage:  {{ in [array] [value] }}`)
 }
 
@@ -185,24 +233,20 @@ func (h *helperContext) mapFn(args ...interface{}) map[string]interface{} {
 }
 
 func (h *helperContext) includeFile(name, file string) (string, error) {
-	templateData, err := ioutil.ReadFile(file)
+	templateData, err := loader.LoadTemplateFS(h.fs, file)
 	if err != nil {
 		return "", fmt.Errorf("error including template file %s, %s", file, err.Error())
 	}
-	tmpl, err := h.Template.New(name).Parse(string(templateData))
-	if err != nil {
+	if err := h.executor.define(name, templateData); err != nil {
 		return "", fmt.Errorf("error parsing template file %s, %s", file, err.Error())
 	}
-	h.Template = tmpl
 	return "", nil
 }
 
 func (h *helperContext) includeTemplate(name, contents string) (string, error) {
-	tmpl, err := h.Template.New(name).Parse(contents)
-	if err != nil {
+	if err := h.executor.define(name, contents); err != nil {
 		return "", fmt.Errorf("error parsing template by name %s, %s", name, err.Error())
 	}
-	h.Template = tmpl
 	return "", nil
 }
 
@@ -242,17 +286,15 @@ func (h *helperContext) loadJson(str string) map[string]interface{} {
 	return ret
 }
 
-func (h *helperContext) invoke(name string, data interface{}) (string, error) {
-	tmpl := h.Template.Lookup(name)
-	if tmpl == nil {
+func (h *helperContext) invoke(name string, data interface{}) (interface{}, error) {
+	if !h.executor.contains(name) {
 		return "", fmt.Errorf("failed to invoke template '%s', not found", name)
 	}
-	buf := &bytes.Buffer{}
-	err := tmpl.Execute(buf, data)
-	return buf.String(), err
+	ret, err := h.executor.execute(name, data)
+	return h.executor.result(ret), err
 }
 
-func (h *helperContext) parse(data interface{}, templateData string, failOnEmptyResult ...bool) (string, error) {
+func (h *helperContext) parse(data interface{}, templateData string, failOnEmptyResult ...bool) (interface{}, error) {
 	if templateData == "" {
 		if len(failOnEmptyResult) > 0 && failOnEmptyResult[0] {
 			return "", errors.New("template produced empty result")
@@ -260,23 +302,19 @@ func (h *helperContext) parse(data interface{}, templateData string, failOnEmpty
 		return "", nil
 	}
 	name := "__internal/parse/" + templateData
-	tmpl := h.Template.Lookup(name)
-	if tmpl == nil {
+	if !h.executor.contains(name) {
 		if _, err := h.includeTemplate(name, templateData); err != nil {
 			return "", err
 		}
-		tmpl = h.Template.Lookup(name)
 	}
-	buf := &bytes.Buffer{}
-	err := tmpl.Execute(buf, data)
-	ret := buf.String()
+	ret, err := h.executor.execute(name, data)
 	if len(failOnEmptyResult) > 0 && failOnEmptyResult[0] && ret == "" && err == nil {
 		return "", errors.New("template produced empty result")
 	}
-	return ret, err
+	return h.executor.result(ret), err
 }
 
-func (h *helperContext) parseXpath(data interface{}, xpath string, failOnEmptyResult ...bool) (string, error) {
+func (h *helperContext) parseXpath(data interface{}, xpath string, failOnEmptyResult ...bool) (interface{}, error) {
 	templateData, ok := h.mapGetFn(data, xpath).(string)
 	if !ok {
 		return "", fmt.Errorf("failed to obtain template data, the provided object does not contain a string at the provided key '%s'", xpath)
@@ -0,0 +1,168 @@
+package gotmpl
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"text/template/parse"
+
+	"github.com/jucardi/infuse/templates"
+	"github.com/jucardi/infuse/util/loader"
+)
+
+// TypeGoHTML is the type for Go HTML templates, which escape the output according to the context where each value is
+// rendered.
+const TypeGoHTML = "gohtml"
+
+func init() {
+	templates.Factory().Register(TypeGoHTML, func(name ...string) templates.ITemplate { return NewHTML(name...) })
+}
+
+// HTMLTemplate represents the implementation of ITemplate for Go templates using html/template, which provides
+// contextual auto-escaping of the rendered values. Shares the helpers and definitions handling of the Go templates.
+type HTMLTemplate struct {
+	*Template
+}
+
+// Type returns the template type of this instance.
+func (t *HTMLTemplate) Type() string {
+	return TypeGoHTML
+}
+
+// Parse parses the template
+func (t *HTMLTemplate) Parse(writer io.Writer, data interface{}) error {
+	ctx := getHelpers().newContext(t.FS)
+	tmpl := template.New(t.NameStr).Delims(t.LeftDelim, t.RightDelim).Funcs(template.FuncMap(t.funcMap(ctx)))
+	if t.Strict {
+		tmpl.Option("missingkey=error")
+	}
+	name, err := t.parseAll(func(name, str string) error {
+		target := tmpl
+		if name != tmpl.Name() {
+			target = tmpl.New(name)
+		}
+		_, err := target.Parse(str)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := ctx.preloadIncludes(tmpl); err != nil {
+		return err
+	}
+
+	e, err := newHTMLExecutor(tmpl)
+	if err != nil {
+		return err
+	}
+	ctx.setExecutor(e)
+
+	return tmpl.ExecuteTemplate(writer, name, data)
+}
+
+// NewHTML creates a new Go HTML template, which extends the default built in functions for Go templates.
+func NewHTML(name ...string) *HTMLTemplate {
+	ht := &HTMLTemplate{Template: New(name...)}
+	ht.IAbstractTemplateMembers = ht
+	return ht
+}
+
+// preloadIncludes parses the templates included with constant arguments before the template is executed, e.g:
+// {{ include "x" "x.html" }}, since html/template fails to execute a 'template' action that references a template
+// defined during the execution. The included templates are looked up in the templates they define as well.
+func (h *helperContext) preloadIncludes(tmpl *template.Template) error {
+	visited := map[string]bool{}
+	for added := true; added; {
+		added = false
+		for _, t := range tmpl.Templates() {
+			if visited[t.Name()] || t.Tree == nil {
+				continue
+			}
+			visited[t.Name()] = true
+
+			var err error
+			walkCommands(t.Tree.Root, func(cmd *parse.CommandNode) {
+				name, load, ok := h.constantInclude(cmd)
+				if !ok || err != nil || tmpl.Lookup(name) != nil {
+					return
+				}
+				var contents string
+				if contents, err = load(); err == nil {
+					_, err = tmpl.New(name).Parse(contents)
+					added = true
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// constantInclude returns the name of the template defined by an 'include' or 'includeAsString' command with constant
+// arguments, and the function that loads its contents.
+func (h *helperContext) constantInclude(cmd *parse.CommandNode) (string, func() (string, error), bool) {
+	if len(cmd.Args) != 3 {
+		return "", nil, false
+	}
+	fn, isIdent := cmd.Args[0].(*parse.IdentifierNode)
+	name, isName := cmd.Args[1].(*parse.StringNode)
+	arg, isArg := cmd.Args[2].(*parse.StringNode)
+	if !isIdent || !isName || !isArg {
+		return "", nil, false
+	}
+
+	switch fn.Ident {
+	case "include":
+		return name.Text, func() (string, error) {
+			contents, err := loader.LoadTemplateFS(h.fs, arg.Text)
+			if err != nil {
+				return "", fmt.Errorf("error including template file %s, %s", arg.Text, err.Error())
+			}
+			return contents, nil
+		}, true
+	case "includeAsString":
+		return name.Text, func() (string, error) { return arg.Text, nil }, true
+	}
+	return "", nil, false
+}
+
+// walkCommands calls the function with every command of the parse tree, including the commands nested in pipelines.
+func walkCommands(node parse.Node, fn func(cmd *parse.CommandNode)) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkCommands(child, fn)
+		}
+	case *parse.ActionNode:
+		walkCommands(n.Pipe, fn)
+	case *parse.TemplateNode:
+		walkCommands(n.Pipe, fn)
+	case *parse.IfNode:
+		walkCommands(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkCommands(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkCommands(&n.BranchNode, fn)
+	case *parse.BranchNode:
+		walkCommands(n.Pipe, fn)
+		walkCommands(n.List, fn)
+		walkCommands(n.ElseList, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			walkCommands(cmd, fn)
+		}
+	case *parse.CommandNode:
+		fn(n)
+		for _, arg := range n.Args {
+			walkCommands(arg, fn)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package gotmpl
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// extendsFn is the function behind the 'extends' directive. The directive is resolved before parsing the template,
+// so it renders nothing.
+func extendsFn(_ string) string {
+	return ""
+}
+
+// parent returns the name of the layout declared by the given template source with {{ extends "name" }}, or an empty
+// string if the template does not extend a layout.
+func (t *Template) parent(src string) string {
+	left, right := t.delims()
+	re := regexp.MustCompile(regexp.QuoteMeta(left) + `-?\s*extends\s+(?:"([^"]*)"|` + "`([^`]*)`" + `)\s*-?` + regexp.QuoteMeta(right))
+	match := re.FindStringSubmatch(src)
+	if match == nil {
+		return ""
+	}
+	return match[1] + match[2]
+}
+
+// layouts returns the chain of layouts extended by the template, from the root layout to the direct parent of the
+// template. Layouts must be loaded as definitions.
+func (t *Template) layouts() ([]string, error) {
+	var (
+		ret  []string
+		seen = map[string]bool{t.NameStr: true}
+		name = t.parent(t.Template)
+	)
+	for name != "" {
+		if seen[name] {
+			return nil, fmt.Errorf("circular layout reference, '%s' extends itself", name)
+		}
+		seen[name] = true
+		src, ok := t.Definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("layout '%s' not found, layouts must be loaded as definitions", name)
+		}
+		ret = append([]string{name}, ret...)
+		name = t.parent(src)
+	}
+	return ret, nil
+}
+
+// parseAll parses the definitions, the layouts and the template using the provided function, which parses the given
+// contents as the template by the given name. Returns the name of the template to execute. Layouts are parsed from the
+// root down to the template, each one separately, so the blocks redefined by a template override the ones of the
+// layout it extends. If the template extends a layout, the root layout is the one executed.
+func (t *Template) parseAll(parse func(name, contents string) error) (string, error) {
+	layouts, err := t.layouts()
+	if err != nil {
+		return "", err
+	}
+	if err := parse(t.NameStr, t.prepare(layouts...)); err != nil {
+		return "", err
+	}
+	for _, name := range t.definesFiles(layouts) {
+		if err := parse(name, t.Definitions[name]+"\n"); err != nil {
+			return "", err
+		}
+	}
+	for _, name := range layouts {
+		if err := parse(name, t.Definitions[name]+"\n"); err != nil {
+			return "", err
+		}
+	}
+	if err := parse(t.NameStr, t.Template+"\n"); err != nil {
+		return "", err
+	}
+	if len(layouts) > 0 {
+		return layouts[0], nil
+	}
+	return t.NameStr, nil
+}
+
+// definesFiles returns the names of the definitions that declare their own {{define}} blocks, which are not wrapped by
+// prepare, sorted so they are parsed in a consistent order.
+func (t *Template) definesFiles(layouts []string) (ret []string) {
+	for k, v := range t.Definitions {
+		if k != t.NameStr && !isLayout(k, layouts) && t.hasDefines(k, v) {
+			ret = append(ret, k)
+		}
+	}
+	sort.Strings(ret)
+	return
+}
+
+func isLayout(name string, layouts []string) bool {
+	for _, l := range layouts {
+		if l == name {
+			return true
+		}
+	}
+	return false
+}
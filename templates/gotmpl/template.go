@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"text/template"
+	"text/template/parse"
 
 	"github.com/jucardi/go-strings/stringx"
 	"github.com/jucardi/infuse/templates"
@@ -30,44 +31,58 @@ func (t *Template) Type() string {
 
 // Parse parses the template
 func (t *Template) Parse(writer io.Writer, data interface{}) error {
-	str := t.prepare()
-	tmpl := template.New(t.NameStr).Funcs(getHelpers().toMap())
-	getHelpers().setTemplate(tmpl)
-	if _, err := tmpl.Parse(str); err != nil {
+	ctx := getHelpers().newContext(t.FS)
+	tmpl := template.New(t.NameStr).Delims(t.LeftDelim, t.RightDelim).Funcs(t.funcMap(ctx))
+	ctx.setExecutor(&textExecutor{Template: tmpl})
+	if t.Strict {
+		tmpl.Option("missingkey=error")
+	}
+	name, err := t.parseAll(func(name, str string) error {
+		target := tmpl
+		if name != tmpl.Name() {
+			target = tmpl.New(name)
+		}
+		_, err := target.Parse(str)
+		return err
+	})
+	if err != nil {
 		return err
 	}
 
-	return tmpl.Execute(writer, data)
+	return tmpl.ExecuteTemplate(writer, name, data)
 }
 
 // LoadTemplate loads the given string as the template to be parsed.
 func (t *Template) LoadTemplate(tmpl string) error {
-	return validate(t.NameStr, tmpl, func() {
+	return t.validate(t.NameStr, tmpl, func() {
 		t.Template = tmpl
 	})
 }
 
 // LoadDefinition loads the give template string as a definition {{define "name"}}, using the given name as the name of the definition, to be used for 'template' directives.
 func (t *Template) LoadDefinition(name, tmpl string) error {
-	return validate(name, tmpl, func() {
+	return t.validate(name, tmpl, func() {
 		t.Definitions[name] = tmpl
 	})
 }
 
-func (t *Template) prepare() string {
+// prepare wraps the definitions into {{define}} directives, skipping the provided layouts and the definitions that
+// already declare {{define}} blocks, which are parsed as they are.
+func (t *Template) prepare(layouts ...string) string {
 	builder := stringx.Builder()
+	left, right := t.delims()
 
 	for k, v := range t.Definitions {
-		if k == t.NameStr {
+		if k == t.NameStr || isLayout(k, layouts) || t.hasDefines(k, v) {
 			continue
 		}
 		builder.
 			AppendLine().
-			AppendLinef("{{define \"%s\"}}", k).
+			AppendLinef("%sdefine \"%s\"%s", left, k, right).
 			AppendLine(v).
-			AppendLine("{{end}}")
+			AppendLinef("%send%s", left, right)
 	}
-	return builder.AppendLine(t.Template).Build()
+	return builder.Build()
 }
 
 func (t *Template) Helpers() (ret []*helpers.Helper) {
@@ -94,9 +109,13 @@ func (t *Template) Helpers() (ret []*helpers.Helper) {
 
 	registered := getHelpers().Get()
 	for _, h := range registered {
-		h.Category = "Extensions"
+		if h.Category == "" {
+			h.Category = "Extensions"
+		}
 	}
 	ret = append(ret, registered...)
+	ret = append(ret, helpers.PluginHelpers()...)
+	ret = t.WithPackHelpers(ret)
 
 	return
 }
@@ -113,10 +132,40 @@ func New(name ...string) *Template {
 	return gt
 }
 
-func validate(name, tmpl string, successFn func()) error {
-	_, err := template.New(name).Funcs(getHelpers().toMap()).Parse(tmpl)
+// delims returns the delimiters of the template, or the default ones if not set
+func (t *Template) delims() (string, string) {
+	left, right := t.LeftDelim, t.RightDelim
+	if left == "" {
+		left = "{{"
+	}
+	if right == "" {
+		right = "}}"
+	}
+	return left, right
+}
 
-	if err != nil {
+// hasDefines indicates whether the given definition declares {{define}} or {{block}} directives, e.g: a template that
+// extends a layout matched by the definition patterns.
+func (t *Template) hasDefines(name, tmpl string) bool {
+	left, right := t.delims()
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+
+	trees := map[string]*parse.Tree{}
+	if _, err := tree.Parse(tmpl, left, right, trees); err != nil {
+		return false
+	}
+	return len(trees) > 1
+}
+
+// validate parses the given template without checking that the functions it uses exist, since functions declared with
+// 'defineFunc' may be declared by definitions loaded afterwards. Functions are checked when the template is parsed.
+func (t *Template) validate(name, tmpl string, successFn func()) error {
+	left, right := t.delims()
+	tree := parse.New(name)
+	tree.Mode = parse.SkipFuncCheck
+
+	if _, err := tree.Parse(tmpl, left, right, map[string]*parse.Tree{}); err != nil {
 		return fmt.Errorf("unable to load definition '%s', %v", name, err)
 	}
 
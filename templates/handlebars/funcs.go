@@ -0,0 +1,75 @@
+package handlebars
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aymerick/raymond"
+	"github.com/jucardi/infuse/templates/helpers"
+)
+
+var (
+	defineFuncRe  = regexp.MustCompile(`\{\{~?#defineFunc((?:\s+"[^"]*")+)\s*~?\}\}([\s\S]*?)\{\{~?/defineFunc\s*~?\}\}`)
+	stringLiteral = regexp.MustCompile(`"([^"]*)"`)
+)
+
+// declaredFunc is a function declared by a template with {{#defineFunc "name" "param1"}}body{{/defineFunc}}
+type declaredFunc struct {
+	name   string
+	params []string
+	body   string
+}
+
+// declaredFuncs returns the functions declared by the given source, and the source without the declarations, since
+// the declarations are resolved before parsing the template.
+func declaredFuncs(src string) (string, []*declaredFunc) {
+	var ret []*declaredFunc
+	for _, match := range defineFuncRe.FindAllStringSubmatch(src, -1) {
+		var args []string
+		for _, arg := range stringLiteral.FindAllStringSubmatch(match[1], -1) {
+			args = append(args, arg[1])
+		}
+		ret = append(ret, &declaredFunc{name: args[0], params: args[1:], body: match[2]})
+	}
+	return defineFuncRe.ReplaceAllString(src, ""), ret
+}
+
+// registerFuncs registers the given functions as helpers of the template. The functions render their body with the
+// arguments by the names of the declared parameters, and can call each other.
+func registerFuncs(tpl *raymond.Template, fns []*declaredFunc) error {
+	targets := []*raymond.Template{tpl}
+	bodies := map[string]*raymond.Template{}
+	for _, fn := range fns {
+		if _, ok := bodies[fn.name]; ok {
+			return fmt.Errorf("function '%s' is declared more than once", fn.name)
+		}
+		body, err := raymond.Parse(fn.body)
+		if err != nil {
+			return fmt.Errorf("unable to parse the body of function '%s', %v", fn.name, err)
+		}
+		bodies[fn.name] = body
+		targets = append(targets, body)
+	}
+
+	for _, fn := range fns {
+		helper := funcHelper(bodies[fn.name], fn)
+		for _, target := range targets {
+			target.RegisterHelper(fn.name, helper)
+		}
+	}
+	return nil
+}
+
+func funcHelper(body *raymond.Template, fn *declaredFunc) interface{} {
+	return helpers.FixedArity(len(fn.params), func(args ...interface{}) interface{} {
+		data := map[string]interface{}{"args": args}
+		for i, p := range fn.params {
+			data[p] = args[i]
+		}
+		ret, err := body.Exec(data)
+		if err != nil {
+			panic(fmt.Errorf("function '%s' failed, %v", fn.name, err))
+		}
+		return raymond.SafeString(ret)
+	})
+}
@@ -1,10 +1,18 @@
 package handlebars
 
 import (
+	"fmt"
+	"reflect"
+	"sync"
+
 	"github.com/aymerick/raymond"
+	"github.com/aymerick/raymond/ast"
+	"github.com/aymerick/raymond/parser"
 	"github.com/jucardi/infuse/templates/helpers"
 )
 
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
 type hbHelpersManager struct {
 	helpers.IHelpersManager
 }
@@ -14,7 +22,7 @@ func (h *hbHelpersManager) Register(name string, fn interface{}, description ...
 	if err := h.IHelpersManager.Register(name, fn, description...); err != nil {
 		return err
 	}
-	raymond.RegisterHelper(name, fn)
+	raymond.RegisterHelper(name, contextFallback(name, fn))
 	return nil
 }
 
@@ -23,19 +31,200 @@ func (h *hbHelpersManager) Remove(name string) {
 
 }
 
-var instance *hbHelpersManager
+var (
+	instance *hbHelpersManager
+	once     sync.Once
+)
 
 // Helpers returns the singleton helpers instance used for Go templates
 func Helpers() helpers.IHelpersManager {
-	if instance == nil {
-		base := helpers.New()
+	once.Do(func() {
 		instance = &hbHelpersManager{
-			IHelpersManager: base,
+			IHelpersManager: helpers.New(),
 		}
-	}
+	})
 	return instance
 }
 
+// singleResult adapts a helper that returns a value and an error to handlebars, which requires helpers to return a
+// single value. The error is raised as a panic, which fails the render with the error.
+func singleResult(fn interface{}) interface{} {
+	fnVal := reflect.ValueOf(fn)
+	fnType := fnVal.Type()
+	if fnType.Kind() != reflect.Func || fnType.NumOut() != 2 || fnType.Out(1) != errorType {
+		return fn
+	}
+	in := make([]reflect.Type, fnType.NumIn())
+	for i := range in {
+		in[i] = fnType.In(i)
+	}
+	adapted := reflect.FuncOf(in, []reflect.Type{fnType.Out(0)}, fnType.IsVariadic())
+	return reflect.MakeFunc(adapted, func(args []reflect.Value) []reflect.Value {
+		var out []reflect.Value
+		if fnType.IsVariadic() {
+			out = fnVal.CallSlice(args)
+		} else {
+			out = fnVal.Call(args)
+		}
+		if err := out[1]; !err.IsNil() {
+			panic(err.Interface())
+		}
+		return out[:1]
+	}).Interface()
+}
+
+// contextFallback adapts a helper that receives at most one argument, so when it is called without arguments and the
+// current context has a field by the same name, the field is rendered instead of calling the helper. Otherwise helpers
+// such as 'first', 'title' or 'now' would shadow the fields of the data by the same names, e.g: {{first}}. The other
+// helpers are adapted by singleResult.
+func contextFallback(name string, fn interface{}) interface{} {
+	fnType := reflect.TypeOf(fn)
+	fixed := fnType.NumIn()
+	if fnType.IsVariadic() {
+		fixed--
+	}
+	if fixed > 1 || fixed == 1 && fnType.IsVariadic() {
+		return singleResult(fn)
+	}
+	return helpers.FixedArity(1, func(args ...interface{}) interface{} {
+		if _, ok := args[0].(*raymond.Options); !ok && !fnType.IsVariadic() && fixed == 1 {
+			// Converts the argument as handlebars does for the helpers that receive a string or a bool
+			argType := fnType.In(0)
+			if arg := args[0]; arg == nil || !reflect.TypeOf(arg).AssignableTo(argType) {
+				switch argType.Kind() {
+				case reflect.String:
+					args[0] = raymond.Str(arg)
+				case reflect.Bool:
+					args[0] = raymond.IsTrue(arg)
+				}
+			}
+		}
+		return callHelper(name, fn, args)
+	})
+}
+
+// callHelper calls the helper with the given arguments, removing the options that handlebars appends when a helper is
+// called with one argument less than it receives. If the helper is called without arguments and the current context
+// has a field by the same name, the value of the field is returned instead.
+func callHelper(name string, fn interface{}, args []interface{}) interface{} {
+	if n := len(args); n > 0 {
+		if options, ok := args[n-1].(*raymond.Options); ok {
+			args = args[:n-1]
+			if len(args) == 0 {
+				if value := options.Value(name); value != nil {
+					return value
+				}
+			}
+		}
+	}
+	ret, err := helpers.Call(fn, args...)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// pluginHelper returns the plugin helper by the given name as a handlebars helper. Since handlebars helpers have a
+// fixed number of arguments, plugin helpers receive the hash arguments only. E.g: {{vault key="x"}}
+func pluginHelper(plugins *helpers.PluginCache, name string) func(options *raymond.Options) interface{} {
+	return func(options *raymond.Options) interface{} {
+		ret, err := plugins.Call(name, nil, options.Hash())
+		if err != nil {
+			panic(err)
+		}
+		return ret
+	}
+}
+
+// fixedArityHelper adapts a helper of a pack or a variadic helper to handlebars, which requires a fixed number of
+// arguments. Variadic helpers receive the number of arguments they are called with by the template, given the distinct
+// numbers of arguments of their calls. The arguments are converted to the types expected by the helper. Helpers called
+// without arguments render the field of the context by the same name if it is set, see callHelper.
+func fixedArityHelper(name string, fn interface{}, arities map[int]bool) (interface{}, error) {
+	fnType := reflect.TypeOf(fn)
+	arity := fnType.NumIn()
+	if fnType.IsVariadic() {
+		arity--
+		for n := range arities {
+			if n > arity {
+				arity = n
+			}
+		}
+		for n := range arities {
+			if n != arity && n != arity-1 {
+				return nil, fmt.Errorf("helper '%s' is called with %d and %d arguments, handlebars requires a fixed number of arguments", name, n, arity)
+			}
+		}
+	}
+	if arities[0] && arity != 1 {
+		if arity > 1 && len(arities) > 1 {
+			return nil, fmt.Errorf("helper '%s' is called with 0 and %d arguments, handlebars requires a fixed number of arguments", name, arity)
+		}
+		// Receives the options when called without arguments, to look up the field by the same name
+		arity = 1
+	}
+	return helpers.FixedArity(arity, func(args ...interface{}) interface{} {
+		return callHelper(name, fn, args)
+	}), nil
+}
+
+// helperArities returns the distinct numbers of arguments each helper is called with by the given template.
+func helperArities(src string) (map[string]map[int]bool, error) {
+	program, err := parser.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]map[int]bool{}
+
+	var walk func(node ast.Node)
+	walk = func(node ast.Node) {
+		switch n := node.(type) {
+		case *ast.Program:
+			for _, statement := range n.Body {
+				walk(statement)
+			}
+		case *ast.MustacheStatement:
+			walk(n.Expression)
+		case *ast.BlockStatement:
+			walk(n.Expression)
+			if n.Program != nil {
+				walk(n.Program)
+			}
+			if n.Inverse != nil {
+				walk(n.Inverse)
+			}
+		case *ast.PartialStatement:
+			for _, p := range n.Params {
+				walk(p)
+			}
+			if n.Hash != nil {
+				walk(n.Hash)
+			}
+		case *ast.SubExpression:
+			walk(n.Expression)
+		case *ast.Expression:
+			if name := n.HelperName(); name != "" {
+				if ret[name] == nil {
+					ret[name] = map[int]bool{}
+				}
+				ret[name][len(n.Params)] = true
+			}
+			for _, p := range n.Params {
+				walk(p)
+			}
+			if n.Hash != nil {
+				walk(n.Hash)
+			}
+		case *ast.Hash:
+			for _, pair := range n.Pairs {
+				walk(pair.Val)
+			}
+		}
+	}
+	walk(program)
+	return ret, nil
+}
+
 func init() {
 	helpers.RegisterCommon(Helpers())
 }
@@ -1,6 +1,9 @@
 package handlebars
 
 import (
+	"errors"
+	"reflect"
+
 	"github.com/aymerick/raymond"
 	"github.com/jucardi/go-strings/stringx"
 	"github.com/jucardi/infuse/templates"
@@ -26,9 +29,65 @@ func (t *Template) Type() string {
 	return "handlebars"
 }
 
+// SetDelims returns an error if custom delimiters are specified, since they are not supported by handlebars templates.
+func (t *Template) SetDelims(left, right string) error {
+	if left != "" || right != "" {
+		return errors.New("custom delimiters are not supported by handlebars templates")
+	}
+	return nil
+}
+
 // Parse parses the template
 func (t *Template) Parse(writer io.Writer, data interface{}) error {
-	str, err := raymond.Render(t.Template, data)
+	src, fns := declaredFuncs(t.Template)
+	for _, def := range t.Definitions {
+		_, declared := declaredFuncs(def)
+		fns = append(fns, declared...)
+	}
+	tpl, err := raymond.Parse(src)
+	if err != nil {
+		return err
+	}
+	if err := registerFuncs(tpl, fns); err != nil {
+		return err
+	}
+	registered := map[string]bool{}
+	for _, fn := range fns {
+		registered[fn.name] = true
+	}
+	arities, err := helperArities(src)
+	if err != nil {
+		return err
+	}
+	for _, h := range t.PackHelpers() {
+		if registered[h.Name] {
+			continue
+		}
+		fn, err := fixedArityHelper(h.Name, h.Function, arities[h.Name])
+		if err != nil {
+			return err
+		}
+		tpl.RegisterHelper(h.Name, fn)
+		registered[h.Name] = true
+	}
+	for _, h := range Helpers().Get() {
+		if registered[h.Name] || arities[h.Name] == nil || !reflect.TypeOf(h.Function).IsVariadic() && !arities[h.Name][0] {
+			continue
+		}
+		fn, err := fixedArityHelper(h.Name, h.Function, arities[h.Name])
+		if err != nil {
+			return err
+		}
+		tpl.RegisterHelper(h.Name, fn)
+		registered[h.Name] = true
+	}
+	plugins := helpers.NewPluginCache()
+	for _, name := range helpers.PluginNames() {
+		if !registered[name] {
+			tpl.RegisterHelper(name, pluginHelper(plugins, name))
+		}
+	}
+	str, err := tpl.Exec(data)
 	if err != nil {
 		return err
 	}
@@ -49,7 +108,7 @@ func (t *Template) LoadDefinition(name, tmpl string) error {
 }
 
 func (t *Template) Helpers() (ret []*helpers.Helper) {
-	return Helpers().Get()
+	return t.WithPackHelpers(append(Helpers().Get(), helpers.PluginHelpers()...))
 }
 
 // New creates a new template utility which extends the default built in functions for Go templates.
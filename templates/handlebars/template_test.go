@@ -0,0 +1,47 @@
+package handlebars
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jucardi/infuse/templates/helpers"
+)
+
+func render(t *testing.T, tmpl string, data interface{}) string {
+	t.Helper()
+	tpl := New("test")
+	tpl.Template = tmpl
+	var out strings.Builder
+	if err := tpl.Parse(&out, data); err != nil {
+		t.Fatalf("failed to render '%s', %v", tmpl, err)
+	}
+	return out.String()
+}
+
+func TestTimeHelpersDoNotShadowFields(t *testing.T) {
+	helpers.SetNow(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+	defer helpers.SetNow(time.Time{})
+
+	data := map[string]interface{}{"now": "yesterday", "date": "today"}
+	if ret, expected := render(t, "{{now}} {{date}}", data), "yesterday today"; ret != expected {
+		t.Fatalf("expected the fields '%s', got '%s'", expected, ret)
+	}
+	if ret, expected := render(t, `{{date "2006-01-02" (now)}}`, nil), "2024-01-02"; ret != expected {
+		t.Fatalf("expected the helpers to be called with arguments '%s', got '%s'", expected, ret)
+	}
+}
+
+func TestDictHelpersDoNotShadowFields(t *testing.T) {
+	data := map[string]interface{}{
+		"keys":   "K",
+		"values": "V",
+		"labels": map[string]interface{}{"b": "2", "a": "1"},
+	}
+	if ret, expected := render(t, "{{keys}} {{values}}", data), "K V"; ret != expected {
+		t.Fatalf("expected the fields '%s', got '%s'", expected, ret)
+	}
+	if ret, expected := render(t, "{{#each (keys labels)}}{{this}}{{/each}}", data), "ab"; ret != expected {
+		t.Fatalf("expected the helper to be called with arguments '%s', got '%s'", expected, ret)
+	}
+}
@@ -0,0 +1,124 @@
+package helpers
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Call invokes a helper function using reflection, converting the provided arguments to the types expected by the
+// function when possible (e.g. numeric conversions or []interface{} to typed slices). Supports variadic functions and
+// functions that return a value and an error. Panics raised by the helper are recovered and returned as errors.
+func Call(fn interface{}, args ...interface{}) (ret interface{}, err error) {
+	fnVal := reflect.ValueOf(fn)
+	if fnVal.Kind() != reflect.Func {
+		return nil, fmt.Errorf("wrong type %T, must be a function", fn)
+	}
+
+	in, err := callArgs(fnVal.Type(), args)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+
+	out := fnVal.Call(in)
+
+	switch len(out) {
+	case 0:
+		return nil, nil
+	case 1:
+		if out[0].Type() == errorType {
+			if !out[0].IsNil() {
+				return nil, out[0].Interface().(error)
+			}
+			return nil, nil
+		}
+		return out[0].Interface(), nil
+	default:
+		if last := out[len(out)-1]; last.Type() == errorType && !last.IsNil() {
+			return nil, last.Interface().(error)
+		}
+		return out[0].Interface(), nil
+	}
+}
+
+func callArgs(fnType reflect.Type, args []interface{}) ([]reflect.Value, error) {
+	numIn := fnType.NumIn()
+	if fnType.IsVariadic() {
+		if len(args) < numIn-1 {
+			return nil, fmt.Errorf("wrong number of arguments, expected at least %d but got %d", numIn-1, len(args))
+		}
+	} else if len(args) != numIn {
+		return nil, fmt.Errorf("wrong number of arguments, expected %d but got %d", numIn, len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var argType reflect.Type
+		if fnType.IsVariadic() && i >= numIn-1 {
+			argType = fnType.In(numIn - 1).Elem()
+		} else {
+			argType = fnType.In(i)
+		}
+		val, err := ConvertArg(arg, argType)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d, %v", i+1, err)
+		}
+		in[i] = val
+	}
+	return in, nil
+}
+
+// ConvertArg converts the provided argument to a value of the given type, if possible.
+func ConvertArg(arg interface{}, argType reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		switch argType.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+			return reflect.Zero(argType), nil
+		}
+		return reflect.Value{}, fmt.Errorf("nil is not a valid value for %v", argType)
+	}
+
+	val := reflect.ValueOf(arg)
+	if val.Type().AssignableTo(argType) {
+		return val, nil
+	}
+	if isNumber(val.Kind()) && isNumber(argType.Kind()) {
+		return val.Convert(argType), nil
+	}
+	if val.Kind() == reflect.String && argType.Kind() == reflect.String {
+		return val.Convert(argType), nil
+	}
+	if (val.Kind() == reflect.Slice || val.Kind() == reflect.Array) && argType.Kind() == reflect.Slice {
+		ret := reflect.MakeSlice(argType, val.Len(), val.Len())
+		for i := 0; i < val.Len(); i++ {
+			item, err := ConvertArg(val.Index(i).Interface(), argType.Elem())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			ret.Index(i).Set(item)
+		}
+		return ret, nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %T as %v", arg, argType)
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
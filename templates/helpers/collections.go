@@ -0,0 +1,365 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/jucardi/infuse/util/reflectx"
+)
+
+/** In this file are defined the list helpers of the common set. */
+
+// registerCollections registers the list helpers. They accept the []interface{} lists decoded from YAML or JSON as well
+// as typed slices and arrays. The list is the last argument, so the helpers can be used in pipelines, e.g:
+// {{ .services | where "enabled" true | sortBy "name" }}
+func registerCollections(manager IHelpersManager) {
+	_ = manager.Register("first", listFirst, "Returns the first element of a list, or nil if empty. E.g: {{ first .items }}")
+	_ = manager.Register("last", listLast, "Returns the last element of a list, or nil if empty. E.g: {{ last .items }}")
+	_ = manager.Register("rest", listRest, "Returns all the elements of a list but the first. E.g: {{ rest .items }}")
+	_ = manager.Register("slice", listSlice, "Slices a list or a string by the given indexes, as Go's slice expressions. E.g: {{ slice .items 1 3 }}")
+	_ = manager.Register("reverse", listReverse, "Returns a new list with the elements in reverse order. E.g: {{ reverse .items }}")
+	_ = manager.Register("uniq", listUniq, "Returns a new list without the repeated elements. E.g: {{ uniq .items }}")
+	_ = manager.Register("sortAlpha", listSortAlpha, "Sorts the string representations of the elements of a list alphabetically. E.g: {{ sortAlpha .names }}")
+	_ = manager.Register("sortBy", listSortBy, "Sorts a list of maps or structs by the value at the key, which can be a dotted path. Numbers are compared by value. E.g: {{ .services | sortBy \"spec.port\" }}")
+	_ = manager.Register("groupBy", listGroupBy, "Groups a list of maps or structs by the value at the key, returns a map of each value to the list of its elements. E.g: {{ range $tier, $items := groupBy \"tier\" .services }}")
+	_ = manager.Register("where", listWhere, "Returns the elements of a list of maps or structs whose value at the key is equal to the given value. E.g: {{ .services | where \"enabled\" true }}")
+	_ = manager.Register("pluck", listPluck, "Returns the values at the key of each element of a list of maps or structs which contains it. E.g: {{ .services | pluck \"name\" }}")
+	_ = manager.Register("compact", listCompact, "Returns a new list without the empty elements. E.g: {{ compact .items }}")
+	_ = manager.Register("concat", listConcat, "Concatenates the given lists. E.g: {{ concat .a .b }}")
+	_ = manager.Register("chunk", listChunk, "Splits a list into lists of the given size, the last one may be smaller. E.g: {{ range chunk 3 .items }}")
+	_ = manager.Register("flatten", listFlatten, "Flattens nested lists into a single list. E.g: {{ flatten .groups }}")
+	_ = manager.Register("zip", listZip, "Returns a list of pairs, or tuples, with the elements of the lists at the same position, as long as the shortest list. E.g: {{ range zip .names .ports }}")
+	_ = manager.Register("seq", listSeq, "Returns the numbers from start to end, both included, by the given step, 1 or -1 by default. E.g: {{ range seq 1 10 2 }}")
+}
+
+// toList converts an array or slice of any type to a []interface{}, panics if the value is not a list.
+func toList(v interface{}, fn string) []interface{} {
+	if v == nil {
+		return nil
+	}
+	if list, ok := v.([]interface{}); ok {
+		return list
+	}
+	val := reflect.ValueOf(v)
+	if kind := val.Kind(); kind != reflect.Slice && kind != reflect.Array {
+		panic(fmt.Errorf("cannot use '%s' on type %T", fn, v))
+	}
+	ret := make([]interface{}, val.Len())
+	for i := range ret {
+		ret[i] = val.Index(i).Interface()
+	}
+	return ret
+}
+
+func listFirst(v interface{}) interface{} {
+	list := toList(v, "first")
+	if len(list) == 0 {
+		return nil
+	}
+	return list[0]
+}
+
+func listLast(v interface{}) interface{} {
+	list := toList(v, "last")
+	if len(list) == 0 {
+		return nil
+	}
+	return list[len(list)-1]
+}
+
+func listRest(v interface{}) []interface{} {
+	list := toList(v, "rest")
+	if len(list) == 0 {
+		return nil
+	}
+	return append([]interface{}{}, list[1:]...)
+}
+
+// listSlice slices a list or string with the semantics of the 'slice' builtin of Go templates, which it replaces, and
+// accepts the indexes decoded from JSON as floats.
+func listSlice(v interface{}, indexes ...interface{}) interface{} {
+	val, err := reflectx.GetNonPointerValue(v)
+	if err != nil {
+		panic(fmt.Errorf("cannot slice %T, %v", v, err))
+	}
+	if len(indexes) > 3 {
+		panic(fmt.Errorf("too many slice indexes: %d", len(indexes)))
+	}
+
+	var length int
+	switch val.Kind() {
+	case reflect.String:
+		if len(indexes) == 3 {
+			panic(fmt.Errorf("cannot 3-index slice a string"))
+		}
+		length = val.Len()
+	case reflect.Array, reflect.Slice:
+		if !val.CanAddr() && val.Kind() == reflect.Array {
+			addressable := reflect.New(val.Type()).Elem()
+			addressable.Set(val)
+			val = addressable
+		}
+		length = val.Cap()
+	default:
+		panic(fmt.Errorf("cannot slice type %T", v))
+	}
+
+	idx := [3]int{0, val.Len(), length}
+	for i, index := range indexes {
+		idx[i] = int(toInteger(index))
+		if idx[i] < 0 || idx[i] > length {
+			panic(fmt.Errorf("index out of range: %d", idx[i]))
+		}
+	}
+	if idx[0] > idx[1] || idx[1] > idx[2] {
+		panic(fmt.Errorf("invalid slice indexes: %d > %d", idx[0], idx[1]))
+	}
+	if len(indexes) == 3 {
+		return val.Slice3(idx[0], idx[1], idx[2]).Interface()
+	}
+	return val.Slice(idx[0], idx[1]).Interface()
+}
+
+func listReverse(v interface{}) []interface{} {
+	list := toList(v, "reverse")
+	ret := make([]interface{}, len(list))
+	for i, item := range list {
+		ret[len(list)-1-i] = item
+	}
+	return ret
+}
+
+func listUniq(v interface{}) []interface{} {
+	var ret []interface{}
+	for _, item := range toList(v, "uniq") {
+		if !listContains(ret, item) {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func listSortAlpha(v interface{}) []string {
+	ret := toStrings(v)
+	sort.Strings(ret)
+	return ret
+}
+
+func listSortBy(key string, v interface{}) []interface{} {
+	ret := append([]interface{}{}, toList(v, "sortBy")...)
+	sort.SliceStable(ret, func(i, j int) bool {
+		a, _ := fieldValue(ret[i], key)
+		b, _ := fieldValue(ret[j], key)
+		return compareValues(a, b) < 0
+	})
+	return ret
+}
+
+func listGroupBy(key string, v interface{}) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for _, item := range toList(v, "groupBy") {
+		if value, ok := fieldValue(item, key); ok {
+			group := fmt.Sprint(value)
+			list, _ := ret[group].([]interface{})
+			ret[group] = append(list, item)
+		}
+	}
+	return ret
+}
+
+func listWhere(key string, value interface{}, v interface{}) []interface{} {
+	var ret []interface{}
+	for _, item := range toList(v, "where") {
+		if field, ok := fieldValue(item, key); ok && valuesEqual(field, value) {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func listPluck(key string, v interface{}) []interface{} {
+	var ret []interface{}
+	for _, item := range toList(v, "pluck") {
+		if field, ok := fieldValue(item, key); ok {
+			ret = append(ret, field)
+		}
+	}
+	return ret
+}
+
+func listCompact(v interface{}) []interface{} {
+	var ret []interface{}
+	for _, item := range toList(v, "compact") {
+		if !isEmpty(item) {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func listConcat(lists ...interface{}) []interface{} {
+	var ret []interface{}
+	for _, l := range lists {
+		ret = append(ret, toList(l, "concat")...)
+	}
+	return ret
+}
+
+func listChunk(size interface{}, v interface{}) []interface{} {
+	n := int(toInteger(size))
+	if n <= 0 {
+		panic(fmt.Errorf("the chunk size must be greater than 0, got %d", n))
+	}
+	var ret []interface{}
+	list := toList(v, "chunk")
+	for i := 0; i < len(list); i += n {
+		end := i + n
+		if end > len(list) {
+			end = len(list)
+		}
+		ret = append(ret, append([]interface{}{}, list[i:end]...))
+	}
+	return ret
+}
+
+func listFlatten(v interface{}) []interface{} {
+	var ret []interface{}
+	for _, item := range toList(v, "flatten") {
+		if kind := reflect.ValueOf(item).Kind(); kind == reflect.Slice || kind == reflect.Array {
+			ret = append(ret, listFlatten(item)...)
+		} else {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func listZip(lists ...interface{}) []interface{} {
+	if len(lists) == 0 {
+		return nil
+	}
+	converted := make([][]interface{}, len(lists))
+	length := -1
+	for i, l := range lists {
+		converted[i] = toList(l, "zip")
+		if length < 0 || len(converted[i]) < length {
+			length = len(converted[i])
+		}
+	}
+	ret := make([]interface{}, length)
+	for i := range ret {
+		tuple := make([]interface{}, len(converted))
+		for j, l := range converted {
+			tuple[j] = l[i]
+		}
+		ret[i] = tuple
+	}
+	return ret
+}
+
+func listSeq(start, end interface{}, step ...interface{}) []interface{} {
+	from, to := toRat(start), toRat(end)
+	inc := big.NewRat(1, 1)
+	if len(step) > 0 {
+		inc = toRat(step[0])
+	} else if from.Cmp(to) > 0 {
+		inc = big.NewRat(-1, 1)
+	}
+	if inc.Sign() == 0 {
+		panic(fmt.Errorf("the step of 'seq' cannot be 0"))
+	}
+	var ret []interface{}
+	for n := new(big.Rat).Set(from); n.Cmp(to)*inc.Sign() <= 0; n = new(big.Rat).Add(n, inc) {
+		ret = append(ret, fromRat(n))
+	}
+	return ret
+}
+
+// listContains indicates whether the list contains a value deeply equal to the given one.
+func listContains(list []interface{}, value interface{}) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldValue returns the value at the given key of a map or struct, which can be a dotted path to a nested value.
+func fieldValue(obj interface{}, key string) (interface{}, bool) {
+	current := obj
+	for _, part := range strings.Split(key, ".") {
+		val, err := reflectx.GetNonPointerValue(current)
+		if err != nil {
+			return nil, false
+		}
+		switch val.Kind() {
+		case reflect.Map:
+			k := reflect.ValueOf(part)
+			if !k.Type().ConvertibleTo(val.Type().Key()) {
+				return nil, false
+			}
+			item := val.MapIndex(k.Convert(val.Type().Key()))
+			if !item.IsValid() {
+				return nil, false
+			}
+			current = item.Interface()
+		case reflect.Struct:
+			field := reflectx.GetValue(val, part)
+			if !field.IsValid() || !field.CanInterface() {
+				return nil, false
+			}
+			current = field.Interface()
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// numberValue returns the given value as an exact number if it is a number, including json.Number but not strings.
+func numberValue(v interface{}) (*big.Rat, bool) {
+	switch v.(type) {
+	case string, nil:
+		return nil, false
+	case json.Number:
+		return ratOf(v)
+	}
+	if reflect.ValueOf(v).Kind() == reflect.String {
+		return nil, false
+	}
+	return ratOf(v)
+}
+
+// compareValues compares two values, numbers by their value and other values by their string representation. Nil
+// values are the lowest.
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if x, ok := numberValue(a); ok {
+		if y, ok := numberValue(b); ok {
+			return x.Cmp(y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// valuesEqual indicates whether the values are equal, numbers are compared by value regardless of their type.
+func valuesEqual(a, b interface{}) bool {
+	if x, ok := numberValue(a); ok {
+		if y, ok := numberValue(b); ok {
+			return x.Cmp(y) == 0
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/jucardi/go-streams/streams"
 	"github.com/jucardi/go-strings/stringx"
+	"github.com/jucardi/infuse/util/ioutils"
 	"github.com/jucardi/infuse/util/log"
 	"gopkg.in/yaml.v2"
 )
@@ -40,10 +41,9 @@ func RegisterCommon(manager IHelpersManager) {
 	_ = manager.Register("yaml", toYMLString, "Marshals the provided object as YAML")
 	_ = manager.Register("json", toJSONString, "Marshals the provided object as JSON")
 	_ = manager.Register("rem", comment, "Helper to add comments")
+	_ = manager.Register("file", ioutils.FileMarker, "Marks the beginning of a new output file in a multi-document render, the contents that follow are written to the provided path relative to the output directory. E.g: {{ file \"services/api.yaml\" }}")
 	_ = manager.Register("env", os.Getenv, "Returns the value set in the provided environment variable")
 	_ = manager.Register("stringArray", stringArray, "Creates an array of strings with the provided string args")
-	_ = manager.Register("mathAdd", mathAdd, "Adds all the provided numbers together and returns the result")
-	_ = manager.Register("mathMult", mathMult, "Multiplies all the provided numbers together and returns the result")
 	_ = manager.Register("indent", indent, "Indents a given string using the provided indentation")
 	_ = manager.Register("stringxCamelToDash", stringx.CamelToDash, "Converts a camelCase string to a dash-separated string")
 	_ = manager.Register("stringxCamelToSnake", stringx.CamelToSnake, "Converts a camelCase string to a snake_case string")
@@ -53,6 +53,31 @@ func RegisterCommon(manager IHelpersManager) {
 	_ = manager.Register("stringxPascalToSnake", stringx.PascalToSnake, "Converts a PascalCase string to a snake_case string")
 	_ = manager.Register("stringxDashToPascal", stringx.DashToPascal, "Converts a dash-separated string to a PascalCase string")
 	_ = manager.Register("stringxSnakeToPascal", stringx.SnakeToPascal, "Converts a SnakeToCamel string to a PascalCase string")
+	registerCategory(manager, "Math", registerMath)
+	registerCategory(manager, "Time", registerTime)
+	registerCategory(manager, "Encoding", registerEncoding)
+	registerCategory(manager, "Crypto", registerCrypto)
+	registerCategory(manager, "Regex", registerRegex)
+	registerCategory(manager, "Collections", registerCollections)
+	registerCategory(manager, "Dictionaries", registerDicts)
+	registerCategory(manager, "Query", registerQuery)
+	registerCategory(manager, "Semantic Versions", registerSemver)
+	registerCategory(manager, "Network", registerNetwork)
+}
+
+// registerCategory registers helpers with the provided function, setting the given category to the helpers it
+// registers so they are listed together by the 'helpers' command.
+func registerCategory(manager IHelpersManager, category string, register func(manager IHelpersManager)) {
+	previous := map[string]*Helper{}
+	for _, h := range manager.Get() {
+		previous[h.Name] = h
+	}
+	register(manager)
+	for _, h := range manager.Get() {
+		if previous[h.Name] != h {
+			h.Category = category
+		}
+	}
 }
 
 /** String helpers */
@@ -122,25 +147,6 @@ func stringArray(args ...string) []string {
 	return args
 }
 
-func mathAdd(nums ...int) int {
-	ret := 0
-	for _, n := range nums {
-		ret += n
-	}
-	return ret
-}
-
-func mathMult(nums ...int) int {
-	if len(nums) == 0 {
-		return 0
-	}
-	ret := 1
-	for _, n := range nums {
-		ret *= n
-	}
-	return ret
-}
-
 func indent(str string, indent string) string {
 	return strings.Replace(str, "\n", "\n"+indent, -1)
 }
@@ -0,0 +1,242 @@
+package helpers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+/** In this file are defined the password and certificate helpers of the common set. */
+
+// Certificate is a certificate generated by the certificate helpers, with the certificate and its private key encoded
+// as PEM.
+type Certificate struct {
+	Cert string `json:"cert" yaml:"cert"`
+	Key  string `json:"key" yaml:"key"`
+}
+
+// registerCrypto registers the password and certificate helpers. The generated values are random, so they change with
+// every render.
+func registerCrypto(manager IHelpersManager) {
+	_ = manager.Register("bcrypt", bcryptFn, "Returns the bcrypt hash of a password. E.g: {{ bcrypt .password }}")
+	_ = manager.Register("htpasswd", htpasswd, "Returns an htpasswd line for the user with the bcrypt hash of the password, as used by basic authentication. E.g: {{ htpasswd .user .password | base64Encode }}")
+	_ = manager.Register("genPrivateKey", genPrivateKey, "Generates a private key encoded as PEM, of type 'rsa', 'ecdsa' or 'ed25519'. E.g: {{ genPrivateKey \"ecdsa\" }}")
+	_ = manager.Register("genCA", genCA, "Generates a certificate authority valid for the given days, with the .Cert and .Key encoded as PEM. E.g: {{ $ca := genCA \"my-ca\" 365 }}")
+	_ = manager.Register("genSelfSignedCert", genSelfSignedCert, "Generates a self-signed server certificate for the IPs and DNS names, valid for the given days. E.g: {{ $cert := genSelfSignedCert \"api\" nil (stringArray \"api.local\") 365 }}")
+	_ = manager.Register("genSignedCert", genSignedCert, "Generates a server certificate for the IPs and DNS names signed by the CA, valid for the given days. E.g: {{ $cert := genSignedCert \"api\" nil (stringArray \"api.local\") 365 $ca }}")
+}
+
+func bcryptFn(password string) string {
+	ret, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return string(ret)
+}
+
+func htpasswd(user, password string) string {
+	if strings.Contains(user, ":") {
+		panic(fmt.Errorf("the user name cannot contain ':'"))
+	}
+	return user + ":" + bcryptFn(password)
+}
+
+func genPrivateKey(keyType string) string {
+	key := generateKey(keyType)
+	block, err := pemKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return string(pem.EncodeToMemory(block))
+}
+
+func genCA(cn string, days interface{}) Certificate {
+	template := certTemplate(cn, nil, nil, days)
+	template.IsCA = true
+	template.KeyUsage |= x509.KeyUsageCertSign
+	template.ExtKeyUsage = nil
+	key := generateKey("rsa")
+	return newCertificate(template, template, key, key)
+}
+
+func genSelfSignedCert(cn string, ips, dnsNames, days interface{}) Certificate {
+	template := certTemplate(cn, ips, dnsNames, days)
+	key := generateKey("rsa")
+	return newCertificate(template, template, key, key)
+}
+
+func genSignedCert(cn string, ips, dnsNames, days, ca interface{}) Certificate {
+	parent, parentKey := parseCA(ca)
+	template := certTemplate(cn, ips, dnsNames, days)
+	return newCertificate(template, parent, generateKey("rsa"), parentKey)
+}
+
+// certTemplate returns the template of a server certificate valid from the time returned by 'now'.
+func certTemplate(cn string, ips, dnsNames, days interface{}) *x509.Certificate {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err)
+	}
+	ret := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             Now(),
+		NotAfter:              Now().Add(time.Duration(toInteger(days)) * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              stringList(dnsNames, "DNS names"),
+	}
+	for _, str := range stringList(ips, "IP addresses") {
+		ip := net.ParseIP(str)
+		if ip == nil {
+			panic(fmt.Errorf("invalid IP address '%s'", str))
+		}
+		ret.IPAddresses = append(ret.IPAddresses, ip)
+	}
+	return ret
+}
+
+func newCertificate(template, parent *x509.Certificate, key, parentKey crypto.Signer) Certificate {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		panic(fmt.Errorf("failed to create the certificate, %v", err))
+	}
+	block, err := pemKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return Certificate{
+		Cert: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		Key:  string(pem.EncodeToMemory(block)),
+	}
+}
+
+// parseCA parses a certificate authority given as a Certificate or as a map with the 'Cert' and 'Key' in PEM.
+func parseCA(ca interface{}) (*x509.Certificate, crypto.Signer) {
+	var c Certificate
+	switch v := ca.(type) {
+	case Certificate:
+		c = v
+	case *Certificate:
+		c = *v
+	default:
+		val := reflect.ValueOf(ca)
+		if val.Kind() != reflect.Map {
+			panic(fmt.Errorf("invalid CA of type %T, expected a certificate generated by 'genCA'", ca))
+		}
+		for _, k := range val.MapKeys() {
+			switch strings.ToLower(fmt.Sprint(k.Interface())) {
+			case "cert":
+				c.Cert = fmt.Sprint(val.MapIndex(k).Interface())
+			case "key":
+				c.Key = fmt.Sprint(val.MapIndex(k).Interface())
+			}
+		}
+	}
+
+	certBlock, _ := pem.Decode([]byte(c.Cert))
+	if certBlock == nil {
+		panic(fmt.Errorf("invalid CA, the certificate is not PEM encoded"))
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		panic(fmt.Errorf("invalid CA certificate, %v", err))
+	}
+	keyBlock, _ := pem.Decode([]byte(c.Key))
+	if keyBlock == nil {
+		panic(fmt.Errorf("invalid CA, the key is not PEM encoded"))
+	}
+	key, err := parseKey(keyBlock)
+	if err != nil {
+		panic(fmt.Errorf("invalid CA key, %v", err))
+	}
+	return cert, key
+}
+
+func generateKey(keyType string) crypto.Signer {
+	var (
+		key crypto.Signer
+		err error
+	)
+	switch strings.ToLower(keyType) {
+	case "rsa":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ecdsa":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "ed25519":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		panic(fmt.Errorf("unsupported key type '%s', the supported types are rsa, ecdsa and ed25519", keyType))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func pemKey(key crypto.Signer) (*pem.Block, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}, nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		return &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}, err
+	default:
+		der, err := x509.MarshalPKCS8PrivateKey(k)
+		return &pem.Block{Type: "PRIVATE KEY", Bytes: der}, err
+	}
+}
+
+func parseKey(block *pem.Block) (crypto.Signer, error) {
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key of type %T", key)
+	}
+	return signer, nil
+}
+
+// stringList converts a list, a single string or nil to a list of strings.
+func stringList(v interface{}, name string) []string {
+	switch s := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{s}
+	case []string:
+		return s
+	}
+	val := reflect.ValueOf(v)
+	if kind := val.Kind(); kind != reflect.Slice && kind != reflect.Array {
+		panic(fmt.Errorf("the %s must be a list, got %T", name, v))
+	}
+	var ret []string
+	for i := 0; i < val.Len(); i++ {
+		ret = append(ret, fmt.Sprint(val.Index(i).Interface()))
+	}
+	return ret
+}
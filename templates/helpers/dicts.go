@@ -0,0 +1,188 @@
+package helpers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/jucardi/infuse/util/maps"
+)
+
+/** In this file are defined the dictionary helpers of the common set. */
+
+// registerDicts registers the dictionary helpers. They accept the map[string]interface{} decoded from JSON as well as the
+// map[interface{}]interface{} decoded from YAML, and the helpers which return a new dictionary return a
+// map[string]interface{}.
+func registerDicts(manager IHelpersManager) {
+	_ = manager.Register("keys", dictKeys, "Returns the sorted keys of the dictionaries. E.g: {{ keys .labels }}")
+	_ = manager.Register("values", dictValues, "Returns the values of a dictionary, sorted by key. E.g: {{ values .labels }}")
+	_ = manager.Register("hasKey", dictHasKey, "Indicates whether a dictionary contains the key. E.g: {{ if hasKey .labels \"app\" }}")
+	_ = manager.Register("pick", dictPick, "Returns a new dictionary with only the given keys. E.g: {{ pick .labels \"app\" \"tier\" }}")
+	_ = manager.Register("omit", dictOmit, "Returns a new dictionary without the given keys. E.g: {{ omit .labels \"app\" }}")
+	_ = manager.Register("merge", dictMerge, "Deep merges the dictionaries into the first one, without overwriting existing keys, and returns it. E.g: {{ merge $dst $src1 $src2 }}")
+	_ = manager.Register("mergeOverwrite", dictMergeOverwrite, "Deep merges the dictionaries into the first one, overwriting existing keys as the data files are merged, and returns it. E.g: {{ mergeOverwrite $defaults .config }}")
+	_ = manager.Register("deepCopy", maps.DeepCopy, "Returns a deep copy of the dictionaries and lists of a value. E.g: {{ $cfg := deepCopy .config }}")
+	_ = manager.Register("unset", dictUnset, "Removes a key from a dictionary and returns the dictionary. E.g: {{ $_ := unset .labels \"app\" }}")
+	_ = manager.Register("dig", dictDig, "Returns the value at the path of keys in nested dictionaries, or the default value if not found. E.g: {{ dig \"user\" \"role\" \"guest\" .data }}")
+	_ = manager.Register("toEntries", dictToEntries, "Returns the entries of a dictionary as a list of dictionaries with the 'key' and 'value', sorted by key. E.g: {{ range toEntries .labels }}{{ .key }}={{ .value }}{{ end }}")
+	_ = manager.Register("fromEntries", dictFromEntries, "Creates a dictionary from a list of dictionaries with the 'key' and 'value', or of key and value pairs. E.g: {{ fromEntries (toEntries .labels) }}")
+}
+
+// toDict returns the given map as a map[string]interface{}, converting the maps with non string keys decoded from YAML.
+func toDict(v interface{}, fn string) map[string]interface{} {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		return m
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(m))
+		for k, item := range m {
+			ret[fmt.Sprint(k)] = item
+		}
+		return ret
+	case nil:
+		return map[string]interface{}{}
+	}
+	panic(fmt.Errorf("cannot use '%s' on type %T", fn, v))
+}
+
+func isDict(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return true
+	}
+	return false
+}
+
+func dictKeys(dicts ...interface{}) []string {
+	var ret []string
+	for _, d := range dicts {
+		for k := range toDict(d, "keys") {
+			ret = append(ret, k)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func dictValues(d interface{}) []interface{} {
+	m := toDict(d, "values")
+	var ret []interface{}
+	for _, k := range dictKeys(m) {
+		ret = append(ret, m[k])
+	}
+	return ret
+}
+
+func dictHasKey(d interface{}, key string) bool {
+	_, ok := toDict(d, "hasKey")[key]
+	return ok
+}
+
+func dictPick(d interface{}, keys ...string) map[string]interface{} {
+	m := toDict(d, "pick")
+	ret := map[string]interface{}{}
+	for _, k := range keys {
+		if val, ok := m[k]; ok {
+			ret[k] = val
+		}
+	}
+	return ret
+}
+
+func dictOmit(d interface{}, keys ...string) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for k, val := range toDict(d, "omit") {
+		ret[k] = val
+	}
+	for _, k := range keys {
+		delete(ret, k)
+	}
+	return ret
+}
+
+func dictMerge(dst interface{}, srcs ...interface{}) interface{} {
+	return mergeDicts("merge", maps.MergeMissing, dst, srcs)
+}
+
+func dictMergeOverwrite(dst interface{}, srcs ...interface{}) interface{} {
+	return mergeDicts("mergeOverwrite", maps.Merge, dst, srcs)
+}
+
+// mergeDicts merges the sources into the destination in place, which keeps its type, or into a new dictionary if nil.
+func mergeDicts(fn string, merge func(dest, source interface{}), dst interface{}, srcs []interface{}) interface{} {
+	if dst == nil {
+		dst = map[string]interface{}{}
+	}
+	if !isDict(dst) {
+		panic(fmt.Errorf("cannot use '%s' on type %T", fn, dst))
+	}
+	for _, src := range srcs {
+		if src != nil && !isDict(src) {
+			panic(fmt.Errorf("cannot use '%s' on type %T", fn, src))
+		}
+		merge(dst, src)
+	}
+	return dst
+}
+
+func dictUnset(d interface{}, key string) interface{} {
+	if m, ok := d.(map[interface{}]interface{}); ok {
+		delete(m, key)
+		return m
+	}
+	m := toDict(d, "unset")
+	delete(m, key)
+	return m
+}
+
+// dictDig receives the keys, followed by the default value and the dictionary.
+func dictDig(args ...interface{}) (interface{}, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("dig needs at least three arguments, the keys, the default value and the dictionary")
+	}
+	current := args[len(args)-1]
+	def := args[len(args)-2]
+	for _, key := range args[:len(args)-2] {
+		if !isDict(current) {
+			return def, nil
+		}
+		val, ok := toDict(current, "dig")[fmt.Sprint(key)]
+		if !ok {
+			return def, nil
+		}
+		current = val
+	}
+	return current, nil
+}
+
+func dictToEntries(d interface{}) []interface{} {
+	m := toDict(d, "toEntries")
+	var ret []interface{}
+	for _, k := range dictKeys(m) {
+		ret = append(ret, map[string]interface{}{"key": k, "value": m[k]})
+	}
+	return ret
+}
+
+func dictFromEntries(v interface{}) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for _, entry := range toList(v, "fromEntries") {
+		if isDict(entry) {
+			e := toDict(entry, "fromEntries")
+			key, ok := e["key"]
+			if !ok {
+				panic(fmt.Errorf("invalid entry %v, the entries must have a 'key'", entry))
+			}
+			ret[fmt.Sprint(key)] = e["value"]
+			continue
+		}
+		if kind := reflect.ValueOf(entry).Kind(); kind == reflect.Slice || kind == reflect.Array {
+			if pair := toList(entry, "fromEntries"); len(pair) == 2 {
+				ret[fmt.Sprint(pair[0])] = pair[1]
+				continue
+			}
+		}
+		panic(fmt.Errorf("invalid entry %v, expected a dictionary with the 'key' and 'value' or a key and value pair", entry))
+	}
+	return ret
+}
@@ -0,0 +1,130 @@
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"net/url"
+	"strings"
+)
+
+/** In this file are defined the encoding and digest helpers of the common set. */
+
+// hashes are the algorithms accepted by the 'hmac' helper, by name
+var hashes = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// registerEncoding registers the encoding and digest helpers. The digests are hex encoded, as used in checksum
+// annotations, e.g: checksum/config: {{ include "config.yaml" . | sha256sum }}
+func registerEncoding(manager IHelpersManager) {
+	_ = manager.Register("base64Encode", base64Encode, "Encodes a string as base64, as required by the data of Kubernetes Secrets. E.g: {{ .password | base64Encode }}")
+	_ = manager.Register("base64Decode", base64Decode, "Decodes a base64 string. E.g: {{ .encoded | base64Decode }}")
+	_ = manager.Register("base64URLEncode", base64URLEncode, "Encodes a string as URL-safe base64 without padding. E.g: {{ .token | base64URLEncode }}")
+	_ = manager.Register("base64URLDecode", base64URLDecode, "Decodes a URL-safe base64 string, with or without padding. E.g: {{ .encoded | base64URLDecode }}")
+	_ = manager.Register("base32Encode", base32Encode, "Encodes a string as base32. E.g: {{ .value | base32Encode }}")
+	_ = manager.Register("base32Decode", base32Decode, "Decodes a base32 string. E.g: {{ .encoded | base32Decode }}")
+	_ = manager.Register("hexEncode", hexEncode, "Encodes a string as hexadecimal. E.g: {{ .value | hexEncode }}")
+	_ = manager.Register("hexDecode", hexDecode, "Decodes a hexadecimal string. E.g: {{ .encoded | hexDecode }}")
+	_ = manager.Register("urlEncode", url.QueryEscape, "Escapes a string so it can be safely placed in a URL query. E.g: https://example.com/?q={{ urlEncode .query }}")
+	_ = manager.Register("urlDecode", urlDecode, "Unescapes a string escaped for a URL query. E.g: {{ urlDecode .query }}")
+	_ = manager.Register("md5sum", md5sum, "Returns the hex encoded MD5 digest of a string. E.g: {{ md5sum .value }}")
+	_ = manager.Register("sha1sum", sha1sum, "Returns the hex encoded SHA-1 digest of a string. E.g: {{ sha1sum .value }}")
+	_ = manager.Register("sha256sum", sha256sum, "Returns the hex encoded SHA-256 digest of a string. E.g: {{ include \"config.yaml\" . | sha256sum }}")
+	_ = manager.Register("sha512sum", sha512sum, "Returns the hex encoded SHA-512 digest of a string. E.g: {{ sha512sum .value }}")
+	_ = manager.Register("crc32sum", crc32sum, "Returns the hex encoded IEEE CRC-32 checksum of a string. E.g: {{ crc32sum .value }}")
+	_ = manager.Register("hmac", hmacFn, "Returns the hex encoded HMAC of a message with the given key, using 'md5', 'sha1', 'sha256' or 'sha512'. E.g: {{ .body | hmac \"sha256\" .secret }}")
+}
+
+func base64Encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func base64Decode(s string) string {
+	return decode("base64", s, base64.StdEncoding.DecodeString)
+}
+
+func base64URLEncode(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+func base64URLDecode(s string) string {
+	return decode("base64", strings.TrimRight(s, "="), base64.RawURLEncoding.DecodeString)
+}
+
+func base32Encode(s string) string {
+	return base32.StdEncoding.EncodeToString([]byte(s))
+}
+
+func base32Decode(s string) string {
+	return decode("base32", s, base32.StdEncoding.DecodeString)
+}
+
+func hexEncode(s string) string {
+	return hex.EncodeToString([]byte(s))
+}
+
+func hexDecode(s string) string {
+	return decode("hex", s, hex.DecodeString)
+}
+
+func urlDecode(s string) string {
+	ret, err := url.QueryUnescape(s)
+	if err != nil {
+		panic(fmt.Errorf("invalid URL encoded string, %v", err))
+	}
+	return ret
+}
+
+func md5sum(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha1sum(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256sum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha512sum(s string) string {
+	sum := sha512.Sum512([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func crc32sum(s string) string {
+	return fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(s)))
+}
+
+func hmacFn(algorithm, key, message string) string {
+	fn, ok := hashes[strings.ToLower(algorithm)]
+	if !ok {
+		panic(fmt.Errorf("unsupported hash algorithm '%s', the supported algorithms are md5, sha1, sha256 and sha512", algorithm))
+	}
+	mac := hmac.New(fn, []byte(key))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func decode(encoding, s string, fn func(string) ([]byte, error)) string {
+	ret, err := fn(strings.TrimSpace(s))
+	if err != nil {
+		panic(fmt.Errorf("invalid %s string, %v", encoding, err))
+	}
+	return string(ret)
+}
@@ -21,7 +21,9 @@ func (a *Manager) Get() []*Helper {
 	return ret
 }
 
-// Register registers a helper function to be used with Go templates.
+// Register registers a helper function to be used with Go templates. The first optional string is the description
+// of the helper, the following ones are usage examples. If no examples are provided, the examples given in the
+// description, e.g: 'Usage: {{ helper "arg" }}', are used.
 func (a *Manager) Register(name string, fn interface{}, description ...string) error {
 	if name == "" {
 		return errors.New("the helper name cannot be empty")
@@ -35,11 +37,17 @@ func (a *Manager) Register(name string, fn interface{}, description ...string) e
 		return fmt.Errorf("wrong type for 'fn', %v , must be a function", kind)
 	}
 
-	a.helpers[name] = &Helper{
+	h := &Helper{
 		Name:        name,
 		Function:    fn,
 		Description: stringx.GetOrDefault("", description...),
 	}
+	if len(description) > 1 {
+		h.Examples = description[1:]
+	} else {
+		h.Examples = examplesFrom(h.Description)
+	}
+	a.helpers[name] = h
 
 	return nil
 }
@@ -0,0 +1,223 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/** In this file are defined the math helpers of the common set, which accept any numeric type. */
+
+// registerMath registers the math helpers. They accept ints, floats such as the numbers decoded from JSON, json.Number
+// values and numeric strings, and compute exact decimal results, so {{ mathAdd 0.1 0.2 }} renders 0.3 and large
+// integers do not lose precision.
+func registerMath(manager IHelpersManager) {
+	_ = manager.Register("mathAdd", mathAdd, "Adds all the provided numbers together and returns the result. E.g: {{ mathAdd .replicas 1 }}")
+	_ = manager.Register("mathMult", mathMult, "Multiplies all the provided numbers together and returns the result. E.g: {{ mathMult .cpus 1.5 }}")
+	_ = manager.Register("mathSub", mathSub, "Subtracts the second number from the first. E.g: {{ mathSub .replicas 1 }}")
+	_ = manager.Register("mathDiv", mathDiv, "Divides the first number by the second, the result is a decimal if the division is not exact. E.g: {{ mathDiv .memory 1024 }}")
+	_ = manager.Register("mathMod", mathMod, "Returns the remainder of dividing the first number by the second, with the sign of the first. E.g: {{ mathMod .index 2 }}")
+	_ = manager.Register("mathMin", mathMin, "Returns the smallest of the provided numbers. E.g: {{ mathMin .replicas 10 }}")
+	_ = manager.Register("mathMax", mathMax, "Returns the largest of the provided numbers. E.g: {{ mathMax .replicas 1 }}")
+	_ = manager.Register("mathFloor", mathFloor, "Returns the greatest integer less than or equal to the number. E.g: {{ mathFloor 1.5 }}")
+	_ = manager.Register("mathCeil", mathCeil, "Returns the least integer greater than or equal to the number. E.g: {{ mathCeil 1.5 }}")
+	_ = manager.Register("mathRound", mathRound, "Rounds the number half away from zero to the given number of decimal places. E.g: {{ mathRound .ratio 2 }}")
+}
+
+func mathAdd(nums ...interface{}) interface{} {
+	ret := new(big.Rat)
+	for _, n := range nums {
+		ret.Add(ret, toRat(n))
+	}
+	return fromRat(ret)
+}
+
+func mathMult(nums ...interface{}) interface{} {
+	if len(nums) == 0 {
+		return 0
+	}
+	ret := big.NewRat(1, 1)
+	for _, n := range nums {
+		ret.Mul(ret, toRat(n))
+	}
+	return fromRat(ret)
+}
+
+func mathSub(a, b interface{}) interface{} {
+	return fromRat(new(big.Rat).Sub(toRat(a), toRat(b)))
+}
+
+func mathDiv(a, b interface{}) interface{} {
+	divisor := toRat(b)
+	if divisor.Sign() == 0 {
+		panic(fmt.Errorf("division by zero"))
+	}
+	return fromRat(new(big.Rat).Quo(toRat(a), divisor))
+}
+
+func mathMod(a, b interface{}) interface{} {
+	dividend, divisor := toRat(a), toRat(b)
+	if divisor.Sign() == 0 {
+		panic(fmt.Errorf("division by zero"))
+	}
+	quo := new(big.Rat).Quo(dividend, divisor)
+	trunc := new(big.Rat).SetInt(new(big.Int).Quo(quo.Num(), quo.Denom()))
+	return fromRat(new(big.Rat).Sub(dividend, trunc.Mul(trunc, divisor)))
+}
+
+func mathMin(nums ...interface{}) interface{} {
+	return fromRat(mathFold("mathMin", nums, func(a, b *big.Rat) bool { return b.Cmp(a) < 0 }))
+}
+
+func mathMax(nums ...interface{}) interface{} {
+	return fromRat(mathFold("mathMax", nums, func(a, b *big.Rat) bool { return b.Cmp(a) > 0 }))
+}
+
+func mathFloor(v interface{}) interface{} {
+	return fromRat(ratFloor(toRat(v)))
+}
+
+func mathCeil(v interface{}) interface{} {
+	r := ratFloor(new(big.Rat).Neg(toRat(v)))
+	return fromRat(r.Neg(r))
+}
+
+func mathRound(v interface{}, places interface{}) interface{} {
+	n := toInteger(places)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(absInt64(n)), nil))
+	if n < 0 {
+		scale.Inv(scale)
+	}
+	r := new(big.Rat).Mul(toRat(v), scale)
+	sign := r.Sign()
+	r.Abs(r).Add(r, big.NewRat(1, 2))
+	r = ratFloor(r)
+	if sign < 0 {
+		r.Neg(r)
+	}
+	return fromRat(r.Quo(r, scale))
+}
+
+// mathFold returns the number of the list for which 'replace' returns true when compared with every other number.
+func mathFold(name string, nums []interface{}, replace func(current, candidate *big.Rat) bool) *big.Rat {
+	if len(nums) == 0 {
+		panic(fmt.Errorf("'%s' requires at least one number", name))
+	}
+	ret := toRat(nums[0])
+	for _, n := range nums[1:] {
+		if r := toRat(n); replace(ret, r) {
+			ret = r
+		}
+	}
+	return ret
+}
+
+// ratFloor returns the greatest integer less than or equal to the given number.
+func ratFloor(r *big.Rat) *big.Rat {
+	// Rat denominators are always positive, so the euclidean division rounds towards negative infinity.
+	return new(big.Rat).SetInt(new(big.Int).Div(r.Num(), r.Denom()))
+}
+
+// toRat converts the given value to an exact number. Floats are converted from their shortest decimal representation,
+// so 0.1 is exactly one tenth. Panics if the value is not a number.
+func toRat(v interface{}) *big.Rat {
+	if ret, ok := ratOf(v); ok {
+		return ret
+	}
+	panic(fmt.Errorf("'%v' is not a number", v))
+}
+
+// toInteger converts the given value to an integer. Panics if the value is not an integer.
+func toInteger(v interface{}) int64 {
+	if r, ok := ratOf(v); ok && r.IsInt() && r.Num().IsInt64() {
+		return r.Num().Int64()
+	}
+	panic(fmt.Errorf("'%v' is not an integer", v))
+}
+
+// ratOf converts the given value to an exact number, returns false if the value is not a number.
+func ratOf(v interface{}) (*big.Rat, bool) {
+	ret := new(big.Rat)
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return ret.SetInt64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return ret.SetInt(new(big.Int).SetUint64(val.Uint())), true
+	case reflect.Float32, reflect.Float64:
+		f := val.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, false
+		}
+		bitSize := 64
+		if val.Kind() == reflect.Float32 {
+			bitSize = 32
+		}
+		return ret.SetString(strconv.FormatFloat(f, 'g', -1, bitSize))
+	case reflect.String:
+		// json.Number is a string as well
+		return ret.SetString(strings.TrimSpace(val.String()))
+	}
+	return nil, false
+}
+
+// fromRat converts the given number to the type that best represents it: an int if it is an integer in the int range,
+// so it can be passed to the helpers that receive an int such as 'iterate', a float64 if the float prints as the same
+// decimal, or otherwise a json.Number with every digit of the result. Results that are not finite decimals, such as
+// 1/3, are returned as the closest float64.
+func fromRat(r *big.Rat) interface{} {
+	if r.IsInt() {
+		if n := r.Num(); n.IsInt64() && int64(int(n.Int64())) == n.Int64() {
+			return int(n.Int64())
+		}
+		return json.Number(r.Num().String())
+	}
+	f, _ := r.Float64()
+	places, ok := decimalPlaces(r.Denom())
+	if !ok {
+		return f
+	}
+	str := r.FloatString(places)
+	if strconv.FormatFloat(f, 'f', -1, 64) == str {
+		return f
+	}
+	return json.Number(str)
+}
+
+// decimalPlaces returns the number of decimal places required to represent a fraction with the given denominator, or
+// false if the decimal representation is periodic, which happens if the denominator has prime factors other than 2
+// and 5.
+func decimalPlaces(denom *big.Int) (int, bool) {
+	d := new(big.Int).Set(denom)
+	places := map[int64]int{}
+	rem := new(big.Int)
+	for _, factor := range []int64{2, 5} {
+		f := big.NewInt(factor)
+		for {
+			q, m := new(big.Int).QuoRem(d, f, rem)
+			if m.Sign() != 0 {
+				break
+			}
+			d = q
+			places[factor]++
+		}
+	}
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return 0, false
+	}
+	if places[2] > places[5] {
+		return places[2], true
+	}
+	return places[5], true
+}
+
+func absInt64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,206 @@
+package helpers
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+)
+
+/** In this file are defined the network helpers of the common set. */
+
+// registerNetwork registers the network helpers. The CIDR helpers work as the Terraform functions by the same names,
+// for IPv4 and IPv6 prefixes.
+func registerNetwork(manager IHelpersManager) {
+	_ = manager.Register("cidrHost", cidrHost, "Returns the IP address of the given host number in the prefix, negative numbers count from the end. E.g: {{ cidrHost \"10.0.0.0/24\" 5 }} returns 10.0.0.5")
+	_ = manager.Register("cidrSubnet", cidrSubnet, "Returns the subnet of the prefix with the given additional bits and network number. E.g: {{ cidrSubnet \"10.0.0.0/16\" 8 2 }} returns 10.0.2.0/24")
+	_ = manager.Register("cidrSubnets", cidrSubnets, "Returns consecutive subnets of the prefix with the given additional bits each. E.g: {{ cidrSubnets \"10.0.0.0/16\" 8 8 4 }} returns [10.0.0.0/24 10.0.1.0/24 10.0.16.0/20]")
+	_ = manager.Register("cidrNetmask", cidrNetmask, "Returns the netmask of an IPv4 prefix in dotted notation. E.g: {{ cidrNetmask \"10.0.0.0/12\" }} returns 255.240.0.0")
+	_ = manager.Register("cidrContains", cidrContains, "Indicates whether the prefix contains the IP address or prefix. E.g: {{ if cidrContains \"10.0.0.0/8\" .ip }}")
+	_ = manager.Register("ipAdd", ipAdd, "Adds a number to an IP address, which can be negative. E.g: {{ ipAdd \"10.0.0.255\" 1 }} returns 10.0.1.0")
+	_ = manager.Register("ipParse", ipParse, "Parses an IPv4 or IPv6 address into a map with the normalized 'ip', its 'version' 4 or 6, and whether it is 'private' or 'loopback'. E.g: {{ (ipParse .ip).version }}")
+	_ = manager.Register("isIPv4", isIPv4, "Indicates whether the value is an IPv4 address. E.g: {{ if isIPv4 .ip }}")
+	_ = manager.Register("isIPv6", isIPv6, "Indicates whether the value is an IPv6 address. E.g: {{ if isIPv6 .ip }}")
+	_ = manager.Register("splitHostPort", splitHostPort, "Splits an address into a map with the 'host' and 'port', IPv6 hosts are given in brackets. E.g: {{ (splitHostPort \"[::1]:8080\").port }}")
+	_ = manager.Register("joinHostPort", joinHostPort, "Joins a host and a port into an address, adding brackets to IPv6 hosts. E.g: {{ joinHostPort \"::1\" 8080 }} returns [::1]:8080")
+}
+
+func cidrHost(prefix string, hostnum interface{}) (string, error) {
+	network, bits, err := parsePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	ones, size := network.Mask.Size()
+	host := big.NewInt(toInteger(hostnum))
+	hosts := new(big.Int).Lsh(big.NewInt(1), uint(size-ones))
+	if host.Sign() < 0 {
+		host.Add(host, hosts)
+	}
+	if host.Sign() < 0 || host.Cmp(hosts) >= 0 {
+		return "", fmt.Errorf("prefix of %d bits cannot accommodate a host numbered %d", bits-ones, toInteger(hostnum))
+	}
+	return bigToIP(host.Add(host, ipToBig(network.IP)), len(network.IP)).String(), nil
+}
+
+func cidrSubnet(prefix string, newbits, netnum interface{}) (string, error) {
+	network, bits, err := parsePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	ones, _ := network.Mask.Size()
+	length := ones + int(toInteger(newbits))
+	if toInteger(newbits) < 0 || length > bits {
+		return "", fmt.Errorf("insufficient address space to extend prefix of %d by %d", ones, toInteger(newbits))
+	}
+	num := big.NewInt(toInteger(netnum))
+	if num.Sign() < 0 || num.BitLen() > length-ones {
+		return "", fmt.Errorf("prefix extension of %d does not accommodate a subnet numbered %d", length-ones, toInteger(netnum))
+	}
+	ip := new(big.Int).Add(ipToBig(network.IP), num.Lsh(num, uint(bits-length)))
+	return subnetString(ip, length, bits), nil
+}
+
+func cidrSubnets(prefix string, newbits ...interface{}) ([]string, error) {
+	network, bits, err := parsePrefix(prefix)
+	if err != nil {
+		return nil, err
+	}
+	ones, _ := network.Mask.Size()
+	start := ipToBig(network.IP)
+	end := new(big.Int).Add(start, new(big.Int).Lsh(big.NewInt(1), uint(bits-ones)))
+	current := new(big.Int).Set(start)
+
+	var ret []string
+	for _, nb := range newbits {
+		length := ones + int(toInteger(nb))
+		if toInteger(nb) < 1 || length > bits {
+			return nil, fmt.Errorf("invalid new bits %d for a prefix of %d bits, must be between 1 and %d", toInteger(nb), ones, bits-ones)
+		}
+		size := new(big.Int).Lsh(big.NewInt(1), uint(bits-length))
+		// Aligns the subnet to its size, after the previous subnet
+		if rem := new(big.Int).Mod(current, size); rem.Sign() != 0 {
+			current.Add(current, size).Sub(current, rem)
+		}
+		next := new(big.Int).Add(current, size)
+		if next.Cmp(end) > 0 {
+			return nil, fmt.Errorf("not enough remaining address space for a subnet with a prefix of %d bits after %s", length, ret[len(ret)-1])
+		}
+		ret = append(ret, subnetString(current, length, bits))
+		current = next
+	}
+	return ret, nil
+}
+
+func cidrNetmask(prefix string) (string, error) {
+	network, bits, err := parsePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	if bits != 8*net.IPv4len {
+		return "", fmt.Errorf("only IPv4 prefixes have a netmask, got '%s'", prefix)
+	}
+	return net.IP(network.Mask).String(), nil
+}
+
+func cidrContains(prefix, address string) (bool, error) {
+	network, bits, err := parsePrefix(prefix)
+	if err != nil {
+		return false, err
+	}
+	if ip := parseIP(address); ip != nil {
+		return len(ip) == bits/8 && network.Contains(ip), nil
+	}
+	other, otherBits, err := parsePrefix(address)
+	if err != nil {
+		return false, fmt.Errorf("invalid IP address or prefix '%s'", address)
+	}
+	ones, _ := network.Mask.Size()
+	otherOnes, _ := other.Mask.Size()
+	return bits == otherBits && otherOnes >= ones && network.Contains(other.IP), nil
+}
+
+func ipAdd(address string, n interface{}) (string, error) {
+	ip := parseIP(address)
+	if ip == nil {
+		return "", fmt.Errorf("invalid IP address '%s'", address)
+	}
+	ret := new(big.Int).Add(ipToBig(ip), big.NewInt(toInteger(n)))
+	if ret.Sign() < 0 || ret.BitLen() > len(ip)*8 {
+		return "", fmt.Errorf("adding %d to %s is out of the address space", toInteger(n), address)
+	}
+	return bigToIP(ret, len(ip)).String(), nil
+}
+
+func ipParse(address string) (map[string]interface{}, error) {
+	ip := parseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address '%s'", address)
+	}
+	version := 6
+	if len(ip) == net.IPv4len {
+		version = 4
+	}
+	return map[string]interface{}{
+		"ip":       ip.String(),
+		"version":  version,
+		"private":  ip.IsPrivate(),
+		"loopback": ip.IsLoopback(),
+	}, nil
+}
+
+func isIPv4(address string) bool {
+	ip := parseIP(address)
+	return ip != nil && len(ip) == net.IPv4len
+}
+
+func isIPv6(address string) bool {
+	ip := parseIP(address)
+	return ip != nil && len(ip) == net.IPv6len
+}
+
+func splitHostPort(address string) (map[string]interface{}, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address '%s', %v", address, err)
+	}
+	return map[string]interface{}{"host": host, "port": port}, nil
+}
+
+func joinHostPort(host string, port interface{}) string {
+	return net.JoinHostPort(host, fmt.Sprint(port))
+}
+
+// parseIP parses an IP address, returning the IPv4 addresses in their 4 bytes form.
+func parseIP(address string) net.IP {
+	ip := net.ParseIP(address)
+	// The IPv4 addresses mapped to IPv6 are kept as IPv6, e.g: '::ffff:10.0.0.1'
+	if ip4 := ip.To4(); ip4 != nil && !strings.Contains(address, ":") {
+		return ip4
+	}
+	return ip
+}
+
+// parsePrefix parses a prefix in CIDR notation, returning the network and the number of bits of its addresses.
+func parsePrefix(prefix string) (*net.IPNet, int, error) {
+	_, network, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid CIDR prefix '%s'", prefix)
+	}
+	_, bits := network.Mask.Size()
+	return network, bits, nil
+}
+
+func subnetString(ip *big.Int, length, bits int) string {
+	network := net.IPNet{IP: bigToIP(ip, bits/8), Mask: net.CIDRMask(length, bits)}
+	return network.String()
+}
+
+func ipToBig(ip net.IP) *big.Int {
+	return new(big.Int).SetBytes(ip)
+}
+
+func bigToIP(n *big.Int, size int) net.IP {
+	ret := make(net.IP, size)
+	n.FillBytes(ret)
+	return ret
+}
@@ -0,0 +1,220 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+)
+
+// The expected results of the CIDR helpers are the ones documented by the Terraform functions by the same names.
+
+func TestCidrHost(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		hostnum  interface{}
+		expected string
+	}{
+		{"10.0.0.0/24", 5, "10.0.0.5"},
+		{"10.0.0.0/24", -1, "10.0.0.255"},
+		{"10.12.112.0/20", 268, "10.12.113.12"},
+		{"fd00:fd12:3456:7890::/56", 16, "fd00:fd12:3456:7800::10"},
+		{"fd00:fd12:3456:7890::/56", -1, "fd00:fd12:3456:78ff:ffff:ffff:ffff:ffff"},
+	}
+	for _, tt := range tests {
+		actual, err := cidrHost(tt.prefix, tt.hostnum)
+		if err != nil {
+			t.Errorf("cidrHost %s %v failed, %v", tt.prefix, tt.hostnum, err)
+		} else if actual != tt.expected {
+			t.Errorf("cidrHost %s %v returned '%s', expected '%s'", tt.prefix, tt.hostnum, actual, tt.expected)
+		}
+	}
+
+	// Hosts out of the range of the prefix
+	for _, hostnum := range []interface{}{256, -257} {
+		if actual, err := cidrHost("10.0.0.0/24", hostnum); err == nil {
+			t.Errorf("expected cidrHost 10.0.0.0/24 %v to fail, returned '%s'", hostnum, actual)
+		}
+	}
+	if actual, err := cidrHost("10.0.0.0", 1); err == nil {
+		t.Errorf("expected cidrHost to fail with an invalid prefix, returned '%s'", actual)
+	}
+}
+
+func TestCidrSubnet(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		newbits  interface{}
+		netnum   interface{}
+		expected string
+	}{
+		{"10.0.0.0/16", 8, 2, "10.0.2.0/24"},
+		{"172.16.0.0/12", 4, 2, "172.18.0.0/16"},
+		{"10.1.2.0/24", 4, 15, "10.1.2.240/28"},
+		{"fd00:fd12:3456:7890::/56", 16, 162, "fd00:fd12:3456:7800:a200::/72"},
+	}
+	for _, tt := range tests {
+		actual, err := cidrSubnet(tt.prefix, tt.newbits, tt.netnum)
+		if err != nil {
+			t.Errorf("cidrSubnet %s %v %v failed, %v", tt.prefix, tt.newbits, tt.netnum, err)
+		} else if actual != tt.expected {
+			t.Errorf("cidrSubnet %s %v %v returned '%s', expected '%s'", tt.prefix, tt.newbits, tt.netnum, actual, tt.expected)
+		}
+	}
+
+	errors := []struct {
+		prefix  string
+		newbits interface{}
+		netnum  interface{}
+	}{
+		// Not enough space to extend the prefix
+		{"10.0.0.0/30", 3, 0},
+		{"fd00::/120", 9, 0},
+		{"10.0.0.0/16", -1, 0},
+		// Network numbers out of the range of the new bits
+		{"10.0.0.0/16", 2, 4},
+		{"10.0.0.0/16", 2, -1},
+	}
+	for _, tt := range errors {
+		if actual, err := cidrSubnet(tt.prefix, tt.newbits, tt.netnum); err == nil {
+			t.Errorf("expected cidrSubnet %s %v %v to fail, returned '%s'", tt.prefix, tt.newbits, tt.netnum, actual)
+		}
+	}
+}
+
+func TestCidrSubnets(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		newbits  []interface{}
+		expected []string
+	}{
+		{"10.1.0.0/16", []interface{}{4, 4, 8, 4}, []string{"10.1.0.0/20", "10.1.16.0/20", "10.1.32.0/24", "10.1.48.0/20"}},
+		{"10.0.0.0/24", []interface{}{2, 2, 2, 2}, []string{"10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/26"}},
+		{
+			"fd00:fd12:3456:7890::/56",
+			[]interface{}{16, 16, 16, 32},
+			[]string{"fd00:fd12:3456:7800::/72", "fd00:fd12:3456:7800:100::/72", "fd00:fd12:3456:7800:200::/72", "fd00:fd12:3456:7800:300::/88"},
+		},
+	}
+	for _, tt := range tests {
+		actual, err := cidrSubnets(tt.prefix, tt.newbits...)
+		if err != nil {
+			t.Errorf("cidrSubnets %s %v failed, %v", tt.prefix, tt.newbits, err)
+		} else if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("cidrSubnets %s %v returned %v, expected %v", tt.prefix, tt.newbits, actual, tt.expected)
+		}
+	}
+
+	errors := []struct {
+		prefix  string
+		newbits []interface{}
+	}{
+		// Not enough space for the last subnet
+		{"10.0.0.0/30", []interface{}{1, 1, 1}},
+		{"fd00::/120", []interface{}{1, 2, 1}},
+		// Invalid new bits
+		{"10.0.0.0/24", []interface{}{0}},
+		{"10.0.0.0/24", []interface{}{9}},
+	}
+	for _, tt := range errors {
+		if actual, err := cidrSubnets(tt.prefix, tt.newbits...); err == nil {
+			t.Errorf("expected cidrSubnets %s %v to fail, returned %v", tt.prefix, tt.newbits, actual)
+		}
+	}
+}
+
+func TestCidrContains(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		address  string
+		expected bool
+	}{
+		{"10.0.0.0/8", "10.1.2.3", true},
+		{"10.0.0.0/8", "11.0.0.1", false},
+		{"10.0.0.0/8", "10.1.0.0/16", true},
+		{"10.1.0.0/16", "10.0.0.0/8", false},
+		{"fd00::/8", "fd12::1", true},
+		{"fd00::/8", "fe80::1", false},
+		{"fd00::/8", "fd00:1::/32", true},
+		// Addresses and prefixes of the other version are not contained
+		{"0.0.0.0/0", "::1", false},
+		{"::/0", "10.0.0.1", false},
+		{"::/0", "10.0.0.0/8", false},
+	}
+	for _, tt := range tests {
+		actual, err := cidrContains(tt.prefix, tt.address)
+		if err != nil {
+			t.Errorf("cidrContains %s %s failed, %v", tt.prefix, tt.address, err)
+		} else if actual != tt.expected {
+			t.Errorf("cidrContains %s %s returned %v, expected %v", tt.prefix, tt.address, actual, tt.expected)
+		}
+	}
+
+	if _, err := cidrContains("10.0.0.0/8", "10.0.0"); err == nil {
+		t.Error("expected cidrContains to fail with an invalid address")
+	}
+}
+
+func TestIPAdd(t *testing.T) {
+	tests := []struct {
+		address  string
+		n        interface{}
+		expected string
+	}{
+		{"10.0.0.255", 1, "10.0.1.0"},
+		{"10.0.1.0", -1, "10.0.0.255"},
+		{"0.0.0.0", 0, "0.0.0.0"},
+		{"::1", 1, "::2"},
+		{"fd00::ffff", 1, "fd00::1:0"},
+		{"fd00::1:0", -1, "fd00::ffff"},
+	}
+	for _, tt := range tests {
+		actual, err := ipAdd(tt.address, tt.n)
+		if err != nil {
+			t.Errorf("ipAdd %s %v failed, %v", tt.address, tt.n, err)
+		} else if actual != tt.expected {
+			t.Errorf("ipAdd %s %v returned '%s', expected '%s'", tt.address, tt.n, actual, tt.expected)
+		}
+	}
+
+	errors := []struct {
+		address string
+		n       interface{}
+	}{
+		// Overflow and underflow of the address space
+		{"255.255.255.255", 1},
+		{"0.0.0.0", -1},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", 1},
+		{"::", -1},
+		{"10.0.0", 1},
+	}
+	for _, tt := range errors {
+		if actual, err := ipAdd(tt.address, tt.n); err == nil {
+			t.Errorf("expected ipAdd %s %v to fail, returned '%s'", tt.address, tt.n, actual)
+		}
+	}
+}
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected map[string]interface{}
+	}{
+		{"10.0.0.1:8080", map[string]interface{}{"host": "10.0.0.1", "port": "8080"}},
+		{"example.com:443", map[string]interface{}{"host": "example.com", "port": "443"}},
+		{"[::1]:8080", map[string]interface{}{"host": "::1", "port": "8080"}},
+		{"[fd00::1%eth0]:53", map[string]interface{}{"host": "fd00::1%eth0", "port": "53"}},
+	}
+	for _, tt := range tests {
+		actual, err := splitHostPort(tt.address)
+		if err != nil {
+			t.Errorf("splitHostPort %s failed, %v", tt.address, err)
+		} else if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("splitHostPort %s returned %v, expected %v", tt.address, actual, tt.expected)
+		}
+	}
+
+	for _, address := range []string{"10.0.0.1", "::1:8080", "[::1]"} {
+		if actual, err := splitHostPort(address); err == nil {
+			t.Errorf("expected splitHostPort %s to fail, returned %v", address, actual)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type pack struct {
+	category string
+	register func(manager IHelpersManager)
+	helpers  []*Helper
+	once     sync.Once
+}
+
+var (
+	packs      = map[string]*pack{}
+	packsMutex = sync.RWMutex{}
+)
+
+// RegisterPack registers an optional set of helpers by name, which is not available to the templates unless enabled
+// for a template or with the --helpers flag. The helpers of the pack override the registered helpers by the same name.
+func RegisterPack(name, category string, register func(manager IHelpersManager)) {
+	packsMutex.Lock()
+	defer packsMutex.Unlock()
+	packs[name] = &pack{category: category, register: register}
+}
+
+// PackNames returns the names of the registered helper packs, sorted.
+func PackNames() []string {
+	packsMutex.RLock()
+	defer packsMutex.RUnlock()
+	var ret []string
+	for name := range packs {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// PackHelpers returns the helpers of the pack by the given name.
+func PackHelpers(name string) ([]*Helper, error) {
+	packsMutex.RLock()
+	p, ok := packs[name]
+	packsMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("helper pack '%s' not found, the available packs are %v", name, PackNames())
+	}
+
+	p.once.Do(func() {
+		manager := New()
+		p.register(manager)
+		p.helpers = manager.Get()
+		sort.Slice(p.helpers, func(i, j int) bool { return p.helpers[i].Name < p.helpers[j].Name })
+		for _, h := range p.helpers {
+			h.Category = p.category
+		}
+	})
+	return p.helpers, nil
+}
@@ -0,0 +1,232 @@
+package helpers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jucardi/infuse/config"
+)
+
+const (
+	// PluginPrefix is the prefix of the executable of a plugin helper that does not declare a command
+	PluginPrefix = "infuse-helper-"
+
+	// DefaultPluginTimeout is the time a plugin helper can take to respond if it does not declare a timeout
+	DefaultPluginTimeout = 10 * time.Second
+
+	// PluginCategory is the category of the plugin helpers listed by --listHelpers
+	PluginCategory = "Plugins"
+)
+
+var (
+	plugins      = map[string]config.Plugin{}
+	pluginsMutex = sync.RWMutex{}
+)
+
+type pluginRequest struct {
+	Helper string                 `json:"helper"`
+	Args   []interface{}          `json:"args"`
+	Kwargs map[string]interface{} `json:"kwargs,omitempty"`
+}
+
+type pluginResponse struct {
+	Result interface{} `json:"result"`
+	Error  string      `json:"error"`
+}
+
+// RegisterPlugin registers a helper implemented by an external executable, available to every template type. The
+// executable receives the helper name and its arguments as a JSON object through stdin,
+// '{"helper": "vault", "args": ["x"]}', and must write the result as a JSON object to stdout, '{"result": "value"}',
+// or '{"error": "message"}' if the helper failed.
+func RegisterPlugin(plugin config.Plugin) error {
+	if plugin.Name == "" {
+		return errors.New("the plugin name cannot be empty")
+	}
+	pluginsMutex.Lock()
+	defer pluginsMutex.Unlock()
+	plugins[plugin.Name] = plugin
+	return nil
+}
+
+// PluginNames returns the names of the registered plugin helpers, sorted.
+func PluginNames() []string {
+	pluginsMutex.RLock()
+	defer pluginsMutex.RUnlock()
+	var ret []string
+	for name := range plugins {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// PluginHelpers returns the registered plugin helpers, to be listed along with the helpers of a template type. The
+// results of the functions of the returned helpers are not cached.
+func PluginHelpers() []*Helper {
+	var ret []*Helper
+	for _, name := range PluginNames() {
+		plugin, _ := getPlugin(name)
+		description := plugin.Description
+		if description == "" {
+			description = fmt.Sprintf("Plugin helper implemented by '%s'", pluginCommand(plugin))
+		}
+		ret = append(ret, &Helper{
+			Name:        name,
+			Category:    PluginCategory,
+			Description: description,
+			Function:    NewPluginCache().Func(name),
+		})
+	}
+	return ret
+}
+
+// PluginCache caches the results of the plugin helpers by their arguments, so a plugin is executed once per distinct
+// call. A new cache is created for every render.
+type PluginCache struct {
+	results map[string]*pluginResponse
+	mutex   sync.Mutex
+}
+
+// NewPluginCache creates a new cache for the results of the plugin helpers
+func NewPluginCache() *PluginCache {
+	return &PluginCache{results: map[string]*pluginResponse{}}
+}
+
+// Func returns the function of the plugin helper by the given name, which invokes the plugin with the provided
+// arguments.
+func (c *PluginCache) Func(name string) func(args ...interface{}) (interface{}, error) {
+	return func(args ...interface{}) (interface{}, error) {
+		return c.Call(name, args, nil)
+	}
+}
+
+// Call invokes the plugin helper by the given name with the provided positional and named arguments, or returns the
+// cached result if the plugin was already invoked with the same arguments.
+func (c *PluginCache) Call(name string, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	plugin, ok := getPlugin(name)
+	if !ok {
+		return nil, fmt.Errorf("plugin helper '%s' not found", name)
+	}
+
+	req := pluginRequest{Helper: name, Args: jsonValue(args).([]interface{}), Kwargs: jsonValue(kwargs).(map[string]interface{})}
+	if req.Args == nil {
+		req.Args = []interface{}{}
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal the arguments of plugin helper '%s', %v", name, err)
+	}
+
+	c.mutex.Lock()
+	resp, ok := c.results[string(input)]
+	c.mutex.Unlock()
+
+	if !ok {
+		if resp, err = invokePlugin(plugin, input); err != nil {
+			return nil, err
+		}
+		c.mutex.Lock()
+		c.results[string(input)] = resp
+		c.mutex.Unlock()
+	}
+
+	if resp.Error != "" {
+		return nil, fmt.Errorf("plugin helper '%s' failed, %s", name, resp.Error)
+	}
+	return resp.Result, nil
+}
+
+func getPlugin(name string) (config.Plugin, bool) {
+	pluginsMutex.RLock()
+	defer pluginsMutex.RUnlock()
+	plugin, ok := plugins[name]
+	return plugin, ok
+}
+
+func pluginCommand(plugin config.Plugin) string {
+	if plugin.Command != "" {
+		return plugin.Command
+	}
+	return PluginPrefix + plugin.Name
+}
+
+func invokePlugin(plugin config.Plugin, input []byte) (*pluginResponse, error) {
+	timeout := plugin.Timeout
+	if timeout <= 0 {
+		timeout = DefaultPluginTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	command := pluginCommand(plugin)
+	cmd := exec.CommandContext(ctx, command, plugin.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	// The output pipes may be kept open by processes started by the plugin after it is killed, so the result is not
+	// awaited past the timeout.
+	done := make(chan error, 1)
+	go func() { done <- cmd.Run() }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("plugin helper '%s' timed out after %v", plugin.Name, timeout)
+	}
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin helper '%s' timed out after %v", plugin.Name, timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("plugin helper '%s' failed, %v: %s", plugin.Name, err, msg)
+		}
+		return nil, fmt.Errorf("plugin helper '%s' failed, %v", plugin.Name, err)
+	}
+
+	resp := &pluginResponse{}
+	if err := json.Unmarshal(stdout.Bytes(), resp); err != nil {
+		return nil, fmt.Errorf("invalid response from plugin helper '%s', %v", plugin.Name, err)
+	}
+	return resp, nil
+}
+
+// jsonValue converts the maps with non string keys, as unmarshalled from YAML, so the value can be marshalled as JSON.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			ret[fmt.Sprint(k)] = jsonValue(item)
+		}
+		return ret
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		ret := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			ret[k] = jsonValue(item)
+		}
+		return ret
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		ret := make([]interface{}, len(v))
+		for i, item := range v {
+			ret[i] = jsonValue(item)
+		}
+		return ret
+	}
+	return value
+}
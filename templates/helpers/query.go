@@ -0,0 +1,12 @@
+package helpers
+
+import (
+	"github.com/jucardi/infuse/util/jsonpath"
+)
+
+/** In this file are defined the data query helpers of the common set. */
+
+// registerQuery registers the JSONPath query helpers.
+func registerQuery(manager IHelpersManager) {
+	_ = manager.Register("query", jsonpath.Query, "Returns the list of values matched by the JSONPath expression, or jq path if it starts with '.', in the given data. E.g: {{ range query \"$.services[?(@.enabled)].name\" . }}")
+}
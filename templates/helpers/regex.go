@@ -0,0 +1,114 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+/** In this file are defined the regular expression helpers of the common set. */
+
+// maxCachedPatterns is the number of compiled patterns kept in the cache before it is cleared
+const maxCachedPatterns = 256
+
+var (
+	patterns      = map[string]*regexp.Regexp{}
+	patternsMutex = sync.Mutex{}
+)
+
+// registerRegex registers the regular expression helpers. The patterns use the RE2 syntax of Go, and invalid patterns
+// fail the render with an error.
+func registerRegex(manager IHelpersManager) {
+	_ = manager.Register("regexMatch", regexMatch, "Returns whether the string contains a match of the pattern. E.g: {{ if regexMatch \"^v[0-9]+$\" .version }}")
+	_ = manager.Register("regexFind", regexFind, "Returns the first match of the pattern in the string, or an empty string if none. E.g: {{ regexFind \"[0-9]+\" .name }}")
+	_ = manager.Register("regexFindAll", regexFindAll, "Returns the first n matches of the pattern in the string, or all of them if n is negative. E.g: {{ regexFindAll \"[0-9]+\" .name -1 }}")
+	_ = manager.Register("regexReplaceAll", regexReplaceAll, "Replaces the matches of the pattern in the string, the replacement can reference the groups with $1 or ${name}. E.g: {{ regexReplaceAll \"(\\\\w+)@(\\\\w+)\" .email \"$2\" }}")
+	_ = manager.Register("regexReplaceAllLiteral", regexReplaceAllLiteral, "Replaces the matches of the pattern in the string with the literal replacement. E.g: {{ regexReplaceAllLiteral \"\\\\s+\" .name \"$\" }}")
+	_ = manager.Register("regexSplit", regexSplit, "Splits the string by the matches of the pattern into at most n substrings, or all of them if n is negative. E.g: {{ regexSplit \"\\\\s*,\\\\s*\" .list -1 }}")
+	_ = manager.Register("regexCaptures", regexCaptures, "Returns a map with the named groups of the first match of the pattern, which is empty if there is no match. E.g: {{ (regexCaptures \"(?P<major>\\\\d+)\\\\.(?P<minor>\\\\d+)\" .version).major }}")
+}
+
+func regexMatch(pattern, s string) (bool, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+func regexFind(pattern, s string) (string, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.FindString(s), nil
+}
+
+func regexFindAll(pattern, s string, n int) ([]string, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.FindAllString(s, n), nil
+}
+
+func regexReplaceAll(pattern, s, repl string) (string, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, repl), nil
+}
+
+func regexReplaceAllLiteral(pattern, s, repl string) (string, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllLiteralString(s, repl), nil
+}
+
+func regexSplit(pattern, s string, n int) ([]string, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.Split(s, n), nil
+}
+
+func regexCaptures(pattern, s string) (map[string]interface{}, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]interface{}{}
+	match := re.FindStringSubmatch(s)
+	if match == nil {
+		return ret, nil
+	}
+	for i, name := range re.SubexpNames() {
+		if name != "" {
+			ret[name] = match[i]
+		}
+	}
+	return ret, nil
+}
+
+// compile returns the compiled pattern, which is cached so the patterns used in loops or by multiple templates are
+// compiled once.
+func compile(pattern string) (*regexp.Regexp, error) {
+	patternsMutex.Lock()
+	defer patternsMutex.Unlock()
+	if re, ok := patterns[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression '%s', %v", pattern, err)
+	}
+	if len(patterns) >= maxCachedPatterns {
+		patterns = map[string]*regexp.Regexp{}
+	}
+	patterns[pattern] = re
+	return re, nil
+}
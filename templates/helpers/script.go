@@ -0,0 +1,154 @@
+package helpers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"text/template"
+
+	"go.starlark.net/starlark"
+	"gopkg.in/yaml.v2"
+)
+
+// ScriptCategory is the category of the helpers defined in script files
+const ScriptCategory = "Scripts"
+
+// ScriptFunction is a helper defined by a Go template body, or by a Starlark function, instead of Go code, so helpers
+// can be added without recompiling. The body is rendered with the parameters by name, e.g: '{{ .first }} {{ .last }}',
+// and the arguments as a list in '.args'. The body can use the helpers of the manager it is registered to, and the
+// other functions registered with it. Since the body is rendered, it always returns a string, while Starlark functions
+// return values of any type, such as numbers, lists or dicts.
+type ScriptFunction struct {
+	Name        string   `yaml:"-"`
+	Description string   `yaml:"description"`
+	Params      []string `yaml:"params"`
+	Body        string   `yaml:"body"`
+	Examples    []string `yaml:"examples"`
+
+	// starlark is the function called instead of rendering the body, for the functions loaded from Starlark files
+	starlark *starlark.Function
+}
+
+// LoadScript loads the functions declared in a YAML script file, by name:
+//
+//	fullName:
+//	  params: [first, last]
+//	  body: '{{ .first }} {{ .last }}'
+//	  description: Joins the first and last names
+//
+// Files with the '.star' extension are loaded as Starlark files instead, where every top level function not starting
+// with '_' is a script function:
+//
+//	def fullName(first, last):
+//	    """Joins the first and last names"""
+//	    return first + " " + last
+func LoadScript(file string) ([]*ScriptFunction, error) {
+	contents, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read script file '%s', %v", file, err)
+	}
+	if filepath.Ext(file) == StarlarkExt {
+		return loadStarlark(file, contents)
+	}
+	fns := map[string]*ScriptFunction{}
+	if err := yaml.UnmarshalStrict(contents, &fns); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal script file '%s', %v", file, err)
+	}
+
+	var ret []*ScriptFunction
+	for name, fn := range fns {
+		if fn == nil {
+			return nil, fmt.Errorf("function '%s' in script file '%s' has no body", name, file)
+		}
+		fn.Name = name
+		ret = append(ret, fn)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+// RegisterScript registers the given functions as helpers of the manager. The functions receive a fixed number of
+// arguments, one per declared parameter, so they can also be used by template engines that do not support variadic
+// helpers. Functions cannot replace the helpers already registered.
+func RegisterScript(manager IHelpersManager, fns ...*ScriptFunction) error {
+	funcs := template.FuncMap{}
+	for _, h := range manager.Get() {
+		funcs[h.Name] = h.Function
+	}
+
+	set := template.New("")
+	for _, fn := range fns {
+		if manager.Contains(fn.Name) {
+			return fmt.Errorf("unable to register function '%s', a helper by that name is already registered", fn.Name)
+		}
+		funcs[fn.Name] = scriptFunc(set, fn)
+	}
+	set.Funcs(funcs)
+
+	for _, fn := range fns {
+		if fn.starlark != nil {
+			continue
+		}
+		if _, err := set.New(fn.Name).Parse(fn.Body); err != nil {
+			return fmt.Errorf("unable to parse the body of function '%s', %v", fn.Name, err)
+		}
+	}
+
+	for _, fn := range fns {
+		if err := manager.Register(fn.Name, funcs[fn.Name], append([]string{fn.Description}, fn.Examples...)...); err != nil {
+			return err
+		}
+		for _, h := range manager.Get() {
+			if h.Name == fn.Name {
+				h.Category = ScriptCategory
+			}
+		}
+	}
+	return nil
+}
+
+// scriptFunc creates a function with one argument per parameter of the script function, which renders its body from
+// the given template set, or calls its Starlark function.
+func scriptFunc(set *template.Template, fn *ScriptFunction) interface{} {
+	if fn.starlark != nil {
+		return starlarkFunc(fn)
+	}
+	return FixedArity(len(fn.Params), func(args ...interface{}) interface{} {
+		data := map[string]interface{}{"args": args}
+		for i, arg := range args {
+			data[fn.Params[i]] = arg
+		}
+
+		buf := &bytes.Buffer{}
+		if err := set.ExecuteTemplate(buf, fn.Name, data); err != nil {
+			panic(fmt.Errorf("function '%s' failed, %v", fn.Name, err))
+		}
+		return buf.String()
+	})
+}
+
+// FixedArity wraps the given function into a function that receives exactly the given number of arguments, for the
+// template engines that do not support variadic helpers.
+func FixedArity(arity int, fn func(args ...interface{}) interface{}) interface{} {
+	anyType := reflect.TypeOf((*interface{})(nil)).Elem()
+	in := make([]reflect.Type, arity)
+	for i := range in {
+		in[i] = anyType
+	}
+	fnType := reflect.FuncOf(in, []reflect.Type{anyType}, false)
+
+	return reflect.MakeFunc(fnType, func(values []reflect.Value) []reflect.Value {
+		args := make([]interface{}, len(values))
+		for i, v := range values {
+			args[i] = v.Interface()
+		}
+		ret := reflect.New(anyType).Elem()
+		if result := fn(args...); result != nil {
+			ret.Set(reflect.ValueOf(result))
+		}
+		return []reflect.Value{ret}
+	}).Interface()
+}
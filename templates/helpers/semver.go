@@ -0,0 +1,388 @@
+package helpers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/** In this file are defined the semantic version helpers of the common set. */
+
+var versionRegex = regexp.MustCompile(`^[vV]?(\d+)(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+// registerSemver registers the semantic version helpers. The versions may start with 'v' and omit the minor and patch
+// numbers, e.g: 'v1.2', which are then 0.
+func registerSemver(manager IHelpersManager) {
+	_ = manager.Register("semver", semverFn, "Parses a semantic version into a map with the major, minor, patch, prerelease and metadata. E.g: {{ (semver .version).major }}")
+	_ = manager.Register("semverCompare", semverCompare, "Indicates whether a version satisfies the constraint, with comparators separated by spaces or commas, '||' alternatives, ranges as '1.2 - 1.4', wildcards as '1.x', '~' and '^'. E.g: {{ if semverCompare \">=1.2 <2.0\" .version }}")
+	_ = manager.Register("semverBumpMajor", semverBumpMajor, "Increments the major number of a version, resetting the minor and patch. E.g: {{ semverBumpMajor \"1.2.3\" }} returns 2.0.0")
+	_ = manager.Register("semverBumpMinor", semverBumpMinor, "Increments the minor number of a version, resetting the patch. E.g: {{ semverBumpMinor \"1.2.3\" }} returns 1.3.0")
+	_ = manager.Register("semverBumpPatch", semverBumpPatch, "Increments the patch number of a version, or releases it if it is a prerelease. E.g: {{ semverBumpPatch \"1.2.3\" }} returns 1.2.4")
+	_ = manager.Register("semverSort", semverSort, "Sorts a list of versions by their precedence, from the lowest to the highest. E.g: {{ last (semverSort .tags) }}")
+}
+
+// version is a parsed semantic version. The 'partial' count indicates the number of version numbers given, the rest
+// are wildcards or missing, e.g: 2 for '1.2' or '1.2.x'.
+type version struct {
+	major, minor, patch int64
+	prerelease          string
+	metadata            string
+	prefix              string
+	partial             int
+}
+
+func parseVersion(v string) (*version, error) {
+	match := versionRegex.FindStringSubmatch(strings.TrimSpace(v))
+	if match == nil {
+		return nil, fmt.Errorf("invalid semantic version '%s'", v)
+	}
+	ret := &version{prerelease: match[4], metadata: match[5], partial: 1}
+	if strings.HasPrefix(strings.ToLower(match[0]), "v") {
+		ret.prefix = match[0][:1]
+	}
+	numbers := []*int64{&ret.major, &ret.minor, &ret.patch}
+	for i, s := range match[1:4] {
+		if s == "" || strings.ContainsAny(s, "xX*") {
+			break
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid semantic version '%s', %v", v, err)
+		}
+		*numbers[i] = n
+		ret.partial = i + 1
+	}
+	return ret, nil
+}
+
+func (v *version) String() string {
+	ret := fmt.Sprintf("%s%d.%d.%d", v.prefix, v.major, v.minor, v.patch)
+	if v.prerelease != "" {
+		ret += "-" + v.prerelease
+	}
+	if v.metadata != "" {
+		ret += "+" + v.metadata
+	}
+	return ret
+}
+
+// compare compares the versions by their precedence, where the metadata is ignored and a prerelease is lower than its
+// release.
+func (v *version) compare(o *version) int {
+	for _, pair := range [][2]int64{{v.major, o.major}, {v.minor, o.minor}, {v.patch, o.patch}} {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.prerelease == o.prerelease:
+		return 0
+	case v.prerelease == "":
+		return 1
+	case o.prerelease == "":
+		return -1
+	}
+	a, b := strings.Split(v.prerelease, "."), strings.Split(o.prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareIdentifiers(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(int64(len(a)), int64(len(b)))
+}
+
+// compareIdentifiers compares the identifiers of prereleases, numerically if both are numbers, and numbers are lower
+// than alphanumeric identifiers.
+func compareIdentifiers(a, b string) int {
+	x, errA := strconv.ParseInt(a, 10, 64)
+	y, errB := strconv.ParseInt(b, 10, 64)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInts(x, y)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// next returns the lowest version greater than all the versions matched by the partial version, e.g: 1.3.0 for '1.2'.
+func (v *version) next() *version {
+	switch v.partial {
+	case 1:
+		return &version{major: v.major + 1}
+	case 2:
+		return &version{major: v.major, minor: v.minor + 1}
+	}
+	return &version{major: v.major, minor: v.minor, patch: v.patch + 1}
+}
+
+func semverFn(v string) (map[string]interface{}, error) {
+	ver, err := parseVersion(v)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"major":      ver.major,
+		"minor":      ver.minor,
+		"patch":      ver.patch,
+		"prerelease": ver.prerelease,
+		"metadata":   ver.metadata,
+		"version":    ver.String(),
+	}, nil
+}
+
+func semverBumpMajor(v string) (string, error) {
+	return bump(v, func(ver *version) {
+		if ver.minor != 0 || ver.patch != 0 || ver.prerelease == "" {
+			ver.major++
+		}
+		ver.minor, ver.patch = 0, 0
+	})
+}
+
+func semverBumpMinor(v string) (string, error) {
+	return bump(v, func(ver *version) {
+		if ver.patch != 0 || ver.prerelease == "" {
+			ver.minor++
+		}
+		ver.patch = 0
+	})
+}
+
+func semverBumpPatch(v string) (string, error) {
+	return bump(v, func(ver *version) {
+		if ver.prerelease == "" {
+			ver.patch++
+		}
+	})
+}
+
+// bump increments the version and removes the prerelease and metadata. Incrementing a prerelease releases it, e.g:
+// the next patch of 1.2.3-rc.1 is 1.2.3, as the next minor of 1.3.0-rc.1 is 1.3.0.
+func bump(v string, fn func(ver *version)) (string, error) {
+	ver, err := parseVersion(v)
+	if err != nil {
+		return "", err
+	}
+	fn(ver)
+	ver.prerelease, ver.metadata = "", ""
+	return ver.String(), nil
+}
+
+func semverSort(v interface{}) ([]string, error) {
+	list := toStrings(v)
+	versions := make([]*version, len(list))
+	for i, s := range list {
+		ver, err := parseVersion(s)
+		if err != nil {
+			return nil, err
+		}
+		versions[i] = ver
+	}
+	indexes := make([]int, len(list))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return versions[indexes[i]].compare(versions[indexes[j]]) < 0
+	})
+	ret := make([]string, len(list))
+	for i, index := range indexes {
+		ret[i] = list[index]
+	}
+	return ret, nil
+}
+
+func semverCompare(constraint, v string) (bool, error) {
+	ver, err := parseVersion(v)
+	if err != nil {
+		return false, err
+	}
+	alternatives, err := parseConstraint(constraint)
+	if err != nil {
+		return false, err
+	}
+	for _, comparators := range alternatives {
+		matches := ver.prerelease == "" || allowsPrerelease(comparators, ver)
+		for _, c := range comparators {
+			if !c.matches(ver) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// allowsPrerelease indicates whether the comparators may match the prerelease version, which as npm and Helm do requires
+// a comparator with a prerelease of the same major, minor and patch, e.g: '>=1.2.3-rc.1' matches 1.2.3-rc.2 but not
+// 1.2.4-rc.1, and '<2.0' does not match 2.0.0-rc.1.
+func allowsPrerelease(comparators []comparator, v *version) bool {
+	for _, c := range comparators {
+		for _, ver := range []*version{c.ver, c.upper} {
+			if ver != nil && ver.prerelease != "" && ver.major == v.major && ver.minor == v.minor && ver.patch == v.patch {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/** Constraints */
+
+// comparator compares a version against a full version with one of the operators =, !=, <, <=, > or >=. A '!='
+// comparator with an upper version excludes the range between both versions.
+type comparator struct {
+	op    string
+	ver   *version
+	upper *version
+}
+
+func (c comparator) matches(v *version) bool {
+	cmp := v.compare(c.ver)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		if c.upper != nil {
+			return cmp < 0 || v.compare(c.upper) >= 0
+		}
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+var constraintRegex = regexp.MustCompile(`^(=|!=|<=|>=|<|>|~>|~|\^)?\s*(\S+)$`)
+
+// parseConstraint parses the constraint into the alternatives separated by '||', where each alternative is the list
+// of comparators which must match.
+func parseConstraint(constraint string) ([][]comparator, error) {
+	var ret [][]comparator
+	for _, alternative := range strings.Split(constraint, "||") {
+		var comparators []comparator
+		fields := strings.Fields(strings.ReplaceAll(alternative, ",", " "))
+		for i := 0; i < len(fields); i++ {
+			if i+2 < len(fields) && fields[i+1] == "-" {
+				c, err := hyphenRange(fields[i], fields[i+2])
+				if err != nil {
+					return nil, fmt.Errorf("invalid constraint '%s', %v", constraint, err)
+				}
+				comparators = append(comparators, c...)
+				i += 2
+				continue
+			}
+			field := fields[i]
+			// Allows a space between the operator and the version, e.g: '>= 1.2'
+			if strings.Trim(field, "=!<>~^") == "" && i+1 < len(fields) {
+				field += fields[i+1]
+				i++
+			}
+			c, err := parseComparator(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid constraint '%s', %v", constraint, err)
+			}
+			comparators = append(comparators, c...)
+		}
+		ret = append(ret, comparators)
+	}
+	return ret, nil
+}
+
+// parseComparator expands a comparator with a partial version, a tilde or a caret into comparators with full versions.
+func parseComparator(s string) ([]comparator, error) {
+	match := constraintRegex.FindStringSubmatch(s)
+	if match == nil {
+		return nil, fmt.Errorf("invalid comparator '%s'", s)
+	}
+	op, ver := match[1], match[2]
+	if ver == "*" || ver == "x" || ver == "X" {
+		return nil, nil
+	}
+	v, err := parseVersion(ver)
+	if err != nil {
+		return nil, err
+	}
+	v.metadata = ""
+
+	switch op {
+	case "", "=":
+		if v.partial == 3 {
+			return []comparator{{op: "=", ver: v}}, nil
+		}
+		return []comparator{{op: ">=", ver: v}, {op: "<", ver: v.next()}}, nil
+	case "!=":
+		if v.partial == 3 {
+			return []comparator{{op: "!=", ver: v}}, nil
+		}
+		return []comparator{{op: "!=", ver: v, upper: v.next()}}, nil
+	case ">":
+		if v.partial == 3 {
+			return []comparator{{op: ">", ver: v}}, nil
+		}
+		return []comparator{{op: ">=", ver: v.next()}}, nil
+	case "<=":
+		if v.partial == 3 {
+			return []comparator{{op: "<=", ver: v}}, nil
+		}
+		return []comparator{{op: "<", ver: v.next()}}, nil
+	case "~", "~>":
+		upper := &version{major: v.major, minor: v.minor + 1}
+		if v.partial == 1 {
+			upper = &version{major: v.major + 1}
+		}
+		return []comparator{{op: ">=", ver: v}, {op: "<", ver: upper}}, nil
+	case "^":
+		upper := &version{major: v.major + 1}
+		switch {
+		case v.major == 0 && v.partial == 1:
+		case v.major == 0 && (v.minor != 0 || v.partial == 2):
+			upper = &version{minor: v.minor + 1}
+		case v.major == 0:
+			upper = &version{patch: v.patch + 1}
+		}
+		return []comparator{{op: ">=", ver: v}, {op: "<", ver: upper}}, nil
+	}
+	return []comparator{{op: op, ver: v}}, nil
+}
+
+// hyphenRange returns the comparators of an inclusive range, e.g: '1.2 - 1.4' matches from 1.2.0 to any 1.4 version.
+func hyphenRange(from, to string) ([]comparator, error) {
+	lower, err := parseVersion(from)
+	if err != nil {
+		return nil, err
+	}
+	upper, err := parseVersion(to)
+	if err != nil {
+		return nil, err
+	}
+	if upper.partial < 3 {
+		return []comparator{{op: ">=", ver: lower}, {op: "<", ver: upper.next()}}, nil
+	}
+	return []comparator{{op: ">=", ver: lower}, {op: "<=", ver: upper}}, nil
+}
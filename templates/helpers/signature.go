@@ -0,0 +1,66 @@
+package helpers
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var exampleRegex = regexp.MustCompile(`(?i)(?:e\.?g\.?|usage)\s*:?\s*(\{\{.*?\}\})`)
+
+// Signature describes the parameter and return types of a helper function.
+type Signature struct {
+	Params   []string `json:"params"`
+	Variadic bool     `json:"variadic"`
+	Returns  []string `json:"returns"`
+}
+
+// Signature introspects the function of the helper and returns its signature, or nil if the helper has no function,
+// such as the built-in functions of a template engine.
+func (h *Helper) Signature() *Signature {
+	if h.Function == nil {
+		return nil
+	}
+	fnType := reflect.TypeOf(h.Function)
+	if fnType.Kind() != reflect.Func {
+		return nil
+	}
+
+	ret := &Signature{Variadic: fnType.IsVariadic(), Params: []string{}, Returns: []string{}}
+	for i := 0; i < fnType.NumIn(); i++ {
+		if ret.Variadic && i == fnType.NumIn()-1 {
+			ret.Params = append(ret.Params, "..."+typeName(fnType.In(i).Elem()))
+		} else {
+			ret.Params = append(ret.Params, typeName(fnType.In(i)))
+		}
+	}
+	for i := 0; i < fnType.NumOut(); i++ {
+		ret.Returns = append(ret.Returns, typeName(fnType.Out(i)))
+	}
+	return ret
+}
+
+// String returns the signature in Go syntax, e.g: '(string, ...interface{}) (string, error)'
+func (s *Signature) String() string {
+	ret := "(" + strings.Join(s.Params, ", ") + ")"
+	switch len(s.Returns) {
+	case 0:
+		return ret
+	case 1:
+		return ret + " " + s.Returns[0]
+	}
+	return ret + " (" + strings.Join(s.Returns, ", ") + ")"
+}
+
+func typeName(t reflect.Type) string {
+	return strings.ReplaceAll(t.String(), "interface {}", "interface{}")
+}
+
+// examplesFrom returns the examples given in a helper description, e.g: 'Usage: {{ helper "arg" }}'
+func examplesFrom(description string) []string {
+	var ret []string
+	for _, match := range exampleRegex.FindAllStringSubmatch(description, -1) {
+		ret = append(ret, match[1])
+	}
+	return ret
+}
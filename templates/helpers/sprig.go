@@ -0,0 +1,468 @@
+package helpers
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+/** In this file are defined the helpers of the Sprig pack, compatible with the Sprig functions used by Helm charts. */
+
+// SprigPack is the name of the helper pack compatible with the Sprig functions
+const SprigPack = "sprig"
+
+func init() {
+	RegisterPack(SprigPack, "Sprig", RegisterSprig)
+}
+
+// RegisterSprig registers the helpers compatible with the names and semantics of the Sprig functions.
+func RegisterSprig(manager IHelpersManager) {
+	// Strings
+	_ = manager.Register("trim", strings.TrimSpace, "Removes the leading and trailing white space of a string. E.g: {{ trim \"  hello  \" }}")
+	_ = manager.Register("trimAll", func(cutset, s string) string { return strings.Trim(s, cutset) }, "Removes the given characters from the front and back of a string. E.g: {{ trimAll \"$\" \"$5.00\" }}")
+	_ = manager.Register("trimPrefix", func(prefix, s string) string { return strings.TrimPrefix(s, prefix) }, "Removes the prefix from a string. E.g: {{ trimPrefix \"-\" \"-hello\" }}")
+	_ = manager.Register("trimSuffix", func(suffix, s string) string { return strings.TrimSuffix(s, suffix) }, "Removes the suffix from a string. E.g: {{ trimSuffix \"-\" \"hello-\" }}")
+	_ = manager.Register("upper", strings.ToUpper, "Converts a string to upper case. E.g: {{ upper \"hello\" }}")
+	_ = manager.Register("lower", strings.ToLower, "Converts a string to lower case. E.g: {{ lower \"HELLO\" }}")
+	_ = manager.Register("title", sprigTitle, "Converts the first letter of each word to upper case. E.g: {{ title \"hello world\" }}")
+	_ = manager.Register("untitle", sprigUntitle, "Converts the first letter of each word to lower case. E.g: {{ untitle \"Hello World\" }}")
+	_ = manager.Register("repeat", func(count int, s string) string { return strings.Repeat(s, count) }, "Repeats a string the given number of times. E.g: {{ repeat 3 \"hello\" }}")
+	_ = manager.Register("substr", sprigSubstr, "Returns the part of a string between the start and end positions. E.g: {{ substr 0 5 \"hello world\" }}")
+	_ = manager.Register("nospace", sprigNospace, "Removes all the white space from a string. E.g: {{ nospace \"hello w o r l d\" }}")
+	_ = manager.Register("trunc", sprigTrunc, "Truncates a string to the given length, a negative length keeps the end of the string. E.g: {{ trunc 5 \"hello world\" }}")
+	_ = manager.Register("abbrev", sprigAbbrev, "Truncates a string with ellipses to the given maximum length. E.g: {{ abbrev 5 \"hello world\" }}")
+	_ = manager.Register("initials", sprigInitials, "Returns the first letter of each word. E.g: {{ initials \"First Try\" }}")
+	_ = manager.Register("contains", func(substr, s string) bool { return strings.Contains(s, substr) }, "Indicates whether a string contains another. E.g: {{ contains \"cat\" \"catch\" }}")
+	_ = manager.Register("hasPrefix", func(prefix, s string) bool { return strings.HasPrefix(s, prefix) }, "Indicates whether a string starts with the prefix. E.g: {{ hasPrefix \"cat\" \"catch\" }}")
+	_ = manager.Register("hasSuffix", func(suffix, s string) bool { return strings.HasSuffix(s, suffix) }, "Indicates whether a string ends with the suffix. E.g: {{ hasSuffix \"ch\" \"catch\" }}")
+	_ = manager.Register("quote", sprigQuote, "Wraps each argument in double quotes. E.g: {{ .name | quote }}")
+	_ = manager.Register("squote", sprigSquote, "Wraps each argument in single quotes. E.g: {{ .name | squote }}")
+	_ = manager.Register("cat", sprigCat, "Concatenates the arguments into a string, separated by spaces. E.g: {{ cat \"hello\" \"beautiful\" \"world\" }}")
+	_ = manager.Register("indent", sprigIndent, "Indents every line of a string by the given number of spaces. E.g: {{ toYaml .resources | indent 4 }}")
+	_ = manager.Register("nindent", sprigNindent, "Same as indent, but prepends a new line to the string. E.g: {{ toYaml .resources | nindent 4 }}")
+	_ = manager.Register("replace", func(old, new, src string) string { return strings.Replace(src, old, new, -1) }, "Replaces all the occurrences of a string. E.g: {{ \"I Am Henry VIII\" | replace \" \" \"-\" }}")
+	_ = manager.Register("plural", sprigPlural, "Returns the first string if the count is 1, otherwise the second. E.g: {{ len .items | plural \"one item\" \"many items\" }}")
+	_ = manager.Register("snakecase", func(s string) string { return joinWords(s, "_", strings.ToLower) }, "Converts a string to snake_case. E.g: {{ snakecase \"FirstName\" }}")
+	_ = manager.Register("camelcase", func(s string) string { return joinWords(s, "", sprigTitle) }, "Converts a string to CamelCase. E.g: {{ camelcase \"http_server\" }}")
+	_ = manager.Register("kebabcase", func(s string) string { return joinWords(s, "-", strings.ToLower) }, "Converts a string to kebab-case. E.g: {{ kebabcase \"FirstName\" }}")
+	_ = manager.Register("toString", sprigString, "Converts a value to a string. E.g: {{ toString 42 }}")
+	_ = manager.Register("toStrings", toStrings, "Converts a list to a list of strings. E.g: {{ list 1 2 3 | toStrings }}")
+	_ = manager.Register("join", sprigJoin, "Joins the elements of a list into a string with the given separator. E.g: {{ list \"a\" \"b\" | join \",\" }}")
+	_ = manager.Register("split", sprigSplit, "Splits a string into a map with the keys _0, _1, ... E.g: {{ $parts := split \"$\" \"foo$bar\" }}{{ $parts._0 }}")
+	_ = manager.Register("splitn", sprigSplitn, "Splits a string into a map of at most n parts, with the keys _0, _1, ... E.g: {{ splitn \"$\" 2 \"foo$bar$baz\" }}")
+	_ = manager.Register("splitList", func(sep, s string) []string { return strings.Split(s, sep) }, "Splits a string into a list. E.g: {{ splitList \",\" \"a,b,c\" }}")
+
+	// Defaults and flow control
+	_ = manager.Register("default", sprigDefault, "Returns the default value if the given value is empty. E.g: {{ .name | default \"foo\" }}")
+	_ = manager.Register("empty", isEmpty, "Indicates whether the value is empty: zero, nil, false or an empty string, list or map. E.g: {{ if empty .name }}")
+	_ = manager.Register("coalesce", sprigCoalesce, "Returns the first non empty value. E.g: {{ coalesce .name .parent.name \"default\" }}")
+	_ = manager.Register("all", sprigAll, "Indicates whether all the values are non empty. E.g: {{ if all .a .b }}")
+	_ = manager.Register("any", sprigAny, "Indicates whether any of the values is non empty. E.g: {{ if any .a .b }}")
+	_ = manager.Register("ternary", sprigTernary, "Returns the first value if the condition is true, otherwise the second. E.g: {{ .enabled | ternary \"on\" \"off\" }}")
+	_ = manager.Register("required", sprigRequired, "Fails the render with the given message if the value is empty. E.g: {{ required \"the name is required\" .name }}")
+	_ = manager.Register("fail", func(msg string) (string, error) { return "", errors.New(msg) }, "Fails the render with the given message. E.g: {{ fail \"unsupported value\" }}")
+
+	// Serialization and encoding
+	_ = manager.Register("toJson", sprigToJSON, "Encodes a value as JSON. E.g: {{ toJson .labels }}")
+	_ = manager.Register("toPrettyJson", sprigToPrettyJSON, "Encodes a value as indented JSON. E.g: {{ toPrettyJson .labels }}")
+	_ = manager.Register("toRawJson", sprigToRawJSON, "Encodes a value as JSON without escaping HTML characters. E.g: {{ toRawJson .labels }}")
+	_ = manager.Register("fromJson", sprigFromJSON, "Decodes a JSON object into a map. E.g: {{ (fromJson .raw).name }}")
+	_ = manager.Register("toYaml", sprigToYAML, "Encodes a value as YAML, without the trailing new line. E.g: {{ toYaml .resources | nindent 2 }}")
+	_ = manager.Register("fromYaml", sprigFromYAML, "Decodes a YAML object into a map. E.g: {{ (fromYaml .raw).name }}")
+	_ = manager.Register("b64enc", base64Encode, "Encodes a string as base64. E.g: {{ .password | b64enc }}")
+	_ = manager.Register("b64dec", sprigB64dec, "Decodes a base64 string. E.g: {{ .encoded | b64dec }}")
+	_ = manager.Register("b32enc", base32Encode, "Encodes a string as base32. E.g: {{ .value | b32enc }}")
+	_ = manager.Register("b32dec", sprigB32dec, "Decodes a base32 string. E.g: {{ .encoded | b32dec }}")
+	_ = manager.Register("sha1sum", sha1sum, "Returns the hex encoded SHA-1 digest of a string. E.g: {{ sha1sum .value }}")
+	_ = manager.Register("sha256sum", sha256sum, "Returns the hex encoded SHA-256 digest of a string. E.g: {{ include \"config\" . | sha256sum }}")
+
+	// Types
+	_ = manager.Register("typeOf", func(v interface{}) string { return fmt.Sprintf("%T", v) }, "Returns the type of a value. E.g: {{ typeOf .value }}")
+	_ = manager.Register("typeIs", func(target string, v interface{}) bool { return target == fmt.Sprintf("%T", v) }, "Indicates whether a value is of the given type. E.g: {{ typeIs \"string\" .value }}")
+	_ = manager.Register("typeIsLike", sprigTypeIsLike, "Same as typeIs, but also matches a pointer to the type. E.g: {{ typeIsLike \"string\" .value }}")
+	_ = manager.Register("kindOf", sprigKindOf, "Returns the kind of a value: string, slice, map, int... E.g: {{ kindOf .value }}")
+	_ = manager.Register("kindIs", func(target string, v interface{}) bool { return target == sprigKindOf(v) }, "Indicates whether a value is of the given kind. E.g: {{ kindIs \"map\" .value }}")
+	_ = manager.Register("deepEqual", reflect.DeepEqual, "Indicates whether two values are deeply equal. E.g: {{ deepEqual .a .b }}")
+
+	// OS and paths
+	_ = manager.Register("env", os.Getenv, "Returns the value of an environment variable. E.g: {{ env \"HOME\" }}")
+	_ = manager.Register("expandenv", os.ExpandEnv, "Replaces the $VAR and ${VAR} references with the values of the environment variables. E.g: {{ expandenv \"Home is $HOME\" }}")
+	_ = manager.Register("base", path.Base, "Returns the last element of a path. E.g: {{ base \"foo/bar/baz\" }}")
+	_ = manager.Register("dir", path.Dir, "Returns the directory of a path. E.g: {{ dir \"foo/bar/baz\" }}")
+	_ = manager.Register("clean", path.Clean, "Cleans up a path. E.g: {{ clean \"foo/bar/../baz\" }}")
+	_ = manager.Register("ext", path.Ext, "Returns the file extension of a path. E.g: {{ ext \"foo.bar\" }}")
+	_ = manager.Register("isAbs", path.IsAbs, "Indicates whether a path is absolute. E.g: {{ isAbs \"/foo\" }}")
+
+	registerSprigData(manager)
+}
+
+/** Strings */
+
+// sprigTitle converts the first letter of each word to upper case with strings.Title, as Sprig does, so the words are
+// separated by any character other than letters, digits and '_'. E.g: 'hello-world foo_bar' is 'Hello-World Foo_bar'
+func sprigTitle(s string) string {
+	return strings.Title(s)
+}
+
+func sprigUntitle(s string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		defer func() { prev = r }()
+		if unicode.IsSpace(prev) {
+			return unicode.ToLower(r)
+		}
+		return r
+	}, s)
+}
+
+func sprigSubstr(start, end int, s string) string {
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 || end > len(s) {
+		end = len(s)
+	}
+	if start > end {
+		return ""
+	}
+	return s[start:end]
+}
+
+func sprigNospace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func sprigTrunc(length int, s string) string {
+	if length < 0 && len(s)+length > 0 {
+		return s[len(s)+length:]
+	}
+	if length >= 0 && len(s) > length {
+		return s[:length]
+	}
+	return s
+}
+
+func sprigAbbrev(width int, s string) string {
+	if width < 4 || len(s) <= width {
+		return s
+	}
+	return s[:width-3] + "..."
+}
+
+func sprigInitials(s string) string {
+	var ret []rune
+	for _, word := range strings.Fields(s) {
+		ret = append(ret, []rune(word)[0])
+	}
+	return string(ret)
+}
+
+func sprigQuote(args ...interface{}) string {
+	var ret []string
+	for _, arg := range args {
+		if arg != nil {
+			ret = append(ret, strconv.Quote(sprigString(arg)))
+		}
+	}
+	return strings.Join(ret, " ")
+}
+
+func sprigSquote(args ...interface{}) string {
+	var ret []string
+	for _, arg := range args {
+		if arg != nil {
+			ret = append(ret, "'"+sprigString(arg)+"'")
+		}
+	}
+	return strings.Join(ret, " ")
+}
+
+func sprigCat(args ...interface{}) string {
+	var ret []string
+	for _, arg := range args {
+		if arg != nil {
+			ret = append(ret, sprigString(arg))
+		}
+	}
+	return strings.Join(ret, " ")
+}
+
+func sprigIndent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+func sprigNindent(spaces int, s string) string {
+	return "\n" + sprigIndent(spaces, s)
+}
+
+func sprigPlural(one, many string, count int) string {
+	if count == 1 {
+		return one
+	}
+	return many
+}
+
+// joinWords splits a string into words, by separators and by changes of case, and joins them with the given separator
+// after applying the given function to each word.
+func joinWords(s, sep string, fn func(string) string) string {
+	var (
+		words []string
+		word  []rune
+		runes = []rune(s)
+	)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == '.' || unicode.IsSpace(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				words = append(words, string(word))
+				word = nil
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	for i, w := range words {
+		words[i] = fn(strings.ToLower(w))
+	}
+	return strings.Join(words, sep)
+}
+
+func sprigString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	case error:
+		return s.Error()
+	case fmt.Stringer:
+		return s.String()
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func toStrings(v interface{}) []string {
+	var ret []string
+	for _, item := range toList(v, "toStrings") {
+		ret = append(ret, sprigString(item))
+	}
+	return ret
+}
+
+func sprigJoin(sep string, v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	var items []string
+	for _, item := range toList(v, "join") {
+		if item != nil {
+			items = append(items, sprigString(item))
+		}
+	}
+	return strings.Join(items, sep)
+}
+
+func sprigSplit(sep, s string) map[string]string {
+	return splitMap(strings.Split(s, sep))
+}
+
+func sprigSplitn(sep string, n int, s string) map[string]string {
+	return splitMap(strings.SplitN(s, sep, n))
+}
+
+func splitMap(parts []string) map[string]string {
+	ret := make(map[string]string, len(parts))
+	for i, p := range parts {
+		ret["_"+strconv.Itoa(i)] = p
+	}
+	return ret
+}
+
+/** Defaults and flow control */
+
+func sprigDefault(d interface{}, given ...interface{}) interface{} {
+	if len(given) == 0 || isEmpty(given[0]) {
+		return d
+	}
+	return given[0]
+}
+
+func isEmpty(v interface{}) bool {
+	val := reflect.ValueOf(v)
+	if !val.IsValid() {
+		return true
+	}
+	switch val.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.String:
+		return val.Len() == 0
+	case reflect.Bool:
+		return !val.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return val.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return val.Float() == 0
+	case reflect.Complex64, reflect.Complex128:
+		return val.Complex() == 0
+	case reflect.Struct:
+		return false
+	}
+	return val.IsNil()
+}
+
+func sprigCoalesce(args ...interface{}) interface{} {
+	for _, arg := range args {
+		if !isEmpty(arg) {
+			return arg
+		}
+	}
+	return nil
+}
+
+func sprigAll(args ...interface{}) bool {
+	for _, arg := range args {
+		if isEmpty(arg) {
+			return false
+		}
+	}
+	return true
+}
+
+func sprigAny(args ...interface{}) bool {
+	for _, arg := range args {
+		if !isEmpty(arg) {
+			return true
+		}
+	}
+	return false
+}
+
+func sprigTernary(vt, vf interface{}, condition bool) interface{} {
+	if condition {
+		return vt
+	}
+	return vf
+}
+
+func sprigRequired(msg string, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, errors.New(msg)
+	}
+	if s, ok := v.(string); ok && s == "" {
+		return nil, errors.New(msg)
+	}
+	return v, nil
+}
+
+/** Serialization and encoding */
+
+func sprigToJSON(v interface{}) string {
+	data, err := json.Marshal(jsonValue(v))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func sprigToPrettyJSON(v interface{}) string {
+	data, err := json.MarshalIndent(jsonValue(v), "", "  ")
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func sprigToRawJSON(v interface{}) string {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(jsonValue(v)); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func sprigFromJSON(s string) map[string]interface{} {
+	ret := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(s), &ret); err != nil {
+		ret["Error"] = err.Error()
+	}
+	return ret
+}
+
+func sprigToYAML(v interface{}) string {
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+func sprigFromYAML(s string) map[string]interface{} {
+	ret := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(s), &ret); err != nil {
+		ret["Error"] = err.Error()
+		return ret
+	}
+	return jsonValue(ret).(map[string]interface{})
+}
+
+func sprigB64dec(s string) string {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+func sprigB32dec(s string) string {
+	data, err := base32.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+/** Types */
+
+func sprigTypeIsLike(target string, v interface{}) bool {
+	t := fmt.Sprintf("%T", v)
+	return target == t || "*"+target == t
+}
+
+func sprigKindOf(v interface{}) string {
+	if v == nil {
+		return "invalid"
+	}
+	return reflect.ValueOf(v).Kind().String()
+}
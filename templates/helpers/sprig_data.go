@@ -0,0 +1,255 @@
+package helpers
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/jucardi/infuse/util/maps"
+)
+
+/** In this file are defined the list, dictionary and math helpers of the Sprig pack. */
+
+func registerSprigData(manager IHelpersManager) {
+	// Lists
+	_ = manager.Register("list", func(v ...interface{}) []interface{} { return v }, "Creates a list of the given values. E.g: {{ list 1 2 3 }}")
+	_ = manager.Register("first", listFirst, "Returns the first element of a list. E.g: {{ first .items }}")
+	_ = manager.Register("last", listLast, "Returns the last element of a list. E.g: {{ last .items }}")
+	_ = manager.Register("rest", listRest, "Returns all the elements of a list but the first. E.g: {{ rest .items }}")
+	_ = manager.Register("initial", sprigInitial, "Returns all the elements of a list but the last. E.g: {{ initial .items }}")
+	_ = manager.Register("append", sprigAppend, "Returns a new list with the value appended. E.g: {{ append .items 4 }}")
+	_ = manager.Register("prepend", sprigPrepend, "Returns a new list with the value prepended. E.g: {{ prepend .items 0 }}")
+	_ = manager.Register("concat", listConcat, "Concatenates the given lists. E.g: {{ concat .a .b }}")
+	_ = manager.Register("reverse", listReverse, "Returns a new list with the elements in reverse order. E.g: {{ reverse .items }}")
+	_ = manager.Register("uniq", listUniq, "Returns a new list without the repeated elements. E.g: {{ uniq .items }}")
+	_ = manager.Register("without", sprigWithout, "Returns a new list without the given values. E.g: {{ without .items 1 3 }}")
+	_ = manager.Register("has", sprigHas, "Indicates whether a list contains the value. E.g: {{ has 4 .items }}")
+	_ = manager.Register("compact", listCompact, "Returns a new list without the empty elements. E.g: {{ compact .items }}")
+	_ = manager.Register("slice", listSlice, "Returns the part of a list between the given indexes. E.g: {{ slice .items 1 3 }}")
+	_ = manager.Register("sortAlpha", listSortAlpha, "Sorts a list of strings alphabetically. E.g: {{ sortAlpha .names }}")
+	_ = manager.Register("until", func(count int) []int { return sprigUntilStep(0, count, 1) }, "Returns a list of integers from 0 to the given count, exclusive. E.g: {{ range until 3 }}")
+	_ = manager.Register("untilStep", sprigUntilStep, "Returns a list of integers from start to stop, exclusive, by the given step. E.g: {{ range untilStep 0 10 2 }}")
+
+	// Dictionaries
+	_ = manager.Register("dict", sprigDict, "Creates a dictionary from a list of key and value pairs. E.g: {{ dict \"name\" \"api\" \"port\" 80 }}")
+	_ = manager.Register("get", sprigGet, "Returns the value of a key in a dictionary, or an empty string. E.g: {{ get .labels \"app\" }}")
+	_ = manager.Register("set", sprigSet, "Sets a key in a dictionary and returns the dictionary. E.g: {{ $_ := set .labels \"app\" \"api\" }}")
+	_ = manager.Register("unset", dictUnset, "Removes a key from a dictionary and returns the dictionary. E.g: {{ $_ := unset .labels \"app\" }}")
+	_ = manager.Register("hasKey", dictHasKey, "Indicates whether a dictionary contains the key. E.g: {{ if hasKey .labels \"app\" }}")
+	_ = manager.Register("pluck", sprigPluck, "Returns the values of the key in each of the dictionaries, or in each element of a single list. E.g: {{ pluck \"name\" .a .b }}")
+	_ = manager.Register("keys", dictKeys, "Returns the sorted keys of the dictionaries. E.g: {{ keys .labels }}")
+	_ = manager.Register("values", dictValues, "Returns the values of a dictionary, sorted by key. E.g: {{ values .labels }}")
+	_ = manager.Register("pick", dictPick, "Returns a new dictionary with only the given keys. E.g: {{ pick .labels \"app\" \"tier\" }}")
+	_ = manager.Register("omit", dictOmit, "Returns a new dictionary without the given keys. E.g: {{ omit .labels \"app\" }}")
+	_ = manager.Register("dig", dictDig, "Returns the value at the path of keys in nested dictionaries, or the default value. E.g: {{ dig \"user\" \"role\" \"guest\" .data }}")
+	_ = manager.Register("merge", dictMerge, "Deep merges the dictionaries into the first one, without overwriting existing keys. E.g: {{ merge $dst $src1 $src2 }}")
+	_ = manager.Register("mergeOverwrite", dictMergeOverwrite, "Deep merges the dictionaries into the first one, overwriting existing keys. E.g: {{ mergeOverwrite $dst $src1 $src2 }}")
+	_ = manager.Register("deepCopy", maps.DeepCopy, "Returns a deep copy of a value. E.g: {{ deepCopy .config }}")
+
+	// Math
+	_ = manager.Register("add", sprigAdd, "Adds the numbers. E.g: {{ add 1 2 3 }}")
+	_ = manager.Register("add1", func(v interface{}) int64 { return toInt64(v) + 1 }, "Increments a number by 1. E.g: {{ add1 .index }}")
+	_ = manager.Register("sub", func(a, b interface{}) int64 { return toInt64(a) - toInt64(b) }, "Subtracts the second number from the first. E.g: {{ sub 5 2 }}")
+	_ = manager.Register("mul", sprigMul, "Multiplies the numbers. E.g: {{ mul 2 3 }}")
+	_ = manager.Register("div", sprigDiv, "Performs the integer division of the first number by the second. E.g: {{ div 10 3 }}")
+	_ = manager.Register("mod", sprigMod, "Returns the remainder of the division of the first number by the second. E.g: {{ mod 10 3 }}")
+	_ = manager.Register("max", func(a interface{}, v ...interface{}) int64 { return sprigFold(v, toInt64(a), maxInt64) }, "Returns the largest of the numbers. E.g: {{ max 1 5 3 }}")
+	_ = manager.Register("min", func(a interface{}, v ...interface{}) int64 { return sprigFold(v, toInt64(a), minInt64) }, "Returns the smallest of the numbers. E.g: {{ min 1 5 3 }}")
+	_ = manager.Register("floor", func(v interface{}) float64 { return math.Floor(toFloat64(v)) }, "Returns the greatest integer value less than or equal to the number. E.g: {{ floor 1.5 }}")
+	_ = manager.Register("ceil", func(v interface{}) float64 { return math.Ceil(toFloat64(v)) }, "Returns the least integer value greater than or equal to the number. E.g: {{ ceil 1.5 }}")
+	_ = manager.Register("round", sprigRound, "Rounds a number to the given precision. E.g: {{ round 1.2345 2 }}")
+	_ = manager.Register("int", func(v interface{}) int { return int(toInt64(v)) }, "Converts a value to an int. E.g: {{ int \"42\" }}")
+	_ = manager.Register("int64", toInt64, "Converts a value to an int64. E.g: {{ int64 \"42\" }}")
+	_ = manager.Register("float64", toFloat64, "Converts a value to a float64. E.g: {{ float64 \"1.5\" }}")
+	_ = manager.Register("atoi", func(s string) int { i, _ := strconv.Atoi(s); return i }, "Converts a string to an int. E.g: {{ atoi \"42\" }}")
+}
+
+/** Lists */
+
+func sprigInitial(v interface{}) []interface{} {
+	list := toList(v, "initial")
+	if len(list) == 0 {
+		return nil
+	}
+	return append([]interface{}{}, list[:len(list)-1]...)
+}
+
+func sprigAppend(v interface{}, item interface{}) []interface{} {
+	return append(append([]interface{}{}, toList(v, "append")...), item)
+}
+
+func sprigPrepend(v interface{}, item interface{}) []interface{} {
+	return append([]interface{}{item}, toList(v, "prepend")...)
+}
+
+func sprigWithout(v interface{}, omit ...interface{}) []interface{} {
+	var ret []interface{}
+	for _, item := range toList(v, "without") {
+		if !sprigHas(item, omit) {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func sprigHas(needle interface{}, haystack interface{}) bool {
+	return listContains(toList(haystack, "has"), needle)
+}
+
+func sprigUntilStep(start, stop, step int) []int {
+	var ret []int
+	if step > 0 {
+		for i := start; i < stop; i += step {
+			ret = append(ret, i)
+		}
+	} else if step < 0 {
+		for i := start; i > stop; i += step {
+			ret = append(ret, i)
+		}
+	}
+	return ret
+}
+
+/** Dictionaries */
+
+func sprigDict(v ...interface{}) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for i := 0; i < len(v); i += 2 {
+		key := sprigString(v[i])
+		if i+1 >= len(v) {
+			ret[key] = ""
+			break
+		}
+		ret[key] = v[i+1]
+	}
+	return ret
+}
+
+func sprigGet(d interface{}, key string) interface{} {
+	if val, ok := toDict(d, "get")[key]; ok {
+		return val
+	}
+	return ""
+}
+
+func sprigSet(d interface{}, key string, value interface{}) interface{} {
+	if m, ok := d.(map[interface{}]interface{}); ok {
+		m[key] = value
+		return m
+	}
+	m := toDict(d, "set")
+	m[key] = value
+	return m
+}
+
+// sprigPluck returns the values of the key in the dictionaries as Sprig does. A single list is plucked as the common
+// helper does, so the templates using the common form keep working with the pack enabled, e.g: {{ .services | pluck "name" }}
+func sprigPluck(key string, dicts ...interface{}) []interface{} {
+	if len(dicts) == 1 && dicts[0] != nil && !isDict(dicts[0]) {
+		return listPluck(key, dicts[0])
+	}
+	var ret []interface{}
+	for _, d := range dicts {
+		if val, ok := toDict(d, "pluck")[key]; ok {
+			ret = append(ret, val)
+		}
+	}
+	return ret
+}
+
+/** Math */
+
+func toInt64(v interface{}) int64 {
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		return int64(val.Float())
+	case reflect.Bool:
+		if val.Bool() {
+			return 1
+		}
+	case reflect.String:
+		if i, err := strconv.ParseInt(val.String(), 0, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(val.String(), 64); err == nil {
+			return int64(f)
+		}
+	}
+	return 0
+}
+
+func toFloat64(v interface{}) float64 {
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		return val.Float()
+	case reflect.Bool:
+		if val.Bool() {
+			return 1
+		}
+	case reflect.String:
+		if f, err := strconv.ParseFloat(val.String(), 64); err == nil {
+			return f
+		}
+	}
+	return 0
+}
+
+func sprigFold(v []interface{}, initial int64, fn func(a, b int64) int64) int64 {
+	ret := initial
+	for _, n := range v {
+		ret = fn(ret, toInt64(n))
+	}
+	return ret
+}
+
+func sprigAdd(v ...interface{}) int64 {
+	return sprigFold(v, 0, func(a, b int64) int64 { return a + b })
+}
+
+func sprigMul(a interface{}, v ...interface{}) int64 {
+	return sprigFold(v, toInt64(a), func(a, b int64) int64 { return a * b })
+}
+
+func maxInt64(a, b int64) int64 {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+func minInt64(a, b int64) int64 {
+	if b < a {
+		return b
+	}
+	return a
+}
+
+func sprigDiv(a, b interface{}) (int64, error) {
+	if toInt64(b) == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return toInt64(a) / toInt64(b), nil
+}
+
+func sprigMod(a, b interface{}) (int64, error) {
+	if toInt64(b) == 0 {
+		return 0, fmt.Errorf("division by zero")
+	}
+	return toInt64(a) % toInt64(b), nil
+}
+
+func sprigRound(v interface{}, precision int) float64 {
+	pow := math.Pow(10, float64(precision))
+	return math.Round(toFloat64(v)*pow) / pow
+}
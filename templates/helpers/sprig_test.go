@@ -0,0 +1,221 @@
+package helpers
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+)
+
+// renderSprig renders the template with the common helpers and the helpers of the Sprig pack, as enabled with
+// '--helpers sprig'.
+func renderSprig(t *testing.T, tmpl string, data interface{}) (string, error) {
+	t.Helper()
+	manager := New()
+	RegisterCommon(manager)
+	funcs := template.FuncMap{}
+	for _, h := range manager.Get() {
+		funcs[h.Name] = h.Function
+	}
+	pack, err := PackHelpers(SprigPack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range pack {
+		funcs[h.Name] = h.Function
+	}
+
+	parsed, err := template.New("test").Funcs(funcs).Parse(tmpl)
+	if err != nil {
+		t.Fatalf("failed to parse '%s', %v", tmpl, err)
+	}
+	buf := &bytes.Buffer{}
+	err = parsed.Execute(buf, data)
+	return buf.String(), err
+}
+
+// TestSprigCompatibility checks that the Sprig pack renders the same results as the Sprig functions used by Helm. The
+// expected results are the ones documented by Sprig, or rendered with Helm.
+func TestSprigCompatibility(t *testing.T) {
+	data := map[string]interface{}{
+		"name":     "api",
+		"empty":    "",
+		"zero":     0,
+		"items":    []interface{}{1, 2, 3},
+		"labels":   map[string]interface{}{"app": "api", "tier": "backend"},
+		"services": []interface{}{map[string]interface{}{"name": "a"}, map[string]interface{}{"name": "b"}},
+		"nested":   map[string]interface{}{"user": map[string]interface{}{"role": "admin"}},
+	}
+
+	tests := []struct {
+		name     string
+		tmpl     string
+		expected string
+	}{
+		// Strings
+		{"trim", `{{ trim "  hello  " }}`, "hello"},
+		{"trimAll", `{{ trimAll "$" "$5.00" }}`, "5.00"},
+		{"trimPrefix", `{{ trimPrefix "-" "-hello" }}`, "hello"},
+		{"trimSuffix", `{{ trimSuffix "-" "hello-" }}`, "hello"},
+		{"upper", `{{ upper "hello" }}`, "HELLO"},
+		{"lower", `{{ lower "HELLO" }}`, "hello"},
+		{"title", `{{ title "hello world" }}`, "Hello World"},
+		{"title with separators", `{{ title "hello-world foo_bar" }}`, "Hello-World Foo_bar"},
+		{"untitle", `{{ untitle "Hello World" }}`, "hello world"},
+		{"repeat", `{{ repeat 3 "hello" }}`, "hellohellohello"},
+		{"substr", `{{ substr 0 5 "hello world" }}`, "hello"},
+		{"nospace", `{{ nospace "hello w o r l d" }}`, "helloworld"},
+		{"trunc", `{{ trunc 5 "hello world" }}`, "hello"},
+		{"trunc negative", `{{ trunc -5 "hello world" }}`, "world"},
+		{"abbrev", `{{ abbrev 5 "hello world" }}`, "he..."},
+		{"initials", `{{ initials "First Try" }}`, "FT"},
+		{"contains", `{{ contains "cat" "catch" }}`, "true"},
+		{"hasPrefix", `{{ hasPrefix "cat" "catch" }}`, "true"},
+		{"hasSuffix", `{{ hasSuffix "cat" "catch" }}`, "false"},
+		{"quote", `{{ .name | quote }}`, `"api"`},
+		{"squote", `{{ .name | squote }}`, `'api'`},
+		{"cat", `{{ cat "hello" "beautiful" "world" }}`, "hello beautiful world"},
+		{"indent", `{{ "a\nb" | indent 2 }}`, "  a\n  b"},
+		{"nindent", `{{ "a" | nindent 2 }}`, "\n  a"},
+		{"replace", `{{ "I Am Henry VIII" | replace " " "-" }}`, "I-Am-Henry-VIII"},
+		{"plural one", `{{ 1 | plural "one anchovy" "many anchovies" }}`, "one anchovy"},
+		{"plural many", `{{ 2 | plural "one anchovy" "many anchovies" }}`, "many anchovies"},
+		{"snakecase", `{{ snakecase "FirstName" }}`, "first_name"},
+		{"camelcase", `{{ camelcase "http_server" }}`, "HttpServer"},
+		{"kebabcase", `{{ kebabcase "FirstName" }}`, "first-name"},
+		{"toString", `{{ toString 42 | typeOf }}`, "string"},
+		{"join", `{{ list "a" "b" "c" | join "," }}`, "a,b,c"},
+		{"join numbers", `{{ .items | join "-" }}`, "1-2-3"},
+		{"split", `{{ $parts := split "$" "foo$bar$baz" }}{{ $parts._1 }}`, "bar"},
+		{"splitn", `{{ $parts := splitn "$" 2 "foo$bar$baz" }}{{ $parts._1 }}`, "bar$baz"},
+		{"splitList", `{{ splitList "," "a,b,c" }}`, "[a b c]"},
+
+		// Defaults and flow control
+		{"default empty", `{{ .empty | default "foo" }}`, "foo"},
+		{"default zero", `{{ .zero | default 5 }}`, "5"},
+		{"default set", `{{ .name | default "foo" }}`, "api"},
+		{"default missing", `{{ .missing | default "foo" }}`, "foo"},
+		{"empty", `{{ empty .zero }} {{ empty .name }}`, "true false"},
+		{"coalesce", `{{ coalesce .zero .empty "x" }}`, "x"},
+		{"all", `{{ all .name .zero }}`, "false"},
+		{"any", `{{ any .zero .name }}`, "true"},
+		{"ternary", `{{ true | ternary "on" "off" }} {{ false | ternary "on" "off" }}`, "on off"},
+
+		// Serialization and encoding
+		{"toJson", `{{ .labels | toJson }}`, `{"app":"api","tier":"backend"}`},
+		{"toPrettyJson", `{{ dict "a" 1 | toPrettyJson }}`, "{\n  \"a\": 1\n}"},
+		{"toRawJson", `{{ dict "a" "<b>" | toRawJson }}`, `{"a":"<b>"}`},
+		{"fromJson", `{{ (fromJson "{\"a\": 1}").a }}`, "1"},
+		{"toYaml", `{{ .labels | toYaml }}`, "app: api\ntier: backend"},
+		{"fromYaml", `{{ (fromYaml "a: b").a }}`, "b"},
+		{"b64enc", `{{ b64enc "hello" }}`, "aGVsbG8="},
+		{"b64dec", `{{ b64dec "aGVsbG8=" }}`, "hello"},
+		{"b32enc", `{{ b32enc "hello" }}`, "NBSWY3DP"},
+		{"b32dec", `{{ b32dec "NBSWY3DP" }}`, "hello"},
+		{"sha1sum", `{{ sha1sum "hello" }}`, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"},
+		{"sha256sum", `{{ sha256sum "hello" }}`, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+
+		// Types
+		{"typeOf", `{{ typeOf .name }}`, "string"},
+		{"typeIs", `{{ typeIs "string" .name }}`, "true"},
+		{"kindOf", `{{ kindOf .items }} {{ kindOf .labels }}`, "slice map"},
+		{"kindIs", `{{ kindIs "map" .labels }}`, "true"},
+		{"deepEqual", `{{ deepEqual (list 1 2) (list 1 2) }}`, "true"},
+
+		// Paths
+		{"base", `{{ base "foo/bar/baz" }}`, "baz"},
+		{"dir", `{{ dir "foo/bar/baz" }}`, "foo/bar"},
+		{"clean", `{{ clean "foo/bar/../baz" }}`, "foo/baz"},
+		{"ext", `{{ ext "foo.bar" }}`, ".bar"},
+		{"isAbs", `{{ isAbs "/foo" }}`, "true"},
+
+		// Lists
+		{"list", `{{ list 1 2 3 }}`, "[1 2 3]"},
+		{"first", `{{ first .items }}`, "1"},
+		{"last", `{{ last .items }}`, "3"},
+		{"rest", `{{ rest .items }}`, "[2 3]"},
+		{"initial", `{{ initial .items }}`, "[1 2]"},
+		{"append", `{{ append .items 4 }}`, "[1 2 3 4]"},
+		{"prepend", `{{ prepend .items 0 }}`, "[0 1 2 3]"},
+		{"concat", `{{ concat .items (list 4 5) }}`, "[1 2 3 4 5]"},
+		{"reverse", `{{ reverse .items }}`, "[3 2 1]"},
+		{"uniq", `{{ list 1 1 2 | uniq }}`, "[1 2]"},
+		{"without", `{{ without .items 2 }}`, "[1 3]"},
+		{"has", `{{ has 4 .items }} {{ .items | has 2 }}`, "false true"},
+		{"compact", `{{ list 1 "" 2 | compact }}`, "[1 2]"},
+		{"slice", `{{ slice (list 1 2 3 4) 1 3 }}`, "[2 3]"},
+		{"sortAlpha", `{{ list "c" "a" "b" | sortAlpha }}`, "[a b c]"},
+		{"until", `{{ until 3 }}`, "[0 1 2]"},
+		{"untilStep", `{{ untilStep 0 10 3 }}`, "[0 3 6 9]"},
+
+		// Dictionaries
+		{"dict", `{{ (dict "name" "api" "port" 80).port }}`, "80"},
+		{"get", `{{ get .labels "app" }}`, "api"},
+		{"get missing", `{{ get .labels "missing" }}`, ""},
+		{"set", `{{ $d := dict "a" 1 }}{{ $_ := set $d "b" 2 }}{{ $d.b }}`, "2"},
+		{"unset", `{{ $d := dict "a" 1 "b" 2 }}{{ $_ := unset $d "a" }}{{ keys $d }}`, "[b]"},
+		{"hasKey", `{{ hasKey .labels "app" }}`, "true"},
+		{"pluck", `{{ pluck "a" (dict "a" 1) (dict "a" 2) (dict "b" 3) }}`, "[1 2]"},
+		{"pluck list", `{{ list (dict "a" 1) (dict "a" 2) (dict "b" 3) | pluck "a" }}`, "[1 2]"},
+		{"keys", `{{ keys .labels | sortAlpha }}`, "[app tier]"},
+		{"values", `{{ values (dict "a" 1) }}`, "[1]"},
+		{"pick", `{{ pick .labels "app" }}`, "map[app:api]"},
+		{"omit", `{{ omit .labels "app" }}`, "map[tier:backend]"},
+		{"dig", `{{ dig "user" "role" "guest" .nested }} {{ dig "user" "name" "guest" .nested }}`, "admin guest"},
+		{"merge", `{{ merge (dict "a" 1) (dict "a" 2 "b" 2) }}`, "map[a:1 b:2]"},
+		{"mergeOverwrite", `{{ mergeOverwrite (dict "a" 1) (dict "a" 2 "b" 2) }}`, "map[a:2 b:2]"},
+		{"deepCopy", `{{ $c := deepCopy .labels }}{{ $_ := set $c "app" "web" }}{{ .labels.app }}`, "api"},
+
+		// Math
+		{"add", `{{ add 1 2 3 }}`, "6"},
+		{"add1", `{{ add1 1 }}`, "2"},
+		{"sub", `{{ sub 5 2 }}`, "3"},
+		{"mul", `{{ mul 2 3 4 }}`, "24"},
+		{"div", `{{ div 10 3 }}`, "3"},
+		{"mod", `{{ mod 10 3 }}`, "1"},
+		{"max", `{{ max 1 5 3 }}`, "5"},
+		{"min", `{{ min 4 1 3 }}`, "1"},
+		{"floor", `{{ floor 1.5 }}`, "1"},
+		{"ceil", `{{ ceil 1.5 }}`, "2"},
+		{"round", `{{ round 1.2345 2 }}`, "1.23"},
+		{"int", `{{ int "42" }}`, "42"},
+		{"int64", `{{ int64 "42" | typeOf }}`, "int64"},
+		{"float64", `{{ float64 "1.5" }}`, "1.5"},
+		{"atoi", `{{ atoi "42" }}`, "42"},
+
+		// Common helpers with the same names and arguments as Sprig
+		{"regexMatch", `{{ regexMatch "^[a-z]+$" "api" }}`, "true"},
+		{"regexFindAll", `{{ regexFindAll "[2,4,6,8]" "123456789" -1 }}`, "[2 4 6 8]"},
+		{"regexReplaceAll", `{{ regexReplaceAll "a(x*)b" "-ab-axxb-" "${1}W" }}`, "-W-xxW-"},
+		{"regexSplit", `{{ regexSplit "z+" "pizza" -1 }}`, "[pi a]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := renderSprig(t, tt.tmpl, data)
+			if err != nil {
+				t.Fatalf("failed to render '%s', %v", tt.tmpl, err)
+			}
+			if actual != tt.expected {
+				t.Errorf("'%s' rendered '%s', expected '%s'", tt.tmpl, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSprigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+	}{
+		{"required", `{{ required "the name is required" .missing }}`},
+		{"fail", `{{ fail "unsupported value" }}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual, err := renderSprig(t, tt.tmpl, map[string]interface{}{}); err == nil {
+				t.Errorf("expected '%s' to fail, rendered '%s'", tt.tmpl, actual)
+			}
+		})
+	}
+}
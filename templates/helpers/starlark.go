@@ -0,0 +1,157 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.starlark.net/starlark"
+)
+
+/** In this file are defined the script functions written in Starlark, a dialect of Python. */
+
+// StarlarkExt is the extension of the script files written in Starlark instead of YAML.
+const StarlarkExt = ".star"
+
+// loadStarlark loads the functions declared at the top level of a Starlark file, skipping the ones starting with '_'.
+// The parameters of the functions are their positional parameters, and their description is their docstring.
+func loadStarlark(file string, contents []byte) ([]*ScriptFunction, error) {
+	globals, err := starlark.ExecFile(&starlark.Thread{Name: file}, file, contents, nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load script file '%s', %v", file, err)
+	}
+
+	var ret []*ScriptFunction
+	for name, value := range globals {
+		fn, ok := value.(*starlark.Function)
+		if !ok || strings.HasPrefix(name, "_") {
+			continue
+		}
+		positional := fn.NumParams() - fn.NumKwonlyParams()
+		if fn.HasVarargs() {
+			positional--
+		}
+		if fn.HasKwargs() {
+			positional--
+		}
+		params := make([]string, positional)
+		for i := range params {
+			params[i], _ = fn.Param(i)
+		}
+		ret = append(ret, &ScriptFunction{
+			Name:        name,
+			Description: strings.TrimSpace(fn.Doc()),
+			Params:      params,
+			starlark:    fn,
+		})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret, nil
+}
+
+// starlarkFunc creates a function with one argument per parameter of the Starlark function, which converts the
+// arguments to Starlark values and the result back to Go values.
+func starlarkFunc(fn *ScriptFunction) interface{} {
+	return FixedArity(len(fn.Params), func(args ...interface{}) interface{} {
+		values := make(starlark.Tuple, len(args))
+		for i, arg := range args {
+			values[i] = toStarlark(arg)
+		}
+		// The globals of the file are frozen once loaded, so the function can be called concurrently from new threads
+		ret, err := starlark.Call(&starlark.Thread{Name: fn.Name}, fn.starlark, values, nil)
+		if err != nil {
+			panic(fmt.Errorf("function '%s' failed, %v", fn.Name, err))
+		}
+		return fromStarlark(ret)
+	})
+}
+
+// toStarlark converts a value of the template data to a Starlark value. Lists and maps are converted recursively, and
+// the values of other types are converted to their string representation.
+func toStarlark(v interface{}) starlark.Value {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return starlark.MakeInt64(i)
+		}
+		f, _ := n.Float64()
+		return starlark.Float(f)
+	}
+
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Invalid:
+		return starlark.None
+	case reflect.Bool:
+		return starlark.Bool(val.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return starlark.MakeInt64(val.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return starlark.MakeUint64(val.Uint())
+	case reflect.Float32, reflect.Float64:
+		return starlark.Float(val.Float())
+	case reflect.String:
+		return starlark.String(val.String())
+	case reflect.Slice, reflect.Array:
+		items := make([]starlark.Value, val.Len())
+		for i := range items {
+			items[i] = toStarlark(val.Index(i).Interface())
+		}
+		return starlark.NewList(items)
+	case reflect.Map:
+		dict := starlark.NewDict(val.Len())
+		for _, k := range val.MapKeys() {
+			_ = dict.SetKey(toStarlark(k.Interface()), toStarlark(val.MapIndex(k).Interface()))
+		}
+		return dict
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			return starlark.None
+		}
+		return toStarlark(val.Elem().Interface())
+	}
+	return starlark.String(fmt.Sprint(v))
+}
+
+// fromStarlark converts a Starlark value to the Go value used by the templates. Integers are converted to int if they
+// fit, lists, tuples and sets to []interface{}, and dicts to map[string]interface{} with the keys as strings.
+func fromStarlark(v starlark.Value) interface{} {
+	switch x := v.(type) {
+	case starlark.NoneType:
+		return nil
+	case starlark.Bool:
+		return bool(x)
+	case starlark.Int:
+		if i, ok := x.Int64(); ok && int64(int(i)) == i {
+			return int(i)
+		}
+		return new(big.Int).Set(x.BigInt())
+	case starlark.Float:
+		return float64(x)
+	case starlark.String:
+		return string(x)
+	case *starlark.Dict:
+		ret := map[string]interface{}{}
+		for _, item := range x.Items() {
+			key := item[0]
+			if s, ok := key.(starlark.String); ok {
+				ret[string(s)] = fromStarlark(item[1])
+			} else {
+				ret[key.String()] = fromStarlark(item[1])
+			}
+		}
+		return ret
+	case starlark.Iterable:
+		ret := []interface{}{}
+		iter := x.Iterate()
+		defer iter.Done()
+		var item starlark.Value
+		for iter.Next(&item) {
+			ret = append(ret, fromStarlark(item))
+		}
+		return ret
+	}
+	return v.String()
+}
@@ -0,0 +1,230 @@
+package helpers
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/** In this file are defined the date and time helpers of the common set. */
+
+var (
+	fixedNow   time.Time
+	clockMutex = sync.RWMutex{}
+
+	// layouts are the names of the layouts accepted by the date helpers besides Go layouts
+	layouts = map[string]string{
+		"ANSIC":       time.ANSIC,
+		"UnixDate":    time.UnixDate,
+		"RubyDate":    time.RubyDate,
+		"RFC822":      time.RFC822,
+		"RFC822Z":     time.RFC822Z,
+		"RFC850":      time.RFC850,
+		"RFC1123":     time.RFC1123,
+		"RFC1123Z":    time.RFC1123Z,
+		"RFC3339":     time.RFC3339,
+		"RFC3339Nano": time.RFC3339Nano,
+		"ISO8601":     iso8601,
+		"Kitchen":     time.Kitchen,
+		"DateTime":    "2006-01-02 15:04:05",
+		"DateOnly":    "2006-01-02",
+		"TimeOnly":    "15:04:05",
+	}
+
+	// parseLayouts are the layouts tried in order when a string is converted to a time
+	parseLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+		time.RFC1123Z,
+		time.RFC1123,
+	}
+)
+
+// iso8601 is the ISO-8601 layout with milliseconds, as produced by JavaScript's Date.toISOString
+const iso8601 = "2006-01-02T15:04:05.000Z07:00"
+
+// Now returns the current time used by the date helpers, which is the time set with SetNow if any.
+func Now() time.Time {
+	clockMutex.RLock()
+	defer clockMutex.RUnlock()
+	if fixedNow.IsZero() {
+		return time.Now().Round(0)
+	}
+	return fixedNow
+}
+
+// SetNow fixes the time returned by 'now' and used by the date helpers, so the rendered output is reproducible. The
+// zero time restores the system clock.
+func SetNow(t time.Time) {
+	clockMutex.Lock()
+	defer clockMutex.Unlock()
+	fixedNow = t
+}
+
+// ParseTime converts a value to a time. Accepts times, Unix timestamps in seconds, and strings in RFC3339, ISO-8601
+// or 'YYYY-MM-DD' formats, or with the digits of a Unix timestamp.
+func ParseTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+	case string:
+		str := strings.TrimSpace(t)
+		for _, layout := range parseLayouts {
+			if ret, err := time.Parse(layout, str); err == nil {
+				return ret, nil
+			}
+		}
+		if seconds, ok := ratOf(str); ok {
+			return unixTime(seconds), nil
+		}
+	default:
+		if val := reflect.ValueOf(v); val.Kind() == reflect.String {
+			return ParseTime(val.String())
+		}
+		if seconds, ok := ratOf(v); ok {
+			return unixTime(seconds), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to convert '%v' to a time", v)
+}
+
+// registerTime registers the date and time helpers. The times can be provided as times, Unix timestamps or strings in
+// RFC3339 or ISO-8601 formats, and the formats can be Go layouts, e.g. '2006-01-02', or the name of a layout, e.g.
+// 'RFC3339'.
+func registerTime(manager IHelpersManager) {
+	_ = manager.Register("now", Now, "Returns the current time, or the time fixed with --now. E.g: {{ now }}")
+	_ = manager.Register("date", date, "Formats a time with a Go layout or the name of a layout such as 'RFC3339' or 'ISO8601'. E.g: {{ date \"2006-01-02\" now }}")
+	_ = manager.Register("dateInZone", dateInZone, "Formats a time in the given IANA time zone. E.g: {{ dateInZone \"15:04 MST\" now \"Europe/Madrid\" }}")
+	_ = manager.Register("dateParse", dateParse, "Parses a string with a Go layout or the name of a layout. E.g: {{ dateParse \"02/01/2006\" \"31/12/2024\" }}")
+	_ = manager.Register("dateModify", dateModify, "Adds a duration to a time, the duration can start with a number of days with the 'd' unit. E.g: {{ dateModify \"-1d12h\" now }}")
+	_ = manager.Register("dateUnix", dateUnix, "Returns the Unix timestamp of a time, in seconds. E.g: {{ dateUnix now }}")
+	_ = manager.Register("dateFromUnix", dateFromUnix, "Returns the time of a Unix timestamp in seconds, in UTC. E.g: {{ dateFromUnix 1704067200 }}")
+	_ = manager.Register("dateRFC3339", dateRFC3339, "Formats a time as RFC3339. E.g: {{ dateRFC3339 now }}")
+	_ = manager.Register("dateISO8601", dateISO8601, "Formats a time as ISO-8601 in UTC with milliseconds. E.g: {{ dateISO8601 now }}")
+	_ = manager.Register("duration", duration, "Parses a duration such as '1h30m' or '2d', or converts a number of seconds to a duration. E.g: {{ duration 5400 }}")
+	_ = manager.Register("durationSeconds", durationSeconds, "Returns the number of seconds of a duration. E.g: {{ durationSeconds \"1h30m\" }}")
+}
+
+func date(format string, t interface{}) string {
+	return toTime(t).Format(layout(format))
+}
+
+func dateInZone(format string, t interface{}, zone string) string {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		panic(fmt.Errorf("unknown time zone '%s'", zone))
+	}
+	return toTime(t).In(loc).Format(layout(format))
+}
+
+func dateParse(format, value string) time.Time {
+	ret, err := time.Parse(layout(format), value)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+func dateModify(modifier string, t interface{}) time.Time {
+	return toTime(t).Add(parseDuration(modifier))
+}
+
+func dateUnix(t interface{}) int64 {
+	return toTime(t).Unix()
+}
+
+func dateFromUnix(seconds interface{}) time.Time {
+	return unixTime(toRat(seconds))
+}
+
+func dateRFC3339(t interface{}) string {
+	return toTime(t).Format(time.RFC3339)
+}
+
+func dateISO8601(t interface{}) string {
+	return toTime(t).UTC().Format(iso8601)
+}
+
+func duration(v interface{}) time.Duration {
+	switch d := v.(type) {
+	case time.Duration:
+		return d
+	case string:
+		if _, ok := ratOf(d); !ok {
+			return parseDuration(d)
+		}
+	}
+	return time.Duration(nanoseconds(toRat(v)))
+}
+
+func durationSeconds(v interface{}) interface{} {
+	return fromRat(big.NewRat(int64(duration(v)), int64(time.Second)))
+}
+
+// daysRegex matches a duration starting with a number of days, capturing the sign, the days and the rest of the duration.
+var daysRegex = regexp.MustCompile(`^([-+]?)(\d+(?:\.\d+)?)d(.*)$`)
+
+// parseDuration parses a Go duration, which can also start with a number of days with the 'd' unit, e.g: '-2d' or
+// '1d12h'.
+func parseDuration(str string) time.Duration {
+	str = strings.TrimSpace(str)
+	if ret, err := time.ParseDuration(str); err == nil {
+		return ret
+	}
+	match := daysRegex.FindStringSubmatch(str)
+	if match == nil {
+		panic(fmt.Errorf("invalid duration '%s'", str))
+	}
+	days, _ := strconv.ParseFloat(match[2], 64)
+	ret := time.Duration(days * float64(24*time.Hour))
+	if rest := match[3]; rest != "" {
+		// The sign applies to the whole duration, e.g: '-1d12h'
+		d, err := time.ParseDuration(rest)
+		if err != nil || strings.ContainsAny(rest[:1], "+-") {
+			panic(fmt.Errorf("invalid duration '%s'", str))
+		}
+		ret += d
+	}
+	if match[1] == "-" {
+		ret = -ret
+	}
+	return ret
+}
+
+// unixTime returns the time of the given Unix timestamp in seconds, in UTC.
+func unixTime(seconds *big.Rat) time.Time {
+	return time.Unix(0, nanoseconds(seconds)).UTC()
+}
+
+// nanoseconds returns the nanoseconds of the given number of seconds.
+func nanoseconds(seconds *big.Rat) int64 {
+	return ratFloor(new(big.Rat).Mul(seconds, big.NewRat(int64(time.Second), 1))).Num().Int64()
+}
+
+func toTime(v interface{}) time.Time {
+	ret, err := ParseTime(v)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+func layout(format string) string {
+	if ret, ok := layouts[format]; ok {
+		return ret
+	}
+	return format
+}
@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected time.Duration
+	}{
+		{"90m", 90 * time.Minute},
+		{"-1h30m", -90 * time.Minute},
+		{"2d", 48 * time.Hour},
+		{"-2d", -48 * time.Hour},
+		{"1.5d", 36 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"2d30m", 48*time.Hour + 30*time.Minute},
+		{"1d2h3m4s", 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{"-1d12h", -36 * time.Hour},
+		{"+1d1h", 25 * time.Hour},
+		{" 1d12h ", 36 * time.Hour},
+	}
+	for _, tt := range tests {
+		if actual := parseDuration(tt.str); actual != tt.expected {
+			t.Errorf("parseDuration '%s' returned %v, expected %v", tt.str, actual, tt.expected)
+		}
+	}
+
+	for _, str := range []string{"", "d", "1dd", "1d2", "1d-2h", "h1d", "1h1d", "abc"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected parseDuration '%s' to fail", str)
+				}
+			}()
+			parseDuration(str)
+		}()
+	}
+}
+
+func TestDateModify(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		modifier string
+		expected time.Time
+	}{
+		{"-24h", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"1d12h", time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)},
+		{"-2d30m", time.Date(2023, 12, 29, 23, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if actual := dateModify(tt.modifier, start); !actual.Equal(tt.expected) {
+			t.Errorf("dateModify '%s' returned %v, expected %v", tt.modifier, actual, tt.expected)
+		}
+	}
+}
@@ -5,7 +5,8 @@ type IHelpersManager interface {
 	// Get returns the helpers that have been registered to the manager
 	Get() []*Helper
 
-	// Register registers a helper function to be used with Go templates.
+	// Register registers a helper function to be used with Go templates. The first optional string is the description
+	// of the helper, the following ones are usage examples.
 	Register(name string, fn interface{}, description ...string) error
 
 	// Contains indicates whether a helper is contained by the manager
@@ -21,4 +22,5 @@ type Helper struct {
 	Category    string
 	Description string
 	Function    interface{}
+	Examples    []string
 }
@@ -0,0 +1,167 @@
+package jinja
+
+/** Expressions */
+
+type expr interface{}
+
+type literalExpr struct {
+	value interface{}
+}
+
+type nameExpr struct {
+	name string
+}
+
+type attrExpr struct {
+	target expr
+	attr   string
+}
+
+type itemExpr struct {
+	target expr
+	index  expr
+}
+
+type sliceExpr struct {
+	target expr
+	start  expr
+	stop   expr
+	step   expr
+}
+
+type kwarg struct {
+	name  string
+	value expr
+}
+
+type callExpr struct {
+	fn     expr
+	args   []expr
+	kwargs []kwarg
+}
+
+type filterExpr struct {
+	target expr
+	name   string
+	args   []expr
+	kwargs []kwarg
+}
+
+type testExpr struct {
+	target expr
+	name   string
+	args   []expr
+	negate bool
+}
+
+type unaryExpr struct {
+	op      string
+	operand expr
+}
+
+type binaryExpr struct {
+	op    string
+	left  expr
+	right expr
+}
+
+type condExpr struct {
+	cond      expr
+	then      expr
+	otherwise expr
+}
+
+type listExpr struct {
+	items []expr
+}
+
+type dictExpr struct {
+	keys   []expr
+	values []expr
+}
+
+/** Statements */
+
+type node interface{}
+
+type textNode struct {
+	text string
+}
+
+type outputNode struct {
+	expr expr
+	name string
+	line int
+}
+
+type ifNode struct {
+	conds    []expr
+	bodies   [][]node
+	elseBody []node
+}
+
+type forNode struct {
+	targets  []string
+	iter     expr
+	cond     expr
+	body     []node
+	elseBody []node
+}
+
+type setNode struct {
+	targets []expr
+	value   expr
+	body    []node
+}
+
+type blockNode struct {
+	name string
+	body []node
+}
+
+type extendsNode struct {
+	parent expr
+}
+
+type includeNode struct {
+	name          expr
+	ignoreMissing bool
+	withContext   bool
+}
+
+type macroNode struct {
+	name     string
+	params   []string
+	defaults map[string]expr
+	body     []node
+}
+
+type importNode struct {
+	name  expr
+	alias string
+}
+
+type fromImportNode struct {
+	name    expr
+	names   []string
+	aliases []string
+}
+
+type withNode struct {
+	names  []string
+	values []expr
+	body   []node
+}
+
+type filterBlockNode struct {
+	filters []*filterExpr
+	body    []node
+}
+
+// program is the result of compiling a template
+type program struct {
+	name    string
+	nodes   []node
+	blocks  map[string]*blockNode
+	extends *extendsNode
+}
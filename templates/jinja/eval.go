@@ -0,0 +1,996 @@
+package jinja
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/jucardi/infuse/templates/helpers"
+)
+
+const (
+	maxDepth = 64
+
+	// maxCallDepth is the maximum number of nested macro calls and includes, so a recursive macro fails instead of
+	// overflowing the stack.
+	maxCallDepth = 256
+)
+
+var errRecursion = errors.New("maximum recursion depth exceeded, possible recursive macro or include")
+
+// renderer executes compiled programs. A new renderer is created for every render, and for every included or
+// imported template.
+type renderer struct {
+	tmpl     *Template
+	data     interface{}
+	programs map[string]*program
+	helpers  map[string]interface{}
+	scopes   []map[string]interface{}
+	blocks   map[string][]*blockNode
+	lenient  int
+	depth    int
+
+	// calls is the depth of the nested calls, shared with the renderers of the included and imported templates since
+	// their macros can call each other.
+	calls *int
+}
+
+func newRenderer(tmpl *Template, data interface{}) *renderer {
+	funcs := map[string]interface{}{}
+	for _, h := range Helpers().Get() {
+		funcs[h.Name] = h.Function
+	}
+	for _, h := range tmpl.PackHelpers() {
+		funcs[h.Name] = h.Function
+	}
+	plugins := helpers.NewPluginCache()
+	for _, name := range helpers.PluginNames() {
+		funcs[name] = pluginCallable(plugins, name)
+	}
+	return &renderer{
+		tmpl:     tmpl,
+		data:     data,
+		programs: map[string]*program{},
+		helpers:  funcs,
+		scopes:   []map[string]interface{}{{}},
+		blocks:   map[string][]*blockNode{},
+		calls:    new(int),
+	}
+}
+
+// child creates a renderer for an included or imported template, sharing the compiled programs and the helpers. If
+// 'withContext' is set, the variables visible in the current scope are visible to the child.
+func (r *renderer) child(withContext bool) (*renderer, error) {
+	if r.depth >= maxDepth {
+		return nil, fmt.Errorf("maximum template depth exceeded, possible recursive include or import")
+	}
+	scope := map[string]interface{}{}
+	if withContext {
+		for _, s := range r.scopes {
+			for k, v := range s {
+				scope[k] = v
+			}
+		}
+	}
+	return &renderer{
+		tmpl:     r.tmpl,
+		data:     r.data,
+		programs: r.programs,
+		helpers:  r.helpers,
+		scopes:   []map[string]interface{}{scope},
+		blocks:   map[string][]*blockNode{},
+		depth:    r.depth + 1,
+		calls:    r.calls,
+	}, nil
+}
+
+// load returns the compiled program of the definition by the given name
+func (r *renderer) load(name string) (*program, error) {
+	if prog, ok := r.programs[name]; ok {
+		return prog, nil
+	}
+	src, ok := r.tmpl.Definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("template '%s' not found", name)
+	}
+	prog, err := compile(name, src)
+	if err != nil {
+		return nil, err
+	}
+	r.programs[name] = prog
+	return prog, nil
+}
+
+// enter increments the depth of the nested calls, failing if the maximum depth is exceeded. Each successful call must
+// be followed by a call to leave.
+func (r *renderer) enter() error {
+	if *r.calls >= maxCallDepth {
+		return errRecursion
+	}
+	*r.calls++
+	return nil
+}
+
+func (r *renderer) leave() {
+	*r.calls--
+}
+
+func (r *renderer) push(scope map[string]interface{}) {
+	r.scopes = append(r.scopes, scope)
+}
+
+func (r *renderer) pop() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *renderer) set(name string, value interface{}) {
+	r.scopes[len(r.scopes)-1][name] = value
+}
+
+// lookup resolves a name from the local scopes, the data, the global functions and the registered helpers, in that
+// order.
+func (r *renderer) lookup(name string) (interface{}, error) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if v, ok := r.scopes[i][name]; ok {
+			return v, nil
+		}
+	}
+	if v := getItem(r.data, name); !isUndefined(v) {
+		return v, nil
+	}
+	if fn, ok := globals[name]; ok {
+		return fn, nil
+	}
+	if fn, ok := r.helpers[name]; ok {
+		return fn, nil
+	}
+	return r.undefined(name)
+}
+
+// undefined returns an undefined value, or an error if the template is strict and the value is not being tested.
+func (r *renderer) undefined(name string) (interface{}, error) {
+	if r.tmpl.Strict && r.lenient == 0 {
+		return nil, fmt.Errorf("'%s' is undefined", name)
+	}
+	return &undefined{name: name}, nil
+}
+
+// run executes the program, resolving the template inheritance chain if the program extends another template.
+func (r *renderer) run(prog *program, out *strings.Builder) error {
+	for name, b := range prog.blocks {
+		r.blocks[name] = append(r.blocks[name], b)
+	}
+	if prog.extends == nil {
+		return r.execute(prog.nodes, out)
+	}
+	if r.depth >= maxDepth {
+		return fmt.Errorf("maximum template depth exceeded, possible recursive extends")
+	}
+
+	// Only the statements with side effects are executed on the child templates, the output comes from the parent.
+	discard := &strings.Builder{}
+	for _, n := range prog.nodes {
+		switch n.(type) {
+		case *setNode, *macroNode, *importNode, *fromImportNode:
+			if err := r.executeNode(n, discard); err != nil {
+				return err
+			}
+		}
+	}
+
+	name, err := r.eval(prog.extends.parent)
+	if err != nil {
+		return fmt.Errorf("%s: %v", prog.name, err)
+	}
+	parent, err := r.load(toString(name))
+	if err != nil {
+		return fmt.Errorf("%s: %v", prog.name, err)
+	}
+	r.depth++
+	defer func() { r.depth-- }()
+	return r.run(parent, out)
+}
+
+func (r *renderer) execute(nodes []node, out *strings.Builder) error {
+	for _, n := range nodes {
+		if err := r.executeNode(n, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *renderer) executeNode(n node, out *strings.Builder) error {
+	switch n := n.(type) {
+	case *textNode:
+		out.WriteString(n.text)
+	case *outputNode:
+		v, err := r.eval(n.expr)
+		if err == errRecursion && *r.calls > 0 {
+			// Only the outermost output reports the position, instead of one per nested call
+			return err
+		} else if err != nil {
+			return fmt.Errorf("%s:%d: %v", n.name, n.line, err)
+		}
+		out.WriteString(toString(v))
+	case *ifNode:
+		for i, cond := range n.conds {
+			v, err := r.eval(cond)
+			if err != nil {
+				return err
+			}
+			if truthy(v) {
+				return r.execute(n.bodies[i], out)
+			}
+		}
+		return r.execute(n.elseBody, out)
+	case *forNode:
+		return r.executeFor(n, out)
+	case *setNode:
+		return r.executeSet(n)
+	case *blockNode:
+		return r.executeBlock(n.name, 0, out)
+	case *extendsNode:
+		return fmt.Errorf("'extends' must be a top level statement")
+	case *includeNode:
+		return r.executeInclude(n, out)
+	case *macroNode:
+		r.set(n.name, r.macro(n))
+	case *importNode:
+		name, err := r.eval(n.name)
+		if err != nil {
+			return err
+		}
+		module, err := r.importModule(toString(name))
+		if err != nil {
+			return err
+		}
+		r.set(n.alias, module)
+	case *fromImportNode:
+		name, err := r.eval(n.name)
+		if err != nil {
+			return err
+		}
+		module, err := r.importModule(toString(name))
+		if err != nil {
+			return err
+		}
+		for i, imported := range n.names {
+			v, ok := module[imported]
+			if !ok {
+				return fmt.Errorf("'%s' has no exported member '%s'", toString(name), imported)
+			}
+			r.set(n.aliases[i], v)
+		}
+	case *withNode:
+		scope := map[string]interface{}{}
+		for i, name := range n.names {
+			v, err := r.eval(n.values[i])
+			if err != nil {
+				return err
+			}
+			scope[name] = v
+		}
+		r.push(scope)
+		defer r.pop()
+		return r.execute(n.body, out)
+	case *filterBlockNode:
+		body := &strings.Builder{}
+		if err := r.execute(n.body, body); err != nil {
+			return err
+		}
+		var v interface{} = body.String()
+		for _, f := range n.filters {
+			var err error
+			if v, err = r.applyFilter(f, v); err != nil {
+				return err
+			}
+		}
+		out.WriteString(toString(v))
+	default:
+		return fmt.Errorf("unknown node %T", n)
+	}
+	return nil
+}
+
+func (r *renderer) executeFor(n *forNode, out *strings.Builder) error {
+	iter, err := r.eval(n.iter)
+	if err != nil {
+		return err
+	}
+	items, err := toList(iter)
+	if err != nil {
+		return err
+	}
+
+	scope := map[string]interface{}{}
+	r.push(scope)
+	defer r.pop()
+
+	bind := func(item interface{}) error {
+		if len(n.targets) == 1 {
+			scope[n.targets[0]] = item
+			return nil
+		}
+		values, err := toList(item)
+		if err != nil || len(values) != len(n.targets) {
+			return fmt.Errorf("cannot unpack %s into %d values", repr(item), len(n.targets))
+		}
+		for i, target := range n.targets {
+			scope[target] = values[i]
+		}
+		return nil
+	}
+
+	if n.cond != nil {
+		var filtered []interface{}
+		for _, item := range items {
+			if err := bind(item); err != nil {
+				return err
+			}
+			v, err := r.eval(n.cond)
+			if err != nil {
+				return err
+			}
+			if truthy(v) {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+	}
+
+	if len(items) == 0 {
+		return r.execute(n.elseBody, out)
+	}
+
+	for i, item := range items {
+		if err := bind(item); err != nil {
+			return err
+		}
+		loop := map[string]interface{}{
+			"index":     i + 1,
+			"index0":    i,
+			"revindex":  len(items) - i,
+			"revindex0": len(items) - i - 1,
+			"first":     i == 0,
+			"last":      i == len(items)-1,
+			"length":    len(items),
+			"cycle":     cycleFn(i),
+		}
+		if i > 0 {
+			loop["previtem"] = items[i-1]
+		}
+		if i < len(items)-1 {
+			loop["nextitem"] = items[i+1]
+		}
+		scope["loop"] = loop
+		if err := r.execute(n.body, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cycleFn(index int) callable {
+	return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("no items for cycling given")
+		}
+		return args[index%len(args)], nil
+	}
+}
+
+func (r *renderer) executeSet(n *setNode) error {
+	var value interface{}
+	if n.body != nil {
+		body := &strings.Builder{}
+		if err := r.execute(n.body, body); err != nil {
+			return err
+		}
+		value = body.String()
+	} else {
+		v, err := r.eval(n.value)
+		if err != nil {
+			return err
+		}
+		value = v
+	}
+
+	values := []interface{}{value}
+	if len(n.targets) > 1 {
+		list, err := toList(value)
+		if err != nil || len(list) != len(n.targets) {
+			return fmt.Errorf("cannot unpack %s into %d values", repr(value), len(n.targets))
+		}
+		values = list
+	}
+
+	for i, target := range n.targets {
+		switch t := target.(type) {
+		case *nameExpr:
+			r.set(t.name, values[i])
+		case *attrExpr:
+			obj, err := r.eval(t.target)
+			if err != nil {
+				return err
+			}
+			ns, ok := obj.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("cannot assign attribute '%s' on a non-namespace object", t.attr)
+			}
+			ns[t.attr] = values[i]
+		default:
+			return fmt.Errorf("cannot assign to %T", target)
+		}
+	}
+	return nil
+}
+
+// executeBlock renders the block by the given name, 'level' is the position in the inheritance chain, where 0 is the
+// most derived template. The 'super()' function renders the next level.
+func (r *renderer) executeBlock(name string, level int, out *strings.Builder) error {
+	stack := r.blocks[name]
+	if level >= len(stack) {
+		return nil
+	}
+	r.push(map[string]interface{}{
+		"super": callable(func([]interface{}, map[string]interface{}) (interface{}, error) {
+			if level+1 >= len(stack) {
+				return nil, fmt.Errorf("no parent block for '%s'", name)
+			}
+			ret := &strings.Builder{}
+			err := r.executeBlock(name, level+1, ret)
+			return markup(ret.String()), err
+		}),
+	})
+	defer r.pop()
+	return r.execute(stack[level].body, out)
+}
+
+func (r *renderer) executeInclude(n *includeNode, out *strings.Builder) error {
+	v, err := r.eval(n.name)
+	if err != nil {
+		return err
+	}
+	names := []interface{}{v}
+	if _, isStr := asString(v); !isStr {
+		if names, err = toList(v); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := r.tmpl.Definitions[toString(name)]; !ok {
+			continue
+		}
+		prog, err := r.load(toString(name))
+		if err != nil {
+			return err
+		}
+		sub, err := r.child(n.withContext)
+		if err != nil {
+			return err
+		}
+		if err := r.enter(); err != nil {
+			return err
+		}
+		defer r.leave()
+		return sub.run(prog, out)
+	}
+	if n.ignoreMissing {
+		return nil
+	}
+	return fmt.Errorf("template '%s' not found", toString(v))
+}
+
+// importModule renders the given template and returns its top level variables and macros.
+func (r *renderer) importModule(name string) (map[string]interface{}, error) {
+	prog, err := r.load(name)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := r.child(false)
+	if err != nil {
+		return nil, err
+	}
+	if err := sub.run(prog, &strings.Builder{}); err != nil {
+		return nil, err
+	}
+	return sub.scopes[0], nil
+}
+
+// macro creates the callable for a macro definition. Macros see the top level scope where they were defined, plus
+// their arguments.
+func (r *renderer) macro(n *macroNode) callable {
+	global := r.scopes[0]
+	return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		if len(args) > len(n.params) {
+			return nil, fmt.Errorf("macro '%s' takes no more than %d argument(s)", n.name, len(n.params))
+		}
+		scope := map[string]interface{}{}
+		saved := r.scopes
+		r.scopes = []map[string]interface{}{global, scope}
+		defer func() { r.scopes = saved }()
+
+		for k := range kwargs {
+			if ok, _ := contains(n.params, k); !ok {
+				return nil, fmt.Errorf("macro '%s' has no argument '%s'", n.name, k)
+			}
+		}
+		for i, param := range n.params {
+			if i < len(args) {
+				scope[param] = args[i]
+			} else if v, ok := kwargs[param]; ok {
+				scope[param] = v
+			} else if def, ok := n.defaults[param]; ok {
+				v, err := r.eval(def)
+				if err != nil {
+					return nil, err
+				}
+				scope[param] = v
+			} else {
+				scope[param] = &undefined{name: param}
+			}
+		}
+
+		out := &strings.Builder{}
+		if err := r.execute(n.body, out); err == errRecursion {
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("macro '%s': %v", n.name, err)
+		}
+		return markup(out.String()), nil
+	}
+}
+
+/** Expressions */
+
+func (r *renderer) eval(e expr) (interface{}, error) {
+	switch e := e.(type) {
+	case *literalExpr:
+		return e.value, nil
+	case *nameExpr:
+		return r.lookup(e.name)
+	case *attrExpr:
+		obj, err := r.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		if v := getAttr(obj, e.attr); !isUndefined(v) {
+			return v, nil
+		}
+		return r.undefined(e.attr)
+	case *itemExpr:
+		obj, err := r.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		key, err := r.eval(e.index)
+		if err != nil {
+			return nil, err
+		}
+		if v := getAttr(obj, key); !isUndefined(v) {
+			return v, nil
+		}
+		return r.undefined(toString(key))
+	case *sliceExpr:
+		return r.evalSlice(e)
+	case *callExpr:
+		return r.evalCall(e)
+	case *filterExpr:
+		if e.name == "default" || e.name == "d" {
+			r.lenient++
+			defer func() { r.lenient-- }()
+		}
+		target, err := r.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		return r.applyFilter(e, target)
+	case *testExpr:
+		switch e.name {
+		case "defined", "undefined", "none":
+			r.lenient++
+			defer func() { r.lenient-- }()
+		}
+		target, err := r.eval(e.target)
+		if err != nil {
+			return nil, err
+		}
+		args, err := r.evalList(e.args)
+		if err != nil {
+			return nil, err
+		}
+		result, err := r.applyTest(e.name, target, args)
+		if err != nil {
+			return nil, err
+		}
+		return result != e.negate, nil
+	case *unaryExpr:
+		operand, err := r.eval(e.operand)
+		if err != nil {
+			return nil, err
+		}
+		switch e.op {
+		case "not":
+			return !truthy(operand), nil
+		case "-":
+			return arithmetic("-", 0, operand)
+		default:
+			return arithmetic("+", 0, operand)
+		}
+	case *binaryExpr:
+		return r.evalBinary(e)
+	case *condExpr:
+		cond, err := r.eval(e.cond)
+		if err != nil {
+			return nil, err
+		}
+		if truthy(cond) {
+			return r.eval(e.then)
+		}
+		if e.otherwise == nil {
+			return &undefined{}, nil
+		}
+		return r.eval(e.otherwise)
+	case *listExpr:
+		return r.evalList(e.items)
+	case *dictExpr:
+		ret := map[string]interface{}{}
+		for i := range e.keys {
+			k, err := r.eval(e.keys[i])
+			if err != nil {
+				return nil, err
+			}
+			v, err := r.eval(e.values[i])
+			if err != nil {
+				return nil, err
+			}
+			ret[toString(k)] = v
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("unknown expression %T", e)
+}
+
+func (r *renderer) evalList(exprs []expr) ([]interface{}, error) {
+	ret := make([]interface{}, len(exprs))
+	for i, e := range exprs {
+		v, err := r.eval(e)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = v
+	}
+	return ret, nil
+}
+
+func (r *renderer) evalKwargs(kwargs []kwarg) (map[string]interface{}, error) {
+	ret := map[string]interface{}{}
+	for _, kw := range kwargs {
+		v, err := r.eval(kw.value)
+		if err != nil {
+			return nil, err
+		}
+		ret[kw.name] = v
+	}
+	return ret, nil
+}
+
+func (r *renderer) evalCall(e *callExpr) (interface{}, error) {
+	fn, err := r.eval(e.fn)
+	if err != nil {
+		return nil, err
+	}
+	args, err := r.evalList(e.args)
+	if err != nil {
+		return nil, err
+	}
+	kwargs, err := r.evalKwargs(e.kwargs)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+	return call(fn, args, kwargs)
+}
+
+// call invokes a callable defined by the engine, or a Go function such as a registered helper
+func call(fn interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	switch f := fn.(type) {
+	case callable:
+		return f(args, kwargs)
+	case *undefined:
+		return nil, fmt.Errorf("'%s' is undefined", f.name)
+	}
+	if reflect.ValueOf(fn).Kind() != reflect.Func {
+		return nil, fmt.Errorf("'%s' is not callable", toString(fn))
+	}
+	if len(kwargs) > 0 {
+		return nil, fmt.Errorf("keyword arguments are not supported by helper functions")
+	}
+	return helpers.Call(fn, args...)
+}
+
+func (r *renderer) evalSlice(e *sliceExpr) (interface{}, error) {
+	obj, err := r.eval(e.target)
+	if err != nil {
+		return nil, err
+	}
+	bounds := make([]interface{}, 3)
+	for i, b := range []expr{e.start, e.stop, e.step} {
+		if b == nil {
+			continue
+		}
+		if bounds[i], err = r.eval(b); err != nil {
+			return nil, err
+		}
+	}
+
+	_, isStr := asString(obj)
+	items, err := toList(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	step := 1
+	if bounds[2] != nil {
+		if step, _ = toInt(bounds[2]); step == 0 {
+			return nil, fmt.Errorf("slice step cannot be zero")
+		}
+	}
+	length := len(items)
+	index := func(v interface{}, def int) int {
+		if v == nil {
+			return def
+		}
+		i, _ := toInt(v)
+		if i < 0 {
+			i += length
+		}
+		if step > 0 {
+			return int(math.Max(0, math.Min(float64(i), float64(length))))
+		}
+		return int(math.Max(-1, math.Min(float64(i), float64(length-1))))
+	}
+
+	var ret []interface{}
+	if step > 0 {
+		for i := index(bounds[0], 0); i < index(bounds[1], length); i += step {
+			ret = append(ret, items[i])
+		}
+	} else {
+		for i := index(bounds[0], length-1); i > index(bounds[1], -1); i += step {
+			ret = append(ret, items[i])
+		}
+	}
+
+	if isStr {
+		builder := strings.Builder{}
+		for _, c := range ret {
+			builder.WriteString(c.(string))
+		}
+		return builder.String(), nil
+	}
+	if ret == nil {
+		ret = []interface{}{}
+	}
+	return ret, nil
+}
+
+func (r *renderer) evalBinary(e *binaryExpr) (interface{}, error) {
+	left, err := r.eval(e.left)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "and":
+		if !truthy(left) {
+			return left, nil
+		}
+		return r.eval(e.right)
+	case "or":
+		if truthy(left) {
+			return left, nil
+		}
+		return r.eval(e.right)
+	}
+
+	right, err := r.eval(e.right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.op {
+	case "==":
+		return equals(left, right), nil
+	case "!=":
+		return !equals(left, right), nil
+	case "<":
+		return compare(left, right) < 0, nil
+	case ">":
+		return compare(left, right) > 0, nil
+	case "<=":
+		return compare(left, right) <= 0, nil
+	case ">=":
+		return compare(left, right) >= 0, nil
+	case "in":
+		return contains(right, left)
+	case "not in":
+		ok, err := contains(right, left)
+		return !ok, err
+	case "~":
+		return toString(left) + toString(right), nil
+	}
+	return arithmetic(e.op, left, right)
+}
+
+// contains indicates whether the item is contained by the container: a substring of a string, an element of a list
+// or a key of a dictionary.
+func contains(container, item interface{}) (bool, error) {
+	if str, ok := asString(container); ok {
+		return strings.Contains(str, toString(item)), nil
+	}
+	if reflect.ValueOf(container).Kind() == reflect.Map {
+		return !isUndefined(getItem(container, item)), nil
+	}
+	items, err := toList(container)
+	if err != nil {
+		return false, err
+	}
+	for _, i := range items {
+		if equals(i, item) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// arithmetic applies the arithmetic operator to the given values. Integers are preserved unless one of the operands is
+// a float or the operator is a true division.
+func arithmetic(op string, left, right interface{}) (interface{}, error) {
+	if str, ok := asString(left); ok {
+		switch op {
+		case "+":
+			if s, ok := asString(right); ok {
+				return str + s, nil
+			}
+		case "*":
+			if n, ok := right.(int); ok && n >= 0 {
+				return strings.Repeat(str, n), nil
+			}
+		case "%":
+			args, isList := right.([]interface{})
+			if !isList {
+				args = []interface{}{right}
+			}
+			return pyFormat(str, args...), nil
+		}
+		return nil, fmt.Errorf("unsupported operand types for %s: '%T' and '%T'", op, left, right)
+	}
+	if op == "+" {
+		if l, ok := left.([]interface{}); ok {
+			if r, ok := right.([]interface{}); ok {
+				return append(append([]interface{}{}, l...), r...), nil
+			}
+		}
+	}
+
+	a, okA := toNumber(left)
+	b, okB := toNumber(right)
+	if !okA || !okB {
+		return nil, fmt.Errorf("unsupported operand types for %s: '%T' and '%T'", op, left, right)
+	}
+
+	x, isIntA := a.(int)
+	y, isIntB := b.(int)
+	if isIntA && isIntB {
+		switch op {
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "*":
+			return x * y, nil
+		case "//", "%":
+			if y == 0 {
+				return nil, fmt.Errorf("integer division or modulo by zero")
+			}
+			q, m := x/y, x%y
+			if m != 0 && (m < 0) != (y < 0) {
+				q, m = q-1, m+y
+			}
+			if op == "//" {
+				return q, nil
+			}
+			return m, nil
+		case "**":
+			if y >= 0 {
+				ret := 1
+				for i := 0; i < y; i++ {
+					ret *= x
+				}
+				return ret, nil
+			}
+		}
+	}
+
+	fx, _ := toFloat(a)
+	fy, _ := toFloat(b)
+	switch op {
+	case "+":
+		return fx + fy, nil
+	case "-":
+		return fx - fy, nil
+	case "*":
+		return fx * fy, nil
+	case "/":
+		if fy == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return fx / fy, nil
+	case "//":
+		if fy == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Floor(fx / fy), nil
+	case "%":
+		if fy == 0 {
+			return nil, fmt.Errorf("modulo by zero")
+		}
+		return fx - math.Floor(fx/fy)*fy, nil
+	case "**":
+		return math.Pow(fx, fy), nil
+	}
+	return nil, fmt.Errorf("unknown operator '%s'", op)
+}
+
+// pyFormat formats the string using printf-style placeholders, as the Python '%' operator does. The '%s' verb uses
+// the Python string representation of the value.
+func pyFormat(format string, args ...interface{}) string {
+	builder := strings.Builder{}
+	argIndex := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i == len(format)-1 {
+			builder.WriteByte(format[i])
+			continue
+		}
+		j := i + 1
+		for j < len(format) && strings.IndexByte("-+ #0.123456789", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) {
+			builder.WriteString(format[i:])
+			break
+		}
+		verb := format[j]
+		spec := format[i:j]
+		switch {
+		case verb == '%':
+			builder.WriteByte('%')
+		case argIndex >= len(args):
+			builder.WriteString(format[i : j+1])
+		case verb == 's' || verb == 'r':
+			builder.WriteString(fmt.Sprintf(spec+"s", toString(args[argIndex])))
+			argIndex++
+		case verb == 'd' || verb == 'i':
+			n, _ := toInt(args[argIndex])
+			builder.WriteString(fmt.Sprintf(spec+"d", n))
+			argIndex++
+		default:
+			if f, ok := toFloat(args[argIndex]); ok && strings.IndexByte("eEfFgG", verb) >= 0 {
+				builder.WriteString(fmt.Sprintf(spec+string(verb), f))
+			} else {
+				builder.WriteString(fmt.Sprintf(spec+string(verb), args[argIndex]))
+			}
+			argIndex++
+		}
+		i = j
+	}
+	return builder.String()
+}
@@ -0,0 +1,835 @@
+package jinja
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v2"
+)
+
+type filterFunc func(r *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+type testFunc func(value interface{}, args []interface{}) (bool, error)
+
+// globals are the functions available to every template
+var globals = map[string]interface{}{
+	"range": callable(func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+		start, stop, step := 0, 0, 1
+		switch len(args) {
+		case 1:
+			stop, _ = toInt(args[0])
+		case 2, 3:
+			start, _ = toInt(args[0])
+			stop, _ = toInt(args[1])
+			if len(args) == 3 {
+				step, _ = toInt(args[2])
+			}
+		default:
+			return nil, fmt.Errorf("range expects 1 to 3 arguments")
+		}
+		if step == 0 {
+			return nil, fmt.Errorf("range step cannot be zero")
+		}
+		ret := []interface{}{}
+		for i := start; (step > 0 && i < stop) || (step < 0 && i > stop); i += step {
+			ret = append(ret, i)
+		}
+		return ret, nil
+	}),
+	"dict": callable(func(_ []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		return kwargs, nil
+	}),
+	"namespace": callable(func(_ []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		return kwargs, nil
+	}),
+}
+
+var filters map[string]filterFunc
+
+var tests map[string]testFunc
+
+func init() {
+	filters = map[string]filterFunc{
+		"default":      defaultFilter,
+		"d":            defaultFilter,
+		"upper":        stringFilter(strings.ToUpper),
+		"lower":        stringFilter(strings.ToLower),
+		"capitalize":   stringFilter(capitalize),
+		"title":        stringFilter(title),
+		"trim":         trimFilter,
+		"replace":      replaceFilter,
+		"length":       lengthFilter,
+		"count":        lengthFilter,
+		"join":         joinFilter,
+		"first":        firstFilter,
+		"last":         lastFilter,
+		"list":         listFilter,
+		"int":          intFilter,
+		"float":        floatFilter,
+		"string":       stringFilter(func(s string) string { return s }),
+		"bool":         boolFilter,
+		"abs":          absFilter,
+		"round":        roundFilter,
+		"truncate":     truncateFilter,
+		"wordcount":    stringFilter(func(s string) string { return strconv.Itoa(len(strings.Fields(s))) }),
+		"indent":       indentFilter,
+		"center":       centerFilter,
+		"format":       formatFilter,
+		"tojson":       jsonFilter,
+		"to_json":      jsonFilter,
+		"to_nice_json": niceJSONFilter,
+		"from_json":    fromJSONFilter,
+		"to_yaml":      yamlFilter,
+		"to_nice_yaml": yamlFilter,
+		"from_yaml":    fromYAMLFilter,
+		"sort":         sortFilter,
+		"unique":       uniqueFilter,
+		"reverse":      reverseFilter,
+		"sum":          sumFilter,
+		"min":          minMaxFilter(-1),
+		"max":          minMaxFilter(1),
+		"map":          mapFilter,
+		"select":       selectFilter(false, false),
+		"reject":       selectFilter(true, false),
+		"selectattr":   selectFilter(false, true),
+		"rejectattr":   selectFilter(true, true),
+		"dictsort":     dictsortFilter,
+		"items":        itemsFilter,
+		"batch":        batchFilter,
+		"attr":         attrFilter,
+		"safe": func(_ *renderer, v interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+			return v, nil
+		},
+		"escape":    stringFilter(html.EscapeString),
+		"e":         stringFilter(html.EscapeString),
+		"urlencode": urlencodeFilter,
+		"mandatory": mandatoryFilter,
+	}
+
+	tests = map[string]testFunc{
+		"defined":   func(v interface{}, _ []interface{}) (bool, error) { return !isUndefined(v), nil },
+		"undefined": func(v interface{}, _ []interface{}) (bool, error) { return isUndefined(v), nil },
+		"none":      func(v interface{}, _ []interface{}) (bool, error) { return v == nil, nil },
+		"true":      func(v interface{}, _ []interface{}) (bool, error) { return v == true, nil },
+		"false":     func(v interface{}, _ []interface{}) (bool, error) { return v == false, nil },
+		"boolean":   func(v interface{}, _ []interface{}) (bool, error) { _, ok := v.(bool); return ok, nil },
+		"string":    func(v interface{}, _ []interface{}) (bool, error) { _, ok := asString(v); return ok, nil },
+		"number":    isNumberTest,
+		"integer":   isKindTest(reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64),
+		"float":     isKindTest(reflect.Float32, reflect.Float64),
+		"mapping":   isKindTest(reflect.Map),
+		"sequence":  isKindTest(reflect.Slice, reflect.Array, reflect.String, reflect.Map),
+		"iterable":  isKindTest(reflect.Slice, reflect.Array, reflect.String, reflect.Map),
+		"callable":  isKindTest(reflect.Func),
+		"lower": func(v interface{}, _ []interface{}) (bool, error) {
+			s := toString(v)
+			return s == strings.ToLower(s), nil
+		},
+		"upper": func(v interface{}, _ []interface{}) (bool, error) {
+			s := toString(v)
+			return s == strings.ToUpper(s), nil
+		},
+		"even":        func(v interface{}, _ []interface{}) (bool, error) { i, _ := toInt(v); return i%2 == 0, nil },
+		"odd":         func(v interface{}, _ []interface{}) (bool, error) { i, _ := toInt(v); return i%2 != 0, nil },
+		"divisibleby": divisibleByTest,
+		"eq":          compareTest(func(a, b interface{}) bool { return equals(a, b) }),
+		"equalto":     compareTest(func(a, b interface{}) bool { return equals(a, b) }),
+		"ne":          compareTest(func(a, b interface{}) bool { return !equals(a, b) }),
+		"lt":          compareTest(func(a, b interface{}) bool { return compare(a, b) < 0 }),
+		"lessthan":    compareTest(func(a, b interface{}) bool { return compare(a, b) < 0 }),
+		"le":          compareTest(func(a, b interface{}) bool { return compare(a, b) <= 0 }),
+		"gt":          compareTest(func(a, b interface{}) bool { return compare(a, b) > 0 }),
+		"greaterthan": compareTest(func(a, b interface{}) bool { return compare(a, b) > 0 }),
+		"ge":          compareTest(func(a, b interface{}) bool { return compare(a, b) >= 0 }),
+		"sameas":      compareTest(sameAs),
+		"in": func(v interface{}, args []interface{}) (bool, error) {
+			if len(args) != 1 {
+				return false, fmt.Errorf("test 'in' expects 1 argument")
+			}
+			return contains(args[0], v)
+		},
+	}
+}
+
+// applyFilter applies the filter to the value. Filters not built into the engine are resolved from the registered
+// helpers, passing the value as the first argument. Namespaced filter names such as 'ansible.builtin.to_yaml' fall
+// back to their last segment.
+func (r *renderer) applyFilter(f *filterExpr, value interface{}) (interface{}, error) {
+	args, err := r.evalList(f.args)
+	if err != nil {
+		return nil, err
+	}
+	kwargs, err := r.evalKwargs(f.kwargs)
+	if err != nil {
+		return nil, err
+	}
+	return r.filter(f.name, value, args, kwargs)
+}
+
+func (r *renderer) filter(name string, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	names := []string{name}
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		names = append(names, name[i+1:])
+	}
+	for _, n := range names {
+		if fn, ok := filters[n]; ok {
+			ret, err := fn(r, value, args, kwargs)
+			if err != nil {
+				return nil, fmt.Errorf("filter '%s': %v", name, err)
+			}
+			return ret, nil
+		}
+		if fn, ok := r.helpers[n]; ok {
+			ret, err := call(fn, append([]interface{}{value}, args...), kwargs)
+			if err != nil {
+				return nil, fmt.Errorf("filter '%s': %v", name, err)
+			}
+			return ret, nil
+		}
+	}
+	return nil, fmt.Errorf("no filter named '%s'", name)
+}
+
+func (r *renderer) applyTest(name string, value interface{}, args []interface{}) (bool, error) {
+	if fn, ok := tests[name]; ok {
+		return fn(value, args)
+	}
+	if fn, ok := r.helpers[name]; ok {
+		ret, err := call(fn, append([]interface{}{value}, args...), nil)
+		if err != nil {
+			return false, fmt.Errorf("test '%s': %v", name, err)
+		}
+		return truthy(ret), nil
+	}
+	return false, fmt.Errorf("no test named '%s'", name)
+}
+
+// arg returns the argument in the given position, or by the given keyword, or the default value if not provided
+func arg(args []interface{}, kwargs map[string]interface{}, index int, name string, def interface{}) interface{} {
+	if index < len(args) {
+		return args[index]
+	}
+	if v, ok := kwargs[name]; ok {
+		return v
+	}
+	return def
+}
+
+/** Filters */
+
+func stringFilter(fn func(string) string) filterFunc {
+	return func(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+		return fn(toString(value)), nil
+	}
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + strings.ToLower(s[1:])
+}
+
+func title(s string) string {
+	ret := []rune(strings.ToLower(s))
+	for i := range ret {
+		if unicode.IsLetter(ret[i]) && (i == 0 || !unicode.IsLetter(ret[i-1]) && !unicode.IsDigit(ret[i-1]) && ret[i-1] != '\'') {
+			ret[i] = unicode.ToUpper(ret[i])
+		}
+	}
+	return string(ret)
+}
+
+func defaultFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	def := arg(args, kwargs, 0, "default_value", "")
+	if isUndefined(value) || (truthy(arg(args, kwargs, 1, "boolean", false)) && !truthy(value)) {
+		return def, nil
+	}
+	return value, nil
+}
+
+func mandatoryFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if isUndefined(value) {
+		msg := arg(args, kwargs, 0, "msg", nil)
+		if msg == nil {
+			return nil, fmt.Errorf("mandatory variable '%s' not defined", value.(*undefined).name)
+		}
+		return nil, fmt.Errorf("%s", toString(msg))
+	}
+	return value, nil
+}
+
+func trimFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if chars := arg(args, kwargs, 0, "chars", nil); chars != nil {
+		return strings.Trim(toString(value), toString(chars)), nil
+	}
+	return strings.TrimSpace(toString(value)), nil
+}
+
+func replaceFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("expected the old and new strings")
+	}
+	count, _ := toInt(arg(args, kwargs, 2, "count", -1))
+	return strings.Replace(toString(value), toString(args[0]), toString(args[1]), count), nil
+}
+
+func lengthFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	if str, ok := asString(value); ok {
+		return len([]rune(str)), nil
+	}
+	items, err := toList(value)
+	return len(items), err
+}
+
+func joinFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	attribute := arg(args, kwargs, 1, "attribute", nil)
+	parts := make([]string, len(items))
+	for i, item := range items {
+		if attribute != nil {
+			item = getAttr(item, attribute)
+		}
+		parts[i] = toString(item)
+	}
+	return strings.Join(parts, toString(arg(args, kwargs, 0, "d", ""))), nil
+}
+
+func firstFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	items, err := toList(value)
+	if err != nil || len(items) == 0 {
+		return &undefined{name: "first"}, err
+	}
+	return items[0], nil
+}
+
+func lastFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	items, err := toList(value)
+	if err != nil || len(items) == 0 {
+		return &undefined{name: "last"}, err
+	}
+	return items[len(items)-1], nil
+}
+
+func listFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	items, err := toList(value)
+	if items == nil && err == nil {
+		items = []interface{}{}
+	}
+	return items, err
+}
+
+func intFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if str, ok := asString(value); ok {
+		str = strings.TrimSpace(str)
+		base, _ := toInt(arg(args, kwargs, 1, "base", 10))
+		if i, err := strconv.ParseInt(str, base, 64); err == nil {
+			return int(i), nil
+		}
+		if f, err := strconv.ParseFloat(str, 64); err == nil {
+			return int(f), nil
+		}
+		return arg(args, kwargs, 0, "default", 0), nil
+	}
+	if i, ok := toInt(value); ok {
+		return i, nil
+	}
+	return arg(args, kwargs, 0, "default", 0), nil
+}
+
+func floatFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if str, ok := asString(value); ok {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(str), 64); err == nil {
+			return f, nil
+		}
+		return arg(args, kwargs, 0, "default", 0.0), nil
+	}
+	if f, ok := toFloat(value); ok {
+		return f, nil
+	}
+	return arg(args, kwargs, 0, "default", 0.0), nil
+}
+
+func boolFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	if str, ok := asString(value); ok {
+		switch strings.ToLower(strings.TrimSpace(str)) {
+		case "yes", "on", "1", "true", "y", "t":
+			return true, nil
+		}
+		return false, nil
+	}
+	return truthy(value), nil
+}
+
+func absFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	n, ok := toNumber(value)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a number", toString(value))
+	}
+	if i, isInt := n.(int); isInt {
+		if i < 0 {
+			return -i, nil
+		}
+		return i, nil
+	}
+	return math.Abs(n.(float64)), nil
+}
+
+func roundFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	f, ok := toFloat(value)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a number", toString(value))
+	}
+	precision, _ := toInt(arg(args, kwargs, 0, "precision", 0))
+	pow := math.Pow(10, float64(precision))
+	switch toString(arg(args, kwargs, 1, "method", "common")) {
+	case "ceil":
+		return math.Ceil(f*pow) / pow, nil
+	case "floor":
+		return math.Floor(f*pow) / pow, nil
+	}
+	return math.Round(f*pow) / pow, nil
+}
+
+func truncateFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	str := []rune(toString(value))
+	length, _ := toInt(arg(args, kwargs, 0, "length", 255))
+	killWords := truthy(arg(args, kwargs, 1, "killwords", false))
+	end := toString(arg(args, kwargs, 2, "end", "..."))
+	leeway, _ := toInt(arg(args, kwargs, 3, "leeway", 5))
+	if len(str) <= length+leeway {
+		return string(str), nil
+	}
+	cut := length - len([]rune(end))
+	if cut < 0 {
+		cut = 0
+	}
+	ret := string(str[:cut])
+	if !killWords {
+		if i := strings.LastIndex(ret, " "); i >= 0 {
+			ret = ret[:i]
+		}
+	}
+	return ret + end, nil
+}
+
+func indentFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	prefix := ""
+	switch w := arg(args, kwargs, 0, "width", 4).(type) {
+	case string:
+		prefix = w
+	default:
+		n, _ := toInt(w)
+		prefix = strings.Repeat(" ", n)
+	}
+	first := truthy(arg(args, kwargs, 1, "first", false))
+	blank := truthy(arg(args, kwargs, 2, "blank", false))
+
+	lines := strings.Split(toString(value), "\n")
+	for i, line := range lines {
+		if (i == 0 && !first) || (line == "" && !blank) {
+			continue
+		}
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func centerFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	str := toString(value)
+	width, _ := toInt(arg(args, kwargs, 0, "width", 80))
+	pad := width - len([]rune(str))
+	if pad <= 0 {
+		return str, nil
+	}
+	left := pad / 2
+	return strings.Repeat(" ", left) + str + strings.Repeat(" ", pad-left), nil
+}
+
+func formatFilter(_ *renderer, value interface{}, args []interface{}, _ map[string]interface{}) (interface{}, error) {
+	return pyFormat(toString(value), args...), nil
+}
+
+func jsonFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if indent, _ := toInt(arg(args, kwargs, 0, "indent", 0)); indent > 0 {
+		data, err = json.MarshalIndent(jsonValue(value), "", strings.Repeat(" ", indent))
+	} else {
+		data, err = json.Marshal(jsonValue(value))
+	}
+	return string(data), err
+}
+
+func niceJSONFilter(r *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	if _, ok := kwargs["indent"]; !ok && len(args) == 0 {
+		args = []interface{}{4}
+	}
+	return jsonFilter(r, value, args, kwargs)
+}
+
+func fromJSONFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	var ret interface{}
+	err := json.Unmarshal([]byte(toString(value)), &ret)
+	return ret, err
+}
+
+func yamlFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	data, err := yaml.Marshal(value)
+	return string(data), err
+}
+
+func fromYAMLFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	var ret interface{}
+	err := yaml.Unmarshal([]byte(toString(value)), &ret)
+	return jsonValue(ret), err
+}
+
+// jsonValue converts the maps with interface{} keys produced by the YAML decoder into maps with string keys, so the
+// value can be marshaled as JSON.
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		ret := map[string]interface{}{}
+		for k, item := range v {
+			ret[toString(k)] = jsonValue(item)
+		}
+		return ret
+	case map[string]interface{}:
+		ret := map[string]interface{}{}
+		for k, item := range v {
+			ret[k] = jsonValue(item)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for i, item := range v {
+			ret[i] = jsonValue(item)
+		}
+		return ret
+	case markup:
+		return string(v)
+	case *undefined:
+		return nil
+	}
+	return value
+}
+
+func sortFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	ret := append([]interface{}{}, items...)
+	reverse := truthy(arg(args, kwargs, 0, "reverse", false))
+	caseSensitive := truthy(arg(args, kwargs, 1, "case_sensitive", false))
+	attribute := arg(args, kwargs, 2, "attribute", nil)
+
+	key := func(item interface{}) interface{} {
+		if attribute != nil {
+			item = getAttr(item, attribute)
+		}
+		if str, ok := asString(item); ok && !caseSensitive {
+			return strings.ToLower(str)
+		}
+		return item
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if reverse {
+			return compare(key(ret[i]), key(ret[j])) > 0
+		}
+		return compare(key(ret[i]), key(ret[j])) < 0
+	})
+	return ret, nil
+}
+
+func uniqueFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	items, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	ret := []interface{}{}
+	for _, item := range items {
+		if ok, _ := contains(ret, item); !ok {
+			ret = append(ret, item)
+		}
+	}
+	return ret, nil
+}
+
+func reverseFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	if str, ok := asString(value); ok {
+		runes := []rune(str)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	}
+	items, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]interface{}, len(items))
+	for i, item := range items {
+		ret[len(items)-1-i] = item
+	}
+	return ret, nil
+}
+
+func sumFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	attribute := arg(args, kwargs, 0, "attribute", nil)
+	ret := arg(args, kwargs, 1, "start", 0)
+	for _, item := range items {
+		if attribute != nil {
+			item = getAttr(item, attribute)
+		}
+		if ret, err = arithmetic("+", ret, item); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+func minMaxFilter(sign int) filterFunc {
+	return func(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		items, err := toList(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return &undefined{}, nil
+		}
+		attribute := arg(args, kwargs, 1, "attribute", nil)
+		key := func(item interface{}) interface{} {
+			if attribute != nil {
+				return getAttr(item, attribute)
+			}
+			return item
+		}
+		ret := items[0]
+		for _, item := range items[1:] {
+			if compare(key(item), key(ret))*sign > 0 {
+				ret = item
+			}
+		}
+		return ret, nil
+	}
+}
+
+func mapFilter(r *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]interface{}, len(items))
+	if attribute, ok := kwargs["attribute"]; ok {
+		for i, item := range items {
+			v := getAttr(item, attribute)
+			if def, hasDefault := kwargs["default"]; hasDefault && isUndefined(v) {
+				v = def
+			}
+			ret[i] = v
+		}
+		return ret, nil
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expected a filter name or an attribute")
+	}
+	name := toString(args[0])
+	for i, item := range items {
+		if ret[i], err = r.filter(name, item, args[1:], kwargs); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// selectFilter creates the select, reject, selectattr and rejectattr filters
+func selectFilter(reject, byAttribute bool) filterFunc {
+	return func(r *renderer, value interface{}, args []interface{}, _ map[string]interface{}) (interface{}, error) {
+		items, err := toList(value)
+		if err != nil {
+			return nil, err
+		}
+		var attribute interface{}
+		if byAttribute {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("expected an attribute name")
+			}
+			attribute, args = args[0], args[1:]
+		}
+
+		ret := []interface{}{}
+		for _, item := range items {
+			target := item
+			if byAttribute {
+				target = getAttr(item, attribute)
+			}
+			var ok bool
+			if len(args) == 0 {
+				ok = truthy(target)
+			} else if ok, err = r.applyTest(toString(args[0]), target, args[1:]); err != nil {
+				return nil, err
+			}
+			if ok != reject {
+				ret = append(ret, item)
+			}
+		}
+		return ret, nil
+	}
+}
+
+func itemsFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	if isUndefined(value) || value == nil {
+		return []interface{}{}, nil
+	}
+	method := builtinMethod(value, "items")
+	if method == nil {
+		return nil, fmt.Errorf("can only get items from a mapping")
+	}
+	return method(nil, nil)
+}
+
+func dictsortFilter(r *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := itemsFilter(r, value, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	pairs := items.([]interface{})
+	caseSensitive := truthy(arg(args, kwargs, 0, "case_sensitive", false))
+	index := 0
+	if toString(arg(args, kwargs, 1, "by", "key")) == "value" {
+		index = 1
+	}
+	reverse := truthy(arg(args, kwargs, 2, "reverse", false))
+
+	key := func(pair interface{}) interface{} {
+		v := pair.([]interface{})[index]
+		if str, ok := asString(v); ok && !caseSensitive {
+			return strings.ToLower(str)
+		}
+		return v
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if reverse {
+			return compare(key(pairs[i]), key(pairs[j])) > 0
+		}
+		return compare(key(pairs[i]), key(pairs[j])) < 0
+	})
+	return pairs, nil
+}
+
+func batchFilter(_ *renderer, value interface{}, args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+	items, err := toList(value)
+	if err != nil {
+		return nil, err
+	}
+	size, _ := toInt(arg(args, kwargs, 0, "linecount", 1))
+	if size <= 0 {
+		return nil, fmt.Errorf("the batch size must be greater than zero")
+	}
+	fill := arg(args, kwargs, 1, "fill_with", nil)
+	ret := []interface{}{}
+	for i := 0; i < len(items); i += size {
+		end := i + size
+		if end > len(items) {
+			end = len(items)
+		}
+		batch := append([]interface{}{}, items[i:end]...)
+		for fill != nil && len(batch) < size {
+			batch = append(batch, fill)
+		}
+		ret = append(ret, batch)
+	}
+	return ret, nil
+}
+
+func attrFilter(_ *renderer, value interface{}, args []interface{}, _ map[string]interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expected an attribute name")
+	}
+	return getAttr(value, args[0]), nil
+}
+
+func urlencodeFilter(_ *renderer, value interface{}, _ []interface{}, _ map[string]interface{}) (interface{}, error) {
+	if reflect.ValueOf(value).Kind() == reflect.Map {
+		pairs, _ := builtinMethod(value, "items")(nil, nil)
+		var parts []string
+		for _, p := range pairs.([]interface{}) {
+			kv := p.([]interface{})
+			parts = append(parts, url.QueryEscape(toString(kv[0]))+"="+url.QueryEscape(toString(kv[1])))
+		}
+		return strings.Join(parts, "&"), nil
+	}
+	return url.PathEscape(toString(value)), nil
+}
+
+/** Tests */
+
+func isNumberTest(value interface{}, _ []interface{}) (bool, error) {
+	if _, isBool := value.(bool); isBool {
+		return false, nil
+	}
+	_, ok := toNumber(value)
+	return ok, nil
+}
+
+func isKindTest(kinds ...reflect.Kind) testFunc {
+	return func(value interface{}, _ []interface{}) (bool, error) {
+		if value == nil || isUndefined(value) {
+			return false, nil
+		}
+		kind := reflect.ValueOf(value).Kind()
+		for _, k := range kinds {
+			if kind == k {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+func divisibleByTest(value interface{}, args []interface{}) (bool, error) {
+	if len(args) != 1 {
+		return false, fmt.Errorf("test 'divisibleby' expects 1 argument")
+	}
+	a, _ := toInt(value)
+	b, _ := toInt(args[0])
+	if b == 0 {
+		return false, fmt.Errorf("division by zero")
+	}
+	return a%b == 0, nil
+}
+
+func sameAs(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return reflect.TypeOf(a).Comparable() && reflect.TypeOf(a) == reflect.TypeOf(b) && a == b
+}
+
+func compareTest(fn func(a, b interface{}) bool) testFunc {
+	return func(value interface{}, args []interface{}) (bool, error) {
+		if len(args) != 1 {
+			return false, fmt.Errorf("comparison tests expect 1 argument")
+		}
+		return fn(value, args[0]), nil
+	}
+}
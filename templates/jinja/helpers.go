@@ -0,0 +1,32 @@
+package jinja
+
+import (
+	"sync"
+
+	"github.com/jucardi/infuse/templates/helpers"
+)
+
+var (
+	instance helpers.IHelpersManager
+	once     sync.Once
+)
+
+// Helpers returns the singleton helpers instance used for Jinja templates. The registered helpers are available as
+// global functions and as filters.
+func Helpers() helpers.IHelpersManager {
+	once.Do(func() {
+		instance = helpers.New()
+	})
+	return instance
+}
+
+// pluginCallable returns the plugin helper by the given name as a callable, so it receives the keyword arguments
+func pluginCallable(plugins *helpers.PluginCache, name string) callable {
+	return func(args []interface{}, kwargs map[string]interface{}) (interface{}, error) {
+		return plugins.Call(name, args, kwargs)
+	}
+}
+
+func init() {
+	helpers.RegisterCommon(Helpers())
+}
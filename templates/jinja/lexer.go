@@ -0,0 +1,138 @@
+package jinja
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type tagKind int
+
+const (
+	tagText tagKind = iota
+	tagVariable
+	tagBlock
+)
+
+// tag is a piece of the template source, either raw text, a variable expression '{{ }}' or a statement '{% %}'.
+type tag struct {
+	kind    tagKind
+	content string
+	line    int
+}
+
+var rawEndRegex = regexp.MustCompile(`\{%-?\s*endraw\s*-?%\}`)
+
+// lex splits the template source into text, variable and block tags. Comments are discarded, whitespace control
+// markers ('-') are applied to the adjacent text and the contents of {% raw %} blocks are kept as text. As with the
+// 'trim_blocks' setting used by Ansible, the first newline after a statement or a comment is removed.
+func lex(src string) ([]*tag, error) {
+	var (
+		tags      []*tag
+		line      = 1
+		trimStart = false
+	)
+
+	appendText := func(text string, trimEnd bool) {
+		if trimStart {
+			text = strings.TrimLeft(text, " \t\r\n")
+		}
+		if trimEnd {
+			text = strings.TrimRight(text, " \t\r\n")
+		}
+		if text != "" {
+			tags = append(tags, &tag{kind: tagText, content: text, line: line})
+		}
+		trimStart = false
+	}
+
+	for len(src) > 0 {
+		start := nextTagStart(src)
+		if start < 0 {
+			appendText(src, false)
+			break
+		}
+
+		open := src[start : start+2]
+		trimEnd := len(src) > start+2 && src[start+2] == '-'
+		appendText(src[:start], trimEnd)
+		line += strings.Count(src[:start], "\n")
+
+		closing := map[string]string{"{{": "}}", "{%": "%}", "{#": "#}"}[open]
+		bodyStart := start + 2
+		if trimEnd {
+			bodyStart++
+		}
+
+		end := findTagEnd(src[bodyStart:], closing, open != "{#")
+		if end < 0 {
+			return nil, fmt.Errorf("line %d: unclosed tag '%s'", line, open)
+		}
+		content := src[bodyStart : bodyStart+end]
+		rest := src[bodyStart+end+2:]
+		trimStart = strings.HasSuffix(content, "-")
+		content = strings.TrimSpace(strings.TrimSuffix(content, "-"))
+		tagLine := line
+		line += strings.Count(src[start:bodyStart+end+2], "\n")
+
+		switch open {
+		case "{{":
+			tags = append(tags, &tag{kind: tagVariable, content: content, line: tagLine})
+		case "{%":
+			if content == "raw" {
+				loc := rawEndRegex.FindStringIndex(rest)
+				if loc == nil {
+					return nil, fmt.Errorf("line %d: missing endraw", tagLine)
+				}
+				appendText(rest[:loc[0]], strings.HasPrefix(rest[loc[0]:], "{%-"))
+				line += strings.Count(rest[:loc[1]], "\n")
+				trimStart = strings.HasSuffix(rest[:loc[1]], "-%}")
+				rest = rest[loc[1]:]
+				break
+			}
+			tags = append(tags, &tag{kind: tagBlock, content: content, line: tagLine})
+		}
+		if open != "{{" && strings.HasPrefix(rest, "\n") {
+			rest = rest[1:]
+			line++
+		} else if open != "{{" && strings.HasPrefix(rest, "\r\n") {
+			rest = rest[2:]
+			line++
+		}
+		src = rest
+	}
+	return tags, nil
+}
+
+func nextTagStart(src string) int {
+	for i := 0; i < len(src)-1; i++ {
+		if src[i] == '{' && (src[i+1] == '{' || src[i+1] == '%' || src[i+1] == '#') {
+			return i
+		}
+	}
+	return -1
+}
+
+// findTagEnd returns the index of the closing delimiter, skipping string literals if 'skipStrings' is set.
+func findTagEnd(src, closing string, skipStrings bool) int {
+	var quote byte
+	for i := 0; i < len(src)-1; i++ {
+		c := src[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if skipStrings && (c == '"' || c == '\'') {
+			quote = c
+			continue
+		}
+		if src[i:i+2] == closing {
+			return i
+		}
+	}
+	return -1
+}
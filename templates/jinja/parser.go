@@ -0,0 +1,1074 @@
+package jinja
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokName
+	tokString
+	tokInt
+	tokFloat
+	tokOp
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+var operators = []string{"**", "//", "==", "!=", "<=", ">=", "+", "-", "*", "/", "%", "~", "<", ">", "=", "(", ")", "[", "]", "{", "}", ".", ",", ":", "|"}
+
+// tokenize splits the contents of a tag into expression tokens
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '\'':
+			str, n, err := readString(src[i:])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, value: str})
+			i += n
+		case c >= '0' && c <= '9':
+			j := i
+			isFloat := false
+			for j < len(src) && (src[j] >= '0' && src[j] <= '9' || src[j] == '_' || (src[j] == '.' && !isFloat && j+1 < len(src) && src[j+1] >= '0' && src[j+1] <= '9')) {
+				if src[j] == '.' {
+					isFloat = true
+				}
+				j++
+			}
+			kind := tokInt
+			if isFloat {
+				kind = tokFloat
+			}
+			tokens = append(tokens, token{kind: kind, value: strings.Replace(src[i:j], "_", "", -1)})
+			i = j
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] >= 'a' && src[j] <= 'z' || src[j] >= 'A' && src[j] <= 'Z' || src[j] >= '0' && src[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokName, value: src[i:j]})
+			i = j
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokOp, value: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character '%c'", c)
+			}
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+func readString(src string) (string, int, error) {
+	quote := src[0]
+	builder := strings.Builder{}
+	for i := 1; i < len(src); i++ {
+		c := src[i]
+		if c == quote {
+			return builder.String(), i + 1, nil
+		}
+		if c == '\\' && i+1 < len(src) {
+			i++
+			switch src[i] {
+			case 'n':
+				builder.WriteByte('\n')
+			case 't':
+				builder.WriteByte('\t')
+			case 'r':
+				builder.WriteByte('\r')
+			default:
+				builder.WriteByte(src[i])
+			}
+			continue
+		}
+		builder.WriteByte(c)
+	}
+	return "", 0, fmt.Errorf("unterminated string %s", src)
+}
+
+/** Expression parser */
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+func newExprParser(src string) (*exprParser, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	return &exprParser{tokens: tokens}, nil
+}
+
+func (p *exprParser) current() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *exprParser) isOp(op string) bool {
+	t := p.current()
+	return t.kind == tokOp && t.value == op
+}
+
+func (p *exprParser) isName(name string) bool {
+	t := p.current()
+	return t.kind == tokName && t.value == name
+}
+
+func (p *exprParser) skipOp(op string) bool {
+	if p.isOp(op) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) skipName(name string) bool {
+	if p.isName(name) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expectOp(op string) error {
+	if !p.skipOp(op) {
+		return fmt.Errorf("expected '%s' but found '%s'", op, p.current().value)
+	}
+	return nil
+}
+
+func (p *exprParser) expectName() (string, error) {
+	t := p.next()
+	if t.kind != tokName {
+		return "", fmt.Errorf("expected a name but found '%s'", t.value)
+	}
+	return t.value, nil
+}
+
+func (p *exprParser) atEnd() bool {
+	return p.current().kind == tokEOF
+}
+
+func (p *exprParser) ensureEnd() error {
+	if !p.atEnd() {
+		return fmt.Errorf("unexpected '%s'", p.current().value)
+	}
+	return nil
+}
+
+// parseTuple parses an expression, or a tuple if multiple expressions are separated by commas
+func (p *exprParser) parseTuple() (expr, error) {
+	first, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp(",") {
+		return first, nil
+	}
+	items := []expr{first}
+	for p.skipOp(",") {
+		if p.atEnd() || p.isOp(")") {
+			break
+		}
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return &listExpr{items: items}, nil
+}
+
+func (p *exprParser) parseExpr() (expr, error) {
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipName("if") {
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		var otherwise expr
+		if p.skipName("else") {
+			if otherwise, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		return &condExpr{cond: cond, then: e, otherwise: otherwise}, nil
+	}
+	return e, nil
+}
+
+func (p *exprParser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipName("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipName("and") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (expr, error) {
+	if p.skipName("not") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "not", operand: operand}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *exprParser) parseCompare() (expr, error) {
+	left, err := p.parseMath1()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op string
+		t := p.current()
+		switch {
+		case t.kind == tokOp && (t.value == "==" || t.value == "!=" || t.value == "<" || t.value == ">" || t.value == "<=" || t.value == ">="):
+			op = t.value
+			p.pos++
+		case p.isName("in"):
+			op = "in"
+			p.pos++
+		case p.isName("not") && p.tokens[p.pos+1].kind == tokName && p.tokens[p.pos+1].value == "in":
+			op = "not in"
+			p.pos += 2
+		default:
+			return left, nil
+		}
+		right, err := p.parseMath1()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseMath1() (expr, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+") || p.isOp("-") {
+		op := p.next().value
+		right, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseConcat() (expr, error) {
+	left, err := p.parseMath2()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipOp("~") {
+		right, err := p.parseMath2()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "~", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseMath2() (expr, error) {
+	left, err := p.parsePow()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*") || p.isOp("/") || p.isOp("//") || p.isOp("%") {
+		op := p.next().value
+		right, err := p.parsePow()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parsePow() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipOp("**") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryExpr{op: "**", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (expr, error) {
+	var (
+		e   expr
+		err error
+	)
+	if p.isOp("-") || p.isOp("+") {
+		op := p.next().value
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		e = &unaryExpr{op: op, operand: operand}
+	} else if e, err = p.parsePrimary(); err != nil {
+		return nil, err
+	} else if e, err = p.parsePostfix(e); err != nil {
+		return nil, err
+	}
+	return p.parseFilters(e)
+}
+
+func (p *exprParser) parsePrimary() (expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokName:
+		switch t.value {
+		case "true", "True":
+			return &literalExpr{value: true}, nil
+		case "false", "False":
+			return &literalExpr{value: false}, nil
+		case "none", "None":
+			return &literalExpr{value: nil}, nil
+		}
+		return &nameExpr{name: t.value}, nil
+	case tokString:
+		str := t.value
+		for p.current().kind == tokString {
+			str += p.next().value
+		}
+		return &literalExpr{value: str}, nil
+	case tokInt:
+		v, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, err
+		}
+		return &literalExpr{value: v}, nil
+	case tokFloat:
+		v, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &literalExpr{value: v}, nil
+	case tokOp:
+		switch t.value {
+		case "(":
+			if p.skipOp(")") {
+				return &listExpr{}, nil
+			}
+			e, err := p.parseTuple()
+			if err != nil {
+				return nil, err
+			}
+			return e, p.expectOp(")")
+		case "[":
+			list := &listExpr{}
+			for !p.skipOp("]") {
+				item, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if !p.skipOp(",") {
+					if err := p.expectOp("]"); err != nil {
+						return nil, err
+					}
+					break
+				}
+			}
+			return list, nil
+		case "{":
+			dict := &dictExpr{}
+			for !p.skipOp("}") {
+				key, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expectOp(":"); err != nil {
+					return nil, err
+				}
+				value, err := p.parseExpr()
+				if err != nil {
+					return nil, err
+				}
+				dict.keys = append(dict.keys, key)
+				dict.values = append(dict.values, value)
+				if !p.skipOp(",") {
+					if err := p.expectOp("}"); err != nil {
+						return nil, err
+					}
+					break
+				}
+			}
+			return dict, nil
+		}
+	}
+	if t.kind == tokEOF {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected '%s'", t.value)
+}
+
+func (p *exprParser) parsePostfix(e expr) (expr, error) {
+	for {
+		switch {
+		case p.skipOp("."):
+			t := p.next()
+			if t.kind != tokName && t.kind != tokInt {
+				return nil, fmt.Errorf("expected an attribute name but found '%s'", t.value)
+			}
+			e = &attrExpr{target: e, attr: t.value}
+		case p.skipOp("["):
+			sub, err := p.parseSubscript(e)
+			if err != nil {
+				return nil, err
+			}
+			e = sub
+		case p.isOp("("):
+			call, err := p.parseCall(e)
+			if err != nil {
+				return nil, err
+			}
+			e = call
+		default:
+			return e, nil
+		}
+	}
+}
+
+func (p *exprParser) parseSubscript(target expr) (expr, error) {
+	var parts [3]expr
+	index, isSlice := 0, false
+	for !p.skipOp("]") {
+		if p.skipOp(":") {
+			isSlice = true
+			index++
+			if index > 2 {
+				return nil, fmt.Errorf("invalid slice")
+			}
+			continue
+		}
+		e, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		parts[index] = e
+	}
+	if isSlice {
+		return &sliceExpr{target: target, start: parts[0], stop: parts[1], step: parts[2]}, nil
+	}
+	if parts[0] == nil {
+		return nil, fmt.Errorf("empty subscript")
+	}
+	return &itemExpr{target: target, index: parts[0]}, nil
+}
+
+// parseArgs parses the positional and keyword arguments of a call, starting at the opening parenthesis
+func (p *exprParser) parseArgs() ([]expr, []kwarg, error) {
+	var (
+		args   []expr
+		kwargs []kwarg
+	)
+	if err := p.expectOp("("); err != nil {
+		return nil, nil, err
+	}
+	for !p.skipOp(")") {
+		if p.current().kind == tokName && p.tokens[p.pos+1].kind == tokOp && p.tokens[p.pos+1].value == "=" {
+			name := p.next().value
+			p.pos++
+			value, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, err
+			}
+			kwargs = append(kwargs, kwarg{name: name, value: value})
+		} else {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, nil, err
+			}
+			args = append(args, arg)
+		}
+		if !p.skipOp(",") {
+			if err := p.expectOp(")"); err != nil {
+				return nil, nil, err
+			}
+			break
+		}
+	}
+	return args, kwargs, nil
+}
+
+func (p *exprParser) parseCall(fn expr) (expr, error) {
+	args, kwargs, err := p.parseArgs()
+	if err != nil {
+		return nil, err
+	}
+	return &callExpr{fn: fn, args: args, kwargs: kwargs}, nil
+}
+
+func (p *exprParser) parseFilters(e expr) (expr, error) {
+	for {
+		switch {
+		case p.skipOp("|"):
+			f, err := p.parseFilter(e)
+			if err != nil {
+				return nil, err
+			}
+			e = f
+		case p.skipName("is"):
+			t, err := p.parseTest(e)
+			if err != nil {
+				return nil, err
+			}
+			e = t
+		default:
+			return e, nil
+		}
+	}
+}
+
+func (p *exprParser) parseFilter(target expr) (*filterExpr, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(".") && p.tokens[p.pos+1].kind == tokName {
+		p.pos++
+		name += "." + p.next().value
+	}
+	f := &filterExpr{target: target, name: name}
+	if p.isOp("(") {
+		if f.args, f.kwargs, err = p.parseArgs(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *exprParser) parseTest(target expr) (expr, error) {
+	negate := p.skipName("not")
+	t := p.next()
+	if t.kind != tokName {
+		return nil, fmt.Errorf("expected a test name but found '%s'", t.value)
+	}
+	test := &testExpr{target: target, name: t.value, negate: negate}
+	switch t.value {
+	case "none", "None", "true", "True", "false", "False":
+		test.name = strings.ToLower(t.value)
+	}
+
+	if p.isOp("(") {
+		args, _, err := p.parseArgs()
+		if err != nil {
+			return nil, err
+		}
+		test.args = args
+	} else if c := p.current(); c.kind == tokString || c.kind == tokInt || c.kind == tokFloat ||
+		(c.kind == tokName && c.value != "and" && c.value != "or" && c.value != "else" && c.value != "if" && c.value != "is" && c.value != "in" && c.value != "not") {
+		arg, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if arg, err = p.parsePostfix(arg); err != nil {
+			return nil, err
+		}
+		test.args = []expr{arg}
+	}
+	return test, nil
+}
+
+/** Statement parser */
+
+type parser struct {
+	name   string
+	tags   []*tag
+	pos    int
+	blocks map[string]*blockNode
+}
+
+// compile parses the template source into a program
+func compile(name, src string) (*program, error) {
+	tags, err := lex(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	p := &parser{name: name, tags: tags, blocks: map[string]*blockNode{}}
+	nodes, end, err := p.parseBody()
+	if err != nil {
+		return nil, err
+	}
+	if end != "" {
+		return nil, p.errorf("unexpected '%s'", end)
+	}
+
+	prog := &program{name: name, nodes: nodes, blocks: p.blocks}
+	for _, n := range nodes {
+		if ext, ok := n.(*extendsNode); ok {
+			prog.extends = ext
+			break
+		}
+	}
+	return prog, nil
+}
+
+// parseError is a syntax error with the template and the line where it was found.
+type parseError struct {
+	name string
+	line int
+	msg  string
+}
+
+func (e *parseError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.name, e.line, e.msg)
+}
+
+// errorf returns a parse error at the last read tag. Errors which already have a position, such as the ones of the
+// nested bodies, are kept as they are.
+func (p *parser) errorf(format string, args ...interface{}) error {
+	if len(args) == 1 && format == "%v" {
+		if err, ok := args[0].(*parseError); ok {
+			return err
+		}
+	}
+	line := 0
+	if p.pos > 0 && p.pos <= len(p.tags) {
+		line = p.tags[p.pos-1].line
+	}
+	return &parseError{name: p.name, line: line, msg: fmt.Sprintf(format, args...)}
+}
+
+// parseBody parses nodes until the end of the template or until one of the given end tags is found. Returns the
+// parsed nodes and the keyword of the tag that ended the body.
+func (p *parser) parseBody(endTags ...string) ([]node, string, error) {
+	var nodes []node
+	for p.pos < len(p.tags) {
+		t := p.tags[p.pos]
+		p.pos++
+
+		switch t.kind {
+		case tagText:
+			nodes = append(nodes, &textNode{text: t.content})
+		case tagVariable:
+			ep, err := newExprParser(t.content)
+			if err != nil {
+				return nil, "", p.errorf("%v", err)
+			}
+			e, err := ep.parseTuple()
+			if err == nil {
+				err = ep.ensureEnd()
+			}
+			if err != nil {
+				return nil, "", p.errorf("%v", err)
+			}
+			nodes = append(nodes, &outputNode{expr: e, name: p.name, line: t.line})
+		case tagBlock:
+			if t.content == "" {
+				return nil, "", p.errorf("empty statement")
+			}
+			keyword := strings.Fields(t.content)[0]
+			for _, end := range endTags {
+				if keyword == end {
+					return nodes, keyword, nil
+				}
+			}
+			n, err := p.parseStatement(keyword, strings.TrimSpace(t.content[len(keyword):]))
+			if err != nil {
+				return nil, "", err
+			}
+			if n != nil {
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	if len(endTags) > 0 {
+		return nil, "", p.errorf("missing '%s'", endTags[len(endTags)-1])
+	}
+	return nodes, "", nil
+}
+
+// lastArgs returns the contents of the tag that ended the last parsed body, without its keyword
+func (p *parser) lastArgs() string {
+	content := p.tags[p.pos-1].content
+	return strings.TrimSpace(content[len(strings.Fields(content)[0]):])
+}
+
+func (p *parser) parseStatement(keyword, args string) (node, error) {
+	ep, err := newExprParser(args)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+
+	var n node
+	switch keyword {
+	case "if":
+		n, err = p.parseIf(ep)
+	case "for":
+		n, err = p.parseFor(ep)
+	case "set":
+		n, err = p.parseSet(ep)
+	case "block":
+		n, err = p.parseBlock(ep)
+	case "extends":
+		var parent expr
+		if parent, err = ep.parseExpr(); err == nil {
+			n = &extendsNode{parent: parent}
+		}
+	case "include":
+		n, err = p.parseInclude(ep)
+	case "macro":
+		n, err = p.parseMacro(ep)
+	case "import":
+		n, err = p.parseImport(ep)
+	case "from":
+		n, err = p.parseFromImport(ep)
+	case "with":
+		n, err = p.parseWith(ep)
+	case "filter":
+		n, err = p.parseFilterBlock(ep)
+	default:
+		return nil, p.errorf("unknown statement '%s'", keyword)
+	}
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	if err := ep.ensureEnd(); err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	return n, nil
+}
+
+func (p *parser) parseIf(ep *exprParser) (node, error) {
+	n := &ifNode{}
+	cond, err := ep.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		body, end, err := p.parseBody("elif", "else", "endif")
+		if err != nil {
+			return nil, err
+		}
+		n.conds = append(n.conds, cond)
+		n.bodies = append(n.bodies, body)
+
+		switch end {
+		case "elif":
+			elif, err := newExprParser(p.lastArgs())
+			if err != nil {
+				return nil, err
+			}
+			if cond, err = elif.parseExpr(); err != nil {
+				return nil, err
+			}
+		case "else":
+			if n.elseBody, _, err = p.parseBody("endif"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parseFor(ep *exprParser) (node, error) {
+	n := &forNode{}
+	for {
+		name, err := ep.expectName()
+		if err != nil {
+			return nil, err
+		}
+		n.targets = append(n.targets, name)
+		if !ep.skipOp(",") {
+			break
+		}
+	}
+	if !ep.skipName("in") {
+		return nil, fmt.Errorf("expected 'in' in for loop")
+	}
+	iter, err := ep.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	n.iter = iter
+	if ep.skipName("if") {
+		if n.cond, err = ep.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+
+	body, end, err := p.parseBody("else", "endfor")
+	if err != nil {
+		return nil, err
+	}
+	n.body = body
+	if end == "else" {
+		if n.elseBody, _, err = p.parseBody("endfor"); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) parseSet(ep *exprParser) (node, error) {
+	n := &setNode{}
+	for {
+		target, err := ep.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if ep.skipOp(".") {
+			attr, err := ep.expectName()
+			if err != nil {
+				return nil, err
+			}
+			target = &attrExpr{target: target, attr: attr}
+		}
+		n.targets = append(n.targets, target)
+		if !ep.skipOp(",") {
+			break
+		}
+	}
+
+	if ep.skipOp("=") {
+		value, err := ep.parseTuple()
+		if err != nil {
+			return nil, err
+		}
+		n.value = value
+		return n, nil
+	}
+
+	body, _, err := p.parseBody("endset")
+	if err != nil {
+		return nil, err
+	}
+	n.body = body
+	return n, nil
+}
+
+func (p *parser) parseBlock(ep *exprParser) (node, error) {
+	name, err := ep.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.blocks[name]; ok {
+		return nil, fmt.Errorf("block '%s' defined twice", name)
+	}
+	body, _, err := p.parseBody("endblock")
+	if err != nil {
+		return nil, err
+	}
+	n := &blockNode{name: name, body: body}
+	p.blocks[name] = n
+	return n, nil
+}
+
+func (p *parser) parseInclude(ep *exprParser) (node, error) {
+	name, err := ep.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	n := &includeNode{name: name, withContext: true}
+	if ep.skipName("ignore") {
+		if !ep.skipName("missing") {
+			return nil, fmt.Errorf("expected 'missing' after 'ignore'")
+		}
+		n.ignoreMissing = true
+	}
+	if ep.skipName("without") {
+		n.withContext = false
+		_ = ep.skipName("context")
+	} else if ep.skipName("with") {
+		_ = ep.skipName("context")
+	}
+	return n, nil
+}
+
+func (p *parser) parseMacro(ep *exprParser) (node, error) {
+	name, err := ep.expectName()
+	if err != nil {
+		return nil, err
+	}
+	n := &macroNode{name: name, defaults: map[string]expr{}}
+	if err := ep.expectOp("("); err != nil {
+		return nil, err
+	}
+	for !ep.skipOp(")") {
+		param, err := ep.expectName()
+		if err != nil {
+			return nil, err
+		}
+		n.params = append(n.params, param)
+		if ep.skipOp("=") {
+			if n.defaults[param], err = ep.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		if !ep.skipOp(",") {
+			if err := ep.expectOp(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	if n.body, _, err = p.parseBody("endmacro"); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (p *parser) parseImport(ep *exprParser) (node, error) {
+	name, err := ep.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !ep.skipName("as") {
+		return nil, fmt.Errorf("expected 'as' in import")
+	}
+	alias, err := ep.expectName()
+	if err != nil {
+		return nil, err
+	}
+	return &importNode{name: name, alias: alias}, nil
+}
+
+func (p *parser) parseFromImport(ep *exprParser) (node, error) {
+	name, err := ep.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if !ep.skipName("import") {
+		return nil, fmt.Errorf("expected 'import' in from statement")
+	}
+	n := &fromImportNode{name: name}
+	for {
+		imported, err := ep.expectName()
+		if err != nil {
+			return nil, err
+		}
+		alias := imported
+		if ep.skipName("as") {
+			if alias, err = ep.expectName(); err != nil {
+				return nil, err
+			}
+		}
+		n.names = append(n.names, imported)
+		n.aliases = append(n.aliases, alias)
+		if !ep.skipOp(",") {
+			break
+		}
+	}
+	return n, nil
+}
+
+func (p *parser) parseWith(ep *exprParser) (node, error) {
+	n := &withNode{}
+	for !ep.atEnd() {
+		name, err := ep.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := ep.expectOp("="); err != nil {
+			return nil, err
+		}
+		value, err := ep.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		n.names = append(n.names, name)
+		n.values = append(n.values, value)
+		if !ep.skipOp(",") {
+			break
+		}
+	}
+	body, _, err := p.parseBody("endwith")
+	if err != nil {
+		return nil, err
+	}
+	n.body = body
+	return n, nil
+}
+
+func (p *parser) parseFilterBlock(ep *exprParser) (node, error) {
+	n := &filterBlockNode{}
+	for {
+		f, err := ep.parseFilter(nil)
+		if err != nil {
+			return nil, err
+		}
+		n.filters = append(n.filters, f)
+		if !ep.skipOp("|") {
+			break
+		}
+	}
+	body, _, err := p.parseBody("endfilter")
+	if err != nil {
+		return nil, err
+	}
+	n.body = body
+	return n, nil
+}
@@ -0,0 +1,96 @@
+package jinja
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/jucardi/go-strings/stringx"
+	"github.com/jucardi/infuse/templates"
+	"github.com/jucardi/infuse/templates/base"
+	"github.com/jucardi/infuse/templates/helpers"
+)
+
+// TypeJinja is the type for Jinja2 templates
+const TypeJinja = "jinja"
+
+func init() {
+	templates.Factory().Register(TypeJinja, func(name ...string) templates.ITemplate { return New(name...) })
+}
+
+// Template represents the implementation of ITemplate for Jinja2 templates. Loaded definitions can be used by name
+// with the 'include', 'extends', 'import' and 'from ... import' statements.
+type Template struct {
+	*base.AbstractTemplate
+	program *program
+}
+
+// Type returns the template type of this instance.
+func (t *Template) Type() string {
+	return TypeJinja
+}
+
+// SetDelims returns an error if custom delimiters are specified, since they are not supported by Jinja templates.
+func (t *Template) SetDelims(left, right string) error {
+	if left != "" || right != "" {
+		return errors.New("custom delimiters are not supported by jinja templates")
+	}
+	return nil
+}
+
+// Parse parses the template
+func (t *Template) Parse(writer io.Writer, data interface{}) error {
+	prog := t.program
+	if prog == nil {
+		var err error
+		if prog, err = compile(t.NameStr, t.Template); err != nil {
+			return err
+		}
+	}
+
+	out := &strings.Builder{}
+	r := newRenderer(t, data)
+	if err := r.run(prog, out); err != nil {
+		return err
+	}
+	_, err := writer.Write([]byte(out.String()))
+	return err
+}
+
+// LoadTemplate loads the given string as the template to be parsed.
+func (t *Template) LoadTemplate(tmpl string) error {
+	prog, err := compile(t.NameStr, tmpl)
+	if err != nil {
+		return err
+	}
+	t.Template = tmpl
+	t.program = prog
+	return nil
+}
+
+// LoadDefinition loads the given template string as a definition, to be used by 'include', 'extends' and 'import'
+// statements.
+func (t *Template) LoadDefinition(name, tmpl string) error {
+	if _, err := compile(name, tmpl); err != nil {
+		return err
+	}
+	t.Definitions[name] = tmpl
+	return nil
+}
+
+func (t *Template) Helpers() (ret []*helpers.Helper) {
+	return t.WithPackHelpers(append(Helpers().Get(), helpers.PluginHelpers()...))
+}
+
+// New creates a new template utility for Jinja2 templates, which supports the infuse helpers as global functions and
+// filters.
+func New(name ...string) *Template {
+	jt := &Template{}
+	bt := &base.AbstractTemplate{
+		IAbstractTemplateMembers: jt,
+		NameStr:                  stringx.GetOrDefault("base", name...),
+		Definitions:              map[string]string{},
+	}
+	jt.AbstractTemplate = bt
+	return jt
+}
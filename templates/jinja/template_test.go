@@ -0,0 +1,215 @@
+package jinja
+
+import (
+	"strings"
+	"testing"
+)
+
+// render renders the template with the given definitions, which can be used by 'include', 'extends' and 'import'.
+func render(tmpl string, data interface{}, definitions map[string]string) (string, error) {
+	tpl := New("test")
+	for name, def := range definitions {
+		if err := tpl.LoadDefinition(name, def); err != nil {
+			return "", err
+		}
+	}
+	if err := tpl.LoadTemplate(tmpl); err != nil {
+		return "", err
+	}
+	out := &strings.Builder{}
+	err := tpl.Parse(out, data)
+	return out.String(), err
+}
+
+type renderTest struct {
+	name     string
+	tmpl     string
+	expected string
+}
+
+func runRenderTests(t *testing.T, tests []renderTest, definitions map[string]string) {
+	t.Helper()
+	data := map[string]interface{}{
+		"name":  "api",
+		"port":  8080,
+		"tags":  []interface{}{"b", "a", "c"},
+		"empty": []interface{}{},
+		"labels": map[interface{}]interface{}{
+			"tier": "backend",
+			"app":  "api",
+		},
+		"services": []interface{}{
+			map[string]interface{}{"name": "web", "port": 80, "enabled": true},
+			map[string]interface{}{"name": "db", "port": 5432, "enabled": false},
+			map[string]interface{}{"name": "cache", "port": 6379, "enabled": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := render(tt.tmpl, data, definitions)
+			if err != nil {
+				t.Fatalf("failed to render '%s', %v", tt.tmpl, err)
+			}
+			if actual != tt.expected {
+				t.Errorf("'%s' rendered '%s', expected '%s'", tt.tmpl, actual, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpressions(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"variable", `{{ name }}`, "api"},
+		{"attribute", `{{ labels.app }}`, "api"},
+		{"item", `{{ labels['tier'] }}`, "backend"},
+		{"index", `{{ tags[0] }} {{ tags[-1] }}`, "b c"},
+		{"slice", `{{ tags[1:] }}`, "['a', 'c']"},
+		{"arithmetic", `{{ 1 + 2 * 3 }} {{ (1 + 2) * 3 }} {{ 7 // 2 }} {{ 7 % 3 }} {{ 2 ** 10 }}`, "7 9 3 1 1024"},
+		{"division", `{{ 7 / 2 }}`, "3.5"},
+		{"concatenation", `{{ name ~ ':' ~ port }}`, "api:8080"},
+		{"comparison", `{{ port > 80 and port <= 8080 }}`, "True"},
+		{"not", `{{ not empty }}`, "True"},
+		{"in", `{{ 'a' in tags }} {{ 'x' not in tags }}`, "True True"},
+		{"conditional", `{{ 'yes' if port == 8080 else 'no' }}`, "yes"},
+		{"list literal", `{{ [1, 2] + [3] }}`, "[1, 2, 3]"},
+		{"dict literal", `{{ {'a': 1}['a'] }}`, "1"},
+		{"string method", `{{ name.upper() }}`, "API"},
+		{"undefined", `[{{ missing }}]`, "[]"},
+		{"test", `{{ name is string }} {{ missing is defined }} {{ port is divisibleby 8 }}`, "True False True"},
+		{"helper", `{{ semverBumpMinor('1.2.3') }}`, "1.3.0"},
+		{"range", `{{ range(3) | list }}`, "[0, 1, 2]"},
+	}, nil)
+}
+
+func TestFilters(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"upper", `{{ name | upper }}`, "API"},
+		{"chain", `{{ '  Api ' | trim | lower }}`, "api"},
+		{"default", `{{ missing | default('none') }} {{ '' | default('empty', true) }}`, "none empty"},
+		{"join", `{{ tags | sort | join(',') }}`, "a,b,c"},
+		{"length", `{{ tags | length }} {{ services | count }}`, "3 3"},
+		{"first last", `{{ tags | first }}{{ tags | last }}`, "bc"},
+		{"replace", `{{ 'a-b-c' | replace('-', '.') }}`, "a.b.c"},
+		{"map attribute", `{{ services | map(attribute='name') | join(' ') }}`, "web db cache"},
+		{"selectattr", `{{ services | selectattr('enabled') | map(attribute='name') | join(' ') }}`, "web cache"},
+		{"rejectattr", `{{ services | rejectattr('port', 'gt', 100) | map(attribute='name') | list }}`, "['web']"},
+		{"dictsort", `{% for k, v in labels | dictsort %}{{ k }}={{ v }} {% endfor %}`, "app=api tier=backend "},
+		{"int", `{{ '42' | int + 1 }}`, "43"},
+		{"round", `{{ 2.567 | round(2) }}`, "2.57"},
+		{"indent", "{{ 'a\nb' | indent(2) }}", "a\n  b"},
+		{"tojson", `{{ labels | tojson }}`, `{"app":"api","tier":"backend"}`},
+		{"format", `{{ '%s:%d' | format(name, port) }}`, "api:8080"},
+		{"helper filter", `{{ '1.2.3' | semverBumpMajor }}`, "2.0.0"},
+		{"filter block", `{% filter upper %}api{% endfilter %}`, "API"},
+	}, nil)
+}
+
+func TestLoops(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"for", `{% for t in tags %}{{ t }}{% endfor %}`, "bac"},
+		{"loop index", `{% for t in tags %}{{ loop.index }}{{ loop.index0 }}{{ loop.revindex }}{{ loop.revindex0 }} {% endfor %}`, "1032 2121 3210 "},
+		{"loop first last", `{% for t in tags %}{% if loop.first %}[{% endif %}{{ t }}{% if loop.last %}]{% else %},{% endif %}{% endfor %}`, "[b,a,c]"},
+		{"loop length", `{% for t in tags %}{{ loop.length }}{% endfor %}`, "333"},
+		{"loop cycle", `{% for t in tags %}{{ loop.cycle('odd', 'even') }} {% endfor %}`, "odd even odd "},
+		{"loop previtem nextitem", `{% for t in tags %}{{ loop.previtem | default('-') }}{{ loop.nextitem | default('-') }} {% endfor %}`, "-a bc a- "},
+		{"filtered loop", `{% for s in services if s.enabled %}{{ loop.index }}{{ s.name }} {% endfor %}`, "1web 2cache "},
+		{"else", `{% for t in empty %}{{ t }}{% else %}none{% endfor %}`, "none"},
+		{"unpacking", `{% for k, v in labels.items() | sort %}{{ k }}={{ v }};{% endfor %}`, "app=api;tier=backend;"},
+		{"nested loop", `{% for i in range(2) %}{% for j in range(2) %}{{ i }}{{ j }} {% endfor %}{% endfor %}`, "00 01 10 11 "},
+		{"set in loop", `{% set ns = namespace(total=0) %}{% for s in services %}{% set ns.total = ns.total + s.port %}{% endfor %}{{ ns.total }}`, "11891"},
+	}, nil)
+}
+
+func TestMacros(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"macro", `{% macro port(name, number) %}{{ name }}: {{ number }}{% endmacro %}{{ port('http', 80) }}`, "http: 80"},
+		{"default argument", `{% macro greet(who='world') %}hello {{ who }}{% endmacro %}{{ greet() }}, {{ greet('api') }}`, "hello world, hello api"},
+		{"keyword argument", `{% macro pair(a, b) %}{{ a }}{{ b }}{% endmacro %}{{ pair(b=2, a=1) }}`, "12"},
+		{"recursive macro", `{% macro count(n) %}{{ n }}{% if n > 0 %}{{ count(n - 1) }}{% endif %}{% endmacro %}{{ count(3) }}`, "3210"},
+		{"macro scope", `{% set x = 'global' %}{% macro show() %}{{ x }}{% endmacro %}{% for x in ['loop'] %}{{ show() }}{% endfor %}`, "global"},
+		{"import", `{% import 'macros.j2' as m %}{{ m.bold('api') }}`, "<b>api</b>"},
+		{"from import", `{% from 'macros.j2' import bold as b %}{{ b('api') }}`, "<b>api</b>"},
+	}, map[string]string{
+		"macros.j2": `{% macro bold(text) %}<b>{{ text }}</b>{% endmacro %}`,
+	})
+}
+
+func TestIncludeAndExtends(t *testing.T) {
+	definitions := map[string]string{
+		"header.j2": `[{{ name }}]`,
+		"base.j2":   `<{% block title %}base{% endblock %}|{% block body %}{% endblock %}>`,
+		"middle.j2": `{% extends 'base.j2' %}{% block title %}middle-{{ super() }}{% endblock %}`,
+	}
+	runRenderTests(t, []renderTest{
+		{"include", `{% include 'header.j2' %}`, "[api]"},
+		{"include context", `{% set name = 'local' %}{% include 'header.j2' %}`, "[local]"},
+		{"include without context", `{% set name = 'local' %}{% include 'header.j2' without context %}`, "[api]"},
+		{"include missing", `{% include 'missing.j2' ignore missing %}ok`, "ok"},
+		{"include list", `{% include ['missing.j2', 'header.j2'] %}`, "[api]"},
+		{"extends", `{% extends 'base.j2' %}{% block body %}{{ name }}{% endblock %}`, "<base|api>"},
+		{"super", `{% extends 'base.j2' %}{% block title %}{{ super() }}!{% endblock %}`, "<base!|>"},
+		{"multilevel", `{% extends 'middle.j2' %}{% block title %}top-{{ super() }}{% endblock %}`, "<top-middle-base|>"},
+	}, definitions)
+}
+
+func TestWhitespaceControl(t *testing.T) {
+	runRenderTests(t, []renderTest{
+		{"trim blocks", "{% if true %}\na\n{% endif %}\nb", "a\nb"},
+		{"trim left", "a  \n  {{- name }}", "aapi"},
+		{"trim right", "{{ name -}}  \n  b", "apib"},
+		{"trim statement", "<ul>\n  {%- for t in tags %}\n  <li>{{ t }}</li>\n  {%- endfor %}\n</ul>", "<ul>  <li>b</li>  <li>a</li>  <li>c</li></ul>"},
+		{"comment", "a{# comment #}\nb", "ab"},
+		{"raw", `{% raw %}{{ name }}{% endraw %}`, "{{ name }}"},
+	}, nil)
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		tmpl     string
+		expected string
+	}{
+		{"unclosed tag", "a\n{{ name", "line 2: unclosed tag '{{'"},
+		{"missing end", "{% if true %}\n{% for t in tags %}\na", "test:3: missing 'endfor'"},
+		{"unknown filter", "a\n\n{{ name | nope }}", "test:3: no filter named 'nope'"},
+		{"call undefined", "{{ nope() }}", "test:1: 'nope' is undefined"},
+		{"macro error", "{% macro m() %}{{ 1 | nope }}{% endmacro %}\n{{ m() }}", "test:2: macro 'm': test:1: no filter named 'nope'"},
+		{"template not found", `{% include 'missing.j2' %}`, "template 'missing.j2' not found"},
+		{"recursive macro", "{% macro f(n) %}{{ f(n + 1) }}{% endmacro %}\n\n{{ f(0) }}", "test:3: maximum recursion depth exceeded"},
+		{"recursive include", `{% include 'loop.j2' %}`, "maximum"},
+		{"recursive macro through include", "{% macro g() %}{% include 'g.j2' %}{% endmacro %}{{ g() }}", "test:1: maximum recursion depth exceeded"},
+	}
+	definitions := map[string]string{
+		"loop.j2": `{% include 'loop.j2' %}`,
+		"g.j2":    `{{ g() }}`,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := render(tt.tmpl, map[string]interface{}{"name": "api"}, definitions)
+			if err == nil {
+				t.Fatalf("expected '%s' to fail, rendered '%s'", tt.tmpl, actual)
+			}
+			if !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected the error of '%s' to contain '%s', got '%v'", tt.tmpl, tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestStrict(t *testing.T) {
+	tpl := New("test")
+	tpl.SetStrict(true)
+	if err := tpl.LoadTemplate("{{ missing }}"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tpl.Parse(&strings.Builder{}, map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "'missing' is undefined") {
+		t.Errorf("expected an undefined error, got '%v'", err)
+	}
+	if err := tpl.LoadTemplate("{{ missing is defined }}{{ missing | default('x') }}"); err != nil {
+		t.Fatal(err)
+	}
+	out := &strings.Builder{}
+	if err := tpl.Parse(out, map[string]interface{}{}); err != nil || out.String() != "Falsex" {
+		t.Errorf("expected the tests and default to accept undefined values, rendered '%s', %v", out.String(), err)
+	}
+}
@@ -0,0 +1,436 @@
+package jinja
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// undefined represents a value that does not exist in the context. Renders as an empty string unless the template
+// is strict, in which case using it in an output fails.
+type undefined struct {
+	name string
+}
+
+// markup is a string that has already been rendered, such as the output of a macro or a block.
+type markup string
+
+// callable is a function defined by the engine that can receive keyword arguments
+type callable func(args []interface{}, kwargs map[string]interface{}) (interface{}, error)
+
+func isUndefined(v interface{}) bool {
+	_, ok := v.(*undefined)
+	return ok
+}
+
+// truthy evaluates the value as a boolean following the Python semantics
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil, *undefined:
+		return false
+	case bool:
+		return x
+	case string:
+		return x != ""
+	case markup:
+		return x != ""
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return val.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return val.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return val.Float() != 0
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+		return val.Len() > 0
+	case reflect.Ptr, reflect.Interface:
+		return !val.IsNil()
+	}
+	return true
+}
+
+// toString converts a value to its string representation following the Python semantics
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "None"
+	case *undefined:
+		return ""
+	case string:
+		return x
+	case markup:
+		return string(x)
+	case bool:
+		if x {
+			return "True"
+		}
+		return "False"
+	case float32:
+		return formatFloat(float64(x))
+	case float64:
+		return formatFloat(x)
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return repr(v)
+	}
+	return fmt.Sprint(v)
+}
+
+func formatFloat(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e16 {
+		return strconv.FormatFloat(f, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// repr returns the Python representation of a value, used when rendering lists and dictionaries
+func repr(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return "'" + strings.Replace(x, "'", "\\'", -1) + "'"
+	case markup:
+		return repr(string(x))
+	case nil:
+		return "None"
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]string, val.Len())
+		for i := range items {
+			items[i] = repr(val.Index(i).Interface())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		keys := sortedKeys(val)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = repr(k.Interface()) + ": " + repr(val.MapIndex(k).Interface())
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return toString(v)
+}
+
+func sortedKeys(val reflect.Value) []reflect.Value {
+	keys := val.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return compare(keys[i].Interface(), keys[j].Interface()) < 0
+	})
+	return keys
+}
+
+// toNumber converts the value to an int or a float64. Returns false if the value is not numeric.
+func toNumber(v interface{}) (interface{}, bool) {
+	switch x := v.(type) {
+	case int:
+		return x, true
+	case float64:
+		return x, true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(val.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return val.Float(), true
+	}
+	return nil, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	n, ok := toNumber(v)
+	if !ok {
+		return 0, false
+	}
+	if i, isInt := n.(int); isInt {
+		return float64(i), true
+	}
+	return n.(float64), true
+}
+
+func toInt(v interface{}) (int, bool) {
+	n, ok := toNumber(v)
+	if !ok {
+		return 0, false
+	}
+	if i, isInt := n.(int); isInt {
+		return i, true
+	}
+	return int(n.(float64)), true
+}
+
+// compare compares two values, returns a negative number if a < b, zero if a == b and a positive number if a > b
+func compare(a, b interface{}) int {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			fx, _ := toFloat(x)
+			fy, _ := toFloat(y)
+			switch {
+			case fx < fy:
+				return -1
+			case fx > fy:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(toString(a), toString(b))
+}
+
+// equals indicates whether two values are equal, numbers are compared by value regardless of their type
+func equals(a, b interface{}) bool {
+	if isUndefined(a) || isUndefined(b) {
+		return isUndefined(a) && isUndefined(b)
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	_, aIsBool := a.(bool)
+	_, bIsBool := b.(bool)
+	if aIsBool != bIsBool {
+		return false
+	}
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			fx, _ := toFloat(x)
+			fy, _ := toFloat(y)
+			return fx == fy
+		}
+		return false
+	}
+	if sa, ok := asString(a); ok {
+		sb, ok := asString(b)
+		return ok && sa == sb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func asString(v interface{}) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case markup:
+		return string(x), true
+	}
+	return "", false
+}
+
+// toList converts an iterable value into a list. Iterating a map returns its keys, iterating a string returns its
+// characters.
+func toList(v interface{}) ([]interface{}, error) {
+	switch x := v.(type) {
+	case nil, *undefined:
+		return nil, nil
+	case []interface{}:
+		return x, nil
+	case string:
+		ret := []interface{}{}
+		for _, r := range x {
+			ret = append(ret, string(r))
+		}
+		return ret, nil
+	case markup:
+		return toList(string(x))
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		ret := make([]interface{}, val.Len())
+		for i := range ret {
+			ret[i] = val.Index(i).Interface()
+		}
+		return ret, nil
+	case reflect.Map:
+		keys := sortedKeys(val)
+		ret := make([]interface{}, len(keys))
+		for i, k := range keys {
+			ret[i] = k.Interface()
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("value of type %T is not iterable", v)
+}
+
+// getAttr resolves an attribute or an item of the given object, falling back to the methods of the object. Returns an
+// undefined value if not found.
+func getAttr(obj interface{}, key interface{}) interface{} {
+	if v := getItem(obj, key); !isUndefined(v) {
+		return v
+	}
+	if name, ok := key.(string); ok && obj != nil && !isUndefined(obj) {
+		if m := reflect.ValueOf(obj).MethodByName(name); m.IsValid() {
+			return m.Interface()
+		}
+		if method := builtinMethod(obj, name); method != nil {
+			return method
+		}
+	}
+	return &undefined{name: toString(key)}
+}
+
+// getItem resolves a map key, a sequence index or a struct field of the given object. Returns an undefined value if
+// not found.
+func getItem(obj interface{}, key interface{}) interface{} {
+	if obj == nil || isUndefined(obj) {
+		return &undefined{name: toString(key)}
+	}
+
+	val := reflect.ValueOf(obj)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return &undefined{name: toString(key)}
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Map:
+		keyVal := reflect.ValueOf(key)
+		if val.Type().Key().Kind() == reflect.String {
+			keyVal = reflect.ValueOf(toString(key)).Convert(val.Type().Key())
+		} else if !keyVal.IsValid() || !keyVal.Type().AssignableTo(val.Type().Key()) {
+			return &undefined{name: toString(key)}
+		}
+		if item := val.MapIndex(keyVal); item.IsValid() {
+			return item.Interface()
+		}
+	case reflect.Slice, reflect.Array, reflect.String:
+		index, ok := key.(int)
+		if !ok {
+			if s, isStr := key.(string); isStr {
+				if index, ok = atoi(s); !ok {
+					break
+				}
+			} else {
+				break
+			}
+		}
+		if index < 0 {
+			index += val.Len()
+		}
+		if index >= 0 && index < val.Len() {
+			if val.Kind() == reflect.String {
+				return string(val.String()[index])
+			}
+			return val.Index(index).Interface()
+		}
+	case reflect.Struct:
+		if name, ok := key.(string); ok {
+			if f := val.FieldByName(name); f.IsValid() && f.CanInterface() {
+				return f.Interface()
+			}
+		}
+	}
+	return &undefined{name: toString(key)}
+}
+
+func atoi(s string) (int, bool) {
+	i, err := strconv.Atoi(s)
+	return i, err == nil
+}
+
+// builtinMethod returns the Python-like methods available for dictionaries and strings, such as dict.items() or
+// str.upper()
+func builtinMethod(obj interface{}, name string) callable {
+	if str, ok := asString(obj); ok {
+		switch name {
+		case "upper":
+			return func([]interface{}, map[string]interface{}) (interface{}, error) { return strings.ToUpper(str), nil }
+		case "lower":
+			return func([]interface{}, map[string]interface{}) (interface{}, error) { return strings.ToLower(str), nil }
+		case "strip":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) > 0 {
+					return strings.Trim(str, toString(args[0])), nil
+				}
+				return strings.TrimSpace(str), nil
+			}
+		case "split":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				var parts []string
+				if len(args) > 0 && args[0] != nil {
+					parts = strings.Split(str, toString(args[0]))
+				} else {
+					parts = strings.Fields(str)
+				}
+				ret := make([]interface{}, len(parts))
+				for i, p := range parts {
+					ret[i] = p
+				}
+				return ret, nil
+			}
+		case "startswith":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				return len(args) > 0 && strings.HasPrefix(str, toString(args[0])), nil
+			}
+		case "endswith":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				return len(args) > 0 && strings.HasSuffix(str, toString(args[0])), nil
+			}
+		case "replace":
+			return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+				if len(args) < 2 {
+					return nil, fmt.Errorf("replace expects 2 arguments")
+				}
+				return strings.Replace(str, toString(args[0]), toString(args[1]), -1), nil
+			}
+		}
+		return nil
+	}
+
+	val := reflect.ValueOf(obj)
+	if val.Kind() != reflect.Map {
+		return nil
+	}
+	switch name {
+	case "items":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) {
+			var ret []interface{}
+			for _, k := range sortedKeys(val) {
+				ret = append(ret, []interface{}{k.Interface(), val.MapIndex(k).Interface()})
+			}
+			return ret, nil
+		}
+	case "keys":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) {
+			return toList(obj)
+		}
+	case "values":
+		return func([]interface{}, map[string]interface{}) (interface{}, error) {
+			var ret []interface{}
+			for _, k := range sortedKeys(val) {
+				ret = append(ret, val.MapIndex(k).Interface())
+			}
+			return ret, nil
+		}
+	case "get":
+		return func(args []interface{}, _ map[string]interface{}) (interface{}, error) {
+			if len(args) == 0 {
+				return nil, fmt.Errorf("get expects at least 1 argument")
+			}
+			if v := getAttr(obj, args[0]); !isUndefined(v) {
+				return v, nil
+			}
+			if len(args) > 1 {
+				return args[1], nil
+			}
+			return nil, nil
+		}
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package templates
 
 import (
 	"github.com/jucardi/infuse/templates/helpers"
+	"github.com/jucardi/infuse/util/library"
 	"io"
+	"io/fs"
 )
 
 // IFactory represents the available functions of the templates factory
@@ -52,6 +54,17 @@ type ITemplate interface {
 	// LoadFileDefinitionsByPattern uses a pattern to find the file definitions to be loaded for the template parsing
 	LoadFileDefinitionsByPattern(pattern string) error
 
+	// LoadFileDefinitionsByPatterns loads the file definitions matching any of the given patterns. Patterns support '**'
+	// to match any number of directories, and patterns starting with '!' exclude the files they match.
+	LoadFileDefinitionsByPatterns(patterns ...string) error
+
+	// LoadFileDefinitionsDir loads every file inside the given directory recursively as a definition, named by its
+	// path relative to the directory.
+	LoadFileDefinitionsDir(dir string) error
+
+	// LoadLibrary loads the definitions of the given library, named 'libname/def'.
+	LoadLibrary(lib *library.Library) error
+
 	// LoadFileDefinition loads a file(s) as definition(s) to be used for 'template' directives
 	LoadFileDefinition(files ...string) error
 
@@ -60,6 +73,26 @@ type ITemplate interface {
 
 	// Helpers returns the list of helpers that have been registered to this template
 	Helpers() []*helpers.Helper
+
+	// EnableHelpers enables the given helper packs for the template, e.g: 'sprig'. The helpers of the packs override
+	// the helpers by the same name.
+	EnableHelpers(packs ...string) error
+
+	// SetStrict indicates whether the template should fail when a value referenced by the template is missing from the data.
+	SetStrict(strict bool)
+
+	// SetStrictDefinitions indicates whether loading two definitions by the same name from different files should fail.
+	SetStrictDefinitions(strict bool)
+
+	// SetDefinitionsRoot sets the directory used to name the definitions loaded from files by their relative path.
+	SetDefinitionsRoot(dir string)
+
+	// SetFS sets the file system the template and definition files are loaded from. The OS file system is used if nil.
+	SetFS(fsys fs.FS)
+
+	// SetDelims sets the action delimiters to the specified strings. An empty delimiter stands for the corresponding
+	// default. Returns an error if the template type does not support custom delimiters.
+	SetDelims(left, right string) error
 }
 
 type baseTemplate struct {
@@ -0,0 +1,90 @@
+package ioutils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileMarkerFormat is the format of the line that indicates the beginning of a new output file in a multi-document
+// render. Since it starts with a YAML document separator, the unsplit output remains a valid multi-document YAML.
+const FileMarkerFormat = "--- # file: %s"
+
+// fileMarkerRegex captures the path up to the end of the line, without the surrounding spaces, so paths may contain
+// spaces. E.g: '--- # file: my dir/a.yaml'
+var fileMarkerRegex = regexp.MustCompile(`(?m)^---[ \t]*#[ \t]*file:[ \t]*(\S(?:[^\r\n]*\S)?)[ \t]*\r?\n?`)
+
+// Document represents a single file contained in a multi-document render
+type Document struct {
+	Path     string
+	Contents string
+}
+
+// FileMarker returns the marker line that indicates that the contents that follow belong to the given path.
+func FileMarker(path string) string {
+	return fmt.Sprintf(FileMarkerFormat, path) + "\n"
+}
+
+// HasDocuments indicates whether the given contents contain at least one file marker.
+func HasDocuments(contents string) bool {
+	return fileMarkerRegex.MatchString(contents)
+}
+
+// SplitDocuments splits the given contents by the file markers it contains. Returns the contents found before the
+// first marker as the preamble, and the list of documents in the order they were found.
+func SplitDocuments(contents string) (preamble string, docs []Document) {
+	matches := fileMarkerRegex.FindAllStringSubmatchIndex(contents, -1)
+	if len(matches) == 0 {
+		return contents, nil
+	}
+
+	preamble = contents[:matches[0][0]]
+	for i, m := range matches {
+		end := len(contents)
+		if i < len(matches)-1 {
+			end = matches[i+1][0]
+		}
+		docs = append(docs, Document{
+			Path:     contents[m[2]:m[3]],
+			Contents: contents[m[1]:end],
+		})
+	}
+	return
+}
+
+// WriteDocuments writes each document to its path relative to the given directory, creating any missing parent
+// directories. Paths that resolve outside of the directory are rejected.
+func WriteDocuments(dir string, docs []Document) error {
+	for _, doc := range docs {
+		target, err := resolveDocumentPath(dir, doc.Path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("unable to create directory for '%s', %v", doc.Path, err)
+		}
+		w, err := NewFileWriter(target)
+		if err != nil {
+			return fmt.Errorf("unable to open file '%s', %v", target, err)
+		}
+		_, err = w.Write([]byte(doc.Contents))
+		_ = w.Close()
+		if err != nil {
+			return fmt.Errorf("unable to write file '%s', %v", target, err)
+		}
+	}
+	return nil
+}
+
+func resolveDocumentPath(dir, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return "", fmt.Errorf("invalid document path '%s', must be relative to the output directory", path)
+	}
+	target := filepath.Join(dir, filepath.FromSlash(path))
+	if rel, err := filepath.Rel(dir, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid document path '%s', resolves outside of the output directory", path)
+	}
+	return target, nil
+}
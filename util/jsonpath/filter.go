@@ -0,0 +1,333 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+/** In this file are defined the filter expressions, e.g: [?(@.enabled && @.port >= 8000)] */
+
+// result is the value of an expression, which may be missing when a path does not match.
+type result struct {
+	value interface{}
+	found bool
+}
+
+type expr interface {
+	eval(current, root interface{}) result
+}
+
+type literalExpr struct {
+	value interface{}
+}
+
+func (e literalExpr) eval(_, _ interface{}) result {
+	return result{value: e.value, found: true}
+}
+
+// pathExpr is a path relative to the current element '@' or to the root '$'. Its value is the first match.
+type pathExpr struct {
+	relative bool
+	segments []segment
+}
+
+func (e pathExpr) eval(current, root interface{}) result {
+	start := root
+	if e.relative {
+		start = current
+	}
+	matches := evalSegments(e.segments, start, root)
+	if len(matches) == 0 {
+		return result{}
+	}
+	return result{value: matches[0], found: true}
+}
+
+type notExpr struct {
+	expr expr
+}
+
+func (e notExpr) eval(current, root interface{}) result {
+	return result{value: !truthy(e.expr.eval(current, root)), found: true}
+}
+
+type logicalExpr struct {
+	and         bool
+	left, right expr
+}
+
+func (e logicalExpr) eval(current, root interface{}) result {
+	left := truthy(e.left.eval(current, root))
+	if left != e.and {
+		return result{value: left, found: true}
+	}
+	return result{value: truthy(e.right.eval(current, root)), found: true}
+}
+
+type compareExpr struct {
+	op          string
+	left, right expr
+	re          *regexp.Regexp
+}
+
+func (e compareExpr) eval(current, root interface{}) result {
+	left, right := e.left.eval(current, root), e.right.eval(current, root)
+	return result{value: compare(e.op, left, right, e.re), found: true}
+}
+
+// compare applies the comparison operator to the values. Numbers are compared by value regardless of their type and
+// strings lexicographically. Values of different types are only different, and missing values are only equal to other
+// missing values.
+func compare(op string, left, right result, re *regexp.Regexp) bool {
+	if op == "=~" {
+		s, ok := left.value.(string)
+		if !left.found || !ok {
+			return false
+		}
+		if re == nil {
+			pattern, ok := right.value.(string)
+			if !right.found || !ok {
+				return false
+			}
+			var err error
+			if re, err = regexp.Compile(pattern); err != nil {
+				return false
+			}
+		}
+		return re.MatchString(s)
+	}
+
+	if !left.found || !right.found {
+		return (op == "==") == (left.found == right.found)
+	}
+	cmp, ordered := 0, false
+	if x, ok := number(left.value); ok {
+		if y, ok := number(right.value); ok {
+			cmp, ordered = x.Cmp(y), true
+		}
+	}
+	if x, ok := left.value.(string); ok {
+		if y, ok := right.value.(string); ok {
+			cmp, ordered = strings.Compare(x, y), true
+		}
+	}
+	if !ordered {
+		equal := reflect.DeepEqual(left.value, right.value)
+		return op == "==" && equal || op == "!=" && !equal
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// truthy indicates whether the result is found and is not null, false, zero or an empty string, so a path like
+// [?(@.enabled)] matches the elements where 'enabled' is set to a true value.
+func truthy(r result) bool {
+	if !r.found || r.value == nil {
+		return false
+	}
+	switch v := r.value.(type) {
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	if n, ok := number(r.value); ok {
+		return n.Sign() != 0
+	}
+	return true
+}
+
+// number returns the value as an exact number if it is a number, including json.Number but not strings.
+func number(v interface{}) (*big.Rat, bool) {
+	if n, ok := v.(json.Number); ok {
+		return new(big.Rat).SetString(n.String())
+	}
+	val := reflect.ValueOf(v)
+	switch val.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return new(big.Rat).SetInt64(val.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Rat).SetUint64(val.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return new(big.Rat).SetString(fmt.Sprint(val.Float()))
+	}
+	return nil, false
+}
+
+/** Parsing */
+
+var comparisonOperators = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+// filterExpr parses the expression of a filter selector, with or without parentheses. E.g: ?(@.a > 1) or ?@.a > 1
+func (p *pathParser) filterExpr() (expr, error) {
+	p.skipSpaces()
+	return p.or()
+}
+
+func (p *pathParser) or() (expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpaces(); p.consume("||"); p.skipSpaces() {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *pathParser) and() (expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.skipSpaces(); p.consume("&&"); p.skipSpaces() {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *pathParser) unary() (expr, error) {
+	p.skipSpaces()
+	if p.peek() == '!' && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		e, err := p.unary()
+		return notExpr{expr: e}, err
+	}
+	return p.comparison()
+}
+
+func (p *pathParser) comparison() (expr, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	for _, op := range comparisonOperators {
+		if !p.consume(op) {
+			continue
+		}
+		p.skipSpaces()
+		ret := compareExpr{op: op, left: left}
+		if op == "=~" && p.peek() == '/' {
+			ret.re, err = p.regex()
+			ret.right = literalExpr{}
+			return ret, err
+		}
+		if ret.right, err = p.operand(); err != nil {
+			return nil, err
+		}
+		if lit, ok := ret.right.(literalExpr); ok && op == "=~" {
+			pattern, _ := lit.value.(string)
+			if ret.re, err = regexp.Compile(pattern); err != nil {
+				return nil, p.errorf("invalid regular expression, %v", err)
+			}
+		}
+		return ret, nil
+	}
+	return left, nil
+}
+
+func (p *pathParser) operand() (expr, error) {
+	p.skipSpaces()
+	switch c := p.peek(); {
+	case c == '(':
+		p.pos++
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return e, nil
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.segments(false)
+		return pathExpr{relative: c == '@', segments: segments}, err
+	case c == '\'' || c == '"':
+		s, err := p.quoted()
+		return literalExpr{value: s}, err
+	case c == '-' || c >= '0' && c <= '9':
+		return p.numberLiteral()
+	}
+	for _, lit := range []struct {
+		word  string
+		value interface{}
+	}{{"true", true}, {"false", false}, {"null", nil}} {
+		if p.consume(lit.word) {
+			return literalExpr{value: lit.value}, nil
+		}
+	}
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of the filter")
+	}
+	return nil, p.errorf("unexpected '%c' in the filter", p.peek())
+}
+
+func (p *pathParser) numberLiteral() (expr, error) {
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte("+-.eE0123456789", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	n := json.Number(p.src[start:p.pos])
+	if _, err := n.Float64(); err != nil {
+		p.pos = start
+		return nil, p.errorf("invalid number '%s'", n)
+	}
+	return literalExpr{value: n}, nil
+}
+
+// regex parses a regular expression literal, e.g: /^api-.*$/i, where 'i' makes it case insensitive.
+func (p *pathParser) regex() (*regexp.Regexp, error) {
+	var b strings.Builder
+	for i := p.pos + 1; i < len(p.src); i++ {
+		c := p.src[i]
+		if c == '\\' && i+1 < len(p.src) && p.src[i+1] == '/' {
+			b.WriteByte('/')
+			i++
+			continue
+		}
+		if c != '/' {
+			b.WriteByte(c)
+			continue
+		}
+		p.pos = i + 1
+		pattern := b.String()
+		if p.consume("i") {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, p.errorf("invalid regular expression, %v", err)
+		}
+		return re, nil
+	}
+	return nil, p.errorf("unterminated regular expression")
+}
@@ -0,0 +1,496 @@
+package jsonpath
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Path is a compiled JSONPath expression, which can be evaluated against the data decoded from JSON or YAML.
+//
+// Supports the root '$', child names as '.name' or ['name'], wildcards as '.*' or [*], recursive descent as '..name'
+// or '..*', indexes and negative indexes as [0] or [-1], unions as [0,2] or ['a','b'], slices as [start:end:step] and
+// filters as [?(@.port > 8000 && @.name =~ /^api/)].
+//
+// Expressions starting with '.' are evaluated as jq paths, where [] iterates over the elements. E.g: '.services[].name'
+type Path struct {
+	expr     string
+	segments []segment
+}
+
+// Compile parses a JSONPath expression, or a jq path if it starts with '.'.
+func Compile(expr string) (*Path, error) {
+	p := &pathParser{src: strings.TrimSpace(expr)}
+	segments, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid path '%s', %v", expr, err)
+	}
+	return &Path{expr: expr, segments: segments}, nil
+}
+
+// Query evaluates the expression against the given data and returns the matching values.
+func Query(expr string, data interface{}) ([]interface{}, error) {
+	path, err := Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return path.Query(data), nil
+}
+
+// String returns the source expression of the path.
+func (p *Path) String() string {
+	return p.expr
+}
+
+// Query returns the values matched by the path in the given data, in document order. The entries of maps are visited
+// sorted by key. Returns an empty list if nothing matches.
+func (p *Path) Query(data interface{}) []interface{} {
+	return evalSegments(p.segments, data, data)
+}
+
+func evalSegments(segments []segment, current, root interface{}) []interface{} {
+	nodes := []interface{}{current}
+	for _, seg := range segments {
+		var next []interface{}
+		for _, node := range nodes {
+			if seg.descendant {
+				for _, d := range descendants(node, nil) {
+					next = seg.apply(d, root, next)
+				}
+			} else {
+				next = seg.apply(node, root, next)
+			}
+		}
+		nodes = next
+	}
+	if nodes == nil {
+		return []interface{}{}
+	}
+	return nodes
+}
+
+/** Segments */
+
+type segment struct {
+	selectors  []selector
+	descendant bool
+}
+
+func (s segment) apply(node, root interface{}, out []interface{}) []interface{} {
+	for _, sel := range s.selectors {
+		out = sel.selectFrom(node, root, out)
+	}
+	return out
+}
+
+type selector interface {
+	selectFrom(node, root interface{}, out []interface{}) []interface{}
+}
+
+type nameSelector string
+
+func (s nameSelector) selectFrom(node, _ interface{}, out []interface{}) []interface{} {
+	if val, ok := child(node, string(s)); ok {
+		out = append(out, val)
+	}
+	return out
+}
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) selectFrom(node, _ interface{}, out []interface{}) []interface{} {
+	return append(out, children(node)...)
+}
+
+type indexSelector int
+
+func (s indexSelector) selectFrom(node, _ interface{}, out []interface{}) []interface{} {
+	val, ok := list(node)
+	if !ok {
+		return out
+	}
+	i := int(s)
+	if i < 0 {
+		i += val.Len()
+	}
+	if i >= 0 && i < val.Len() {
+		out = append(out, val.Index(i).Interface())
+	}
+	return out
+}
+
+type sliceSelector struct {
+	start, end *int
+	step       int
+}
+
+func (s sliceSelector) selectFrom(node, _ interface{}, out []interface{}) []interface{} {
+	val, ok := list(node)
+	if !ok || s.step == 0 {
+		return out
+	}
+	length := val.Len()
+	bound := func(i *int, def int) int {
+		if i == nil {
+			return def
+		}
+		n := *i
+		if n < 0 {
+			n += length
+		}
+		return n
+	}
+	if s.step > 0 {
+		start, end := clamp(bound(s.start, 0), 0, length), clamp(bound(s.end, length), 0, length)
+		for i := start; i < end; i += s.step {
+			out = append(out, val.Index(i).Interface())
+		}
+	} else {
+		start, end := clamp(bound(s.start, length-1), -1, length-1), clamp(bound(s.end, -length-1), -1, length-1)
+		for i := start; i > end; i += s.step {
+			out = append(out, val.Index(i).Interface())
+		}
+	}
+	return out
+}
+
+type filterSelector struct {
+	expr expr
+}
+
+func (s filterSelector) selectFrom(node, root interface{}, out []interface{}) []interface{} {
+	for _, item := range children(node) {
+		if truthy(s.expr.eval(item, root)) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func clamp(n, min, max int) int {
+	if n < min {
+		return min
+	}
+	if n > max {
+		return max
+	}
+	return n
+}
+
+/** Data access */
+
+// value returns the value of the node, dereferencing pointers and interfaces.
+func value(node interface{}) reflect.Value {
+	val := reflect.ValueOf(node)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		val = val.Elem()
+	}
+	return val
+}
+
+func list(node interface{}) (reflect.Value, bool) {
+	val := value(node)
+	if kind := val.Kind(); kind == reflect.Slice || kind == reflect.Array {
+		return val, true
+	}
+	return val, false
+}
+
+// child returns the entry of a map by the given key, or the exported field of a struct by the given name.
+func child(node interface{}, name string) (interface{}, bool) {
+	val := value(node)
+	switch val.Kind() {
+	case reflect.Map:
+		if key := reflect.ValueOf(name); key.Type().ConvertibleTo(val.Type().Key()) {
+			if item := val.MapIndex(key.Convert(val.Type().Key())); item.IsValid() {
+				return item.Interface(), true
+			}
+		}
+		// The keys of YAML maps are not always strings, e.g: numbers
+		for _, k := range val.MapKeys() {
+			if fmt.Sprint(k.Interface()) == name {
+				return val.MapIndex(k).Interface(), true
+			}
+		}
+	case reflect.Struct:
+		if field, ok := val.Type().FieldByName(name); ok && field.PkgPath == "" {
+			return val.FieldByIndex(field.Index).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// children returns the elements of a list, the values of a map sorted by key or the exported fields of a struct.
+func children(node interface{}) []interface{} {
+	var ret []interface{}
+	val := value(node)
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			ret = append(ret, val.Index(i).Interface())
+		}
+	case reflect.Map:
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			ret = append(ret, val.MapIndex(k).Interface())
+		}
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			if val.Type().Field(i).PkgPath == "" {
+				ret = append(ret, val.Field(i).Interface())
+			}
+		}
+	}
+	return ret
+}
+
+// descendants returns the node followed by all of its descendants, in document order.
+func descendants(node interface{}, out []interface{}) []interface{} {
+	out = append(out, node)
+	for _, c := range children(node) {
+		out = descendants(c, out)
+	}
+	return out
+}
+
+/** Parsing */
+
+type pathParser struct {
+	src string
+	pos int
+}
+
+func (p *pathParser) parse() ([]segment, error) {
+	if p.src == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	jq := p.peek() == '.'
+	if !jq && !p.consume("$") {
+		return nil, fmt.Errorf("the path must start with '$', or with '.' for a jq path")
+	}
+	segments, err := p.segments(jq)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected '%c'", p.src[p.pos])
+	}
+	return segments, nil
+}
+
+// segments parses the segments that follow the root of a path, until a character which cannot continue the path.
+func (p *pathParser) segments(jq bool) ([]segment, error) {
+	var ret []segment
+	for p.pos < len(p.src) {
+		var (
+			seg segment
+			err error
+		)
+		switch {
+		case p.consume(".."):
+			seg.descendant = true
+			if p.peek() == '[' {
+				seg.selectors, err = p.bracket(jq)
+			} else {
+				seg.selectors, err = p.dotSelector(false)
+			}
+		case p.consume("."):
+			if jq && (p.pos == len(p.src) || p.peek() == '[') {
+				// '.' is the identity in jq, e.g: '.' or '.[0]'
+				continue
+			}
+			seg.selectors, err = p.dotSelector(jq)
+		case p.peek() == '[':
+			seg.selectors, err = p.bracket(jq)
+		default:
+			return ret, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if jq {
+			// Optional paths in jq, e.g: '.a?', do not fail when missing, as all paths here
+			p.consume("?")
+		}
+		ret = append(ret, seg)
+	}
+	return ret, nil
+}
+
+func (p *pathParser) dotSelector(jq bool) ([]selector, error) {
+	if p.consume("*") {
+		return []selector{wildcardSelector{}}, nil
+	}
+	if jq && (p.peek() == '"') {
+		name, err := p.quoted()
+		return []selector{nameSelector(name)}, err
+	}
+	name := p.name()
+	if name == "" {
+		return nil, p.errorf("expected a name")
+	}
+	return []selector{nameSelector(name)}, nil
+}
+
+func (p *pathParser) bracket(jq bool) ([]selector, error) {
+	p.consume("[")
+	p.skipSpaces()
+	if jq && p.consume("]") {
+		return []selector{wildcardSelector{}}, nil
+	}
+	var ret []selector
+	for {
+		p.skipSpaces()
+		sel, err := p.bracketSelector()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, sel)
+		p.skipSpaces()
+		if p.consume("]") {
+			return ret, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected ',' or ']'")
+		}
+	}
+}
+
+func (p *pathParser) bracketSelector() (selector, error) {
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		return wildcardSelector{}, nil
+	case c == '\'' || c == '"':
+		name, err := p.quoted()
+		return nameSelector(name), err
+	case c == '?':
+		p.pos++
+		e, err := p.filterExpr()
+		return filterSelector{expr: e}, err
+	}
+
+	var bounds [3]*int
+	n := 0
+	for ; n < 3; n++ {
+		p.skipSpaces()
+		if i, ok := p.integer(); ok {
+			bounds[n] = &i
+		}
+		p.skipSpaces()
+		if !p.consume(":") {
+			break
+		}
+	}
+	switch {
+	case n == 0 && bounds[0] != nil:
+		return indexSelector(*bounds[0]), nil
+	case n == 0 || n == 3:
+		return nil, p.errorf("expected an index, a slice, a quoted name, '*' or a filter")
+	}
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	return sliceSelector{start: bounds[0], end: bounds[1], step: step}, nil
+}
+
+func (p *pathParser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c != '_' && c != '-' && c != '$' && c < 0x80 && !isAlphanumeric(c) {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *pathParser) integer() (int, bool) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	i, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		p.pos = start
+		return 0, false
+	}
+	return i, true
+}
+
+// quoted parses a string quoted by single or double quotes, with the escape sequences of JSON strings.
+func (p *pathParser) quoted() (string, error) {
+	quote := p.src[p.pos]
+	var b strings.Builder
+	for i := p.pos + 1; i < len(p.src); i++ {
+		c := p.src[i]
+		switch {
+		case c == quote:
+			p.pos = i + 1
+			return b.String(), nil
+		case c == '\\' && i+1 < len(p.src):
+			i++
+			switch e := p.src[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'u':
+				if i+4 < len(p.src) {
+					if r, err := strconv.ParseUint(p.src[i+1:i+5], 16, 32); err == nil {
+						b.WriteRune(rune(r))
+						i += 4
+						continue
+					}
+				}
+				return "", p.errorf("invalid unicode escape sequence")
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *pathParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *pathParser) consume(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.pos+1)
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
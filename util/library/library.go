@@ -0,0 +1,289 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jucardi/infuse/config"
+	"github.com/jucardi/infuse/util/loader"
+	"github.com/jucardi/infuse/util/log"
+	"gopkg.in/yaml.v2"
+)
+
+// ManifestFile is the name of the file at the root of a library that describes it
+const ManifestFile = "library.yaml"
+
+var (
+	loaded = map[string]*Library{}
+	mutex  = sync.Mutex{}
+)
+
+// Library is a bundle of definitions and default values that can be shared across projects. A library is a directory,
+// or a tar or zip archive, with a library.yaml manifest at its root. Its definitions are named 'libname/def', where
+// 'def' is the path of the definition relative to the library directory.
+type Library struct {
+	// Name is the name of the library, used as the prefix of the names of its definitions
+	Name string `yaml:"name"`
+
+	// Version is the version of the library. Required for archives, which are cached by name and version
+	Version string `yaml:"version"`
+
+	// Description is a short description of the library
+	Description string `yaml:"description"`
+
+	// Definitions are the search patterns of the files loaded as definitions, relative to the library directory.
+	// If not set, every file of the library other than the manifest is loaded as a definition
+	Definitions []string `yaml:"definitions"`
+
+	// Defaults are the default values of the data, overridden by the data provided for the template
+	Defaults map[string]interface{} `yaml:"defaults"`
+
+	// Dir is the directory where the library files are located
+	Dir string `yaml:"-"`
+}
+
+// Load loads a library from a directory, a tar or zip archive, or a reference to a library in the cache in the form
+// 'name' or 'name@version'. Archives are extracted to the cache directory by the name and version declared in their
+// manifest, so they can be referenced by name afterwards. If the version is not specified, the latest cached version
+// is used. Archives are extracted again only if they were modified since they were last loaded by the process.
+func Load(ref string) (*Library, error) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	stat, statErr := os.Stat(ref)
+	if statErr == nil && stat.IsDir() {
+		lib, err := loadDir(ref)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load library '%s', %v", ref, err)
+		}
+		return lib, nil
+	}
+
+	key := ref
+	if statErr == nil {
+		key = fmt.Sprintf("%s@%d", ref, stat.ModTime().UnixNano())
+	}
+	if lib, ok := loaded[key]; ok {
+		return lib, nil
+	}
+
+	var (
+		lib *Library
+		err error
+	)
+	if statErr == nil {
+		lib, err = loadArchive(ref)
+	} else {
+		lib, err = loadCached(ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to load library '%s', %v", ref, err)
+	}
+	log.Debugf("Loaded library %s %s from %s", lib.Name, lib.Version, lib.Dir)
+	loaded[key] = lib
+	return lib, nil
+}
+
+// CacheDir returns the directory where libraries extracted from archives are cached. Defaults to 'infuse/libs' inside
+// the user cache directory.
+func CacheDir() string {
+	if dir := config.Get().LibCache; dir != "" {
+		return dir
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "infuse", "libs")
+}
+
+// LoadDefinitions loads the definition files of the library and returns a map of path,value.
+func (l *Library) LoadDefinitions() (map[string]string, error) {
+	if len(l.Definitions) == 0 {
+		ret, err := loader.LoadTemplateDir(l.Dir)
+		if err != nil {
+			return nil, err
+		}
+		delete(ret, filepath.Join(l.Dir, ManifestFile))
+		return ret, nil
+	}
+
+	var patterns []string
+	for _, p := range l.Definitions {
+		if strings.HasPrefix(p, "!") {
+			patterns = append(patterns, "!"+filepath.Join(l.Dir, strings.TrimPrefix(p, "!")))
+		} else {
+			patterns = append(patterns, filepath.Join(l.Dir, p))
+		}
+	}
+	return loader.LoadTemplateFiles(patterns...)
+}
+
+// DefinitionName returns the name of a definition file of the library, 'libname/def', where 'def' is the path of the
+// file relative to the library directory.
+func (l *Library) DefinitionName(path string) string {
+	return l.Name + "/" + loader.DefinitionName(l.Dir, path)
+}
+
+func loadDir(dir string) (*Library, error) {
+	lib := &Library{}
+	contents, err := ioutil.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(contents, lib); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s, %v", ManifestFile, err)
+	}
+	if lib.Name == "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+		lib.Name = filepath.Base(abs)
+	}
+	if err := validateSegment("name", lib.Name); err != nil || strings.Contains(lib.Name, "@") {
+		return nil, fmt.Errorf("invalid library name '%s'", lib.Name)
+	}
+	if lib.Version != "" {
+		if err := validateSegment("version", lib.Version); err != nil {
+			return nil, err
+		}
+	}
+	lib.Dir = dir
+	return lib, nil
+}
+
+// loadArchive extracts the archive to the cache directory, by the name and version declared in its manifest.
+func loadArchive(file string) (*Library, error) {
+	cache := CacheDir()
+	if err := os.MkdirAll(cache, 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempDir(cache, ".extract-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	if err := extract(file, tmp); err != nil {
+		return nil, err
+	}
+
+	root := archiveRoot(tmp)
+	lib, err := loadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(root, ManifestFile)); err != nil {
+		return nil, fmt.Errorf("%s not found in the archive", ManifestFile)
+	}
+	if lib.Version == "" {
+		return nil, errors.New("the version of the library is required for archives")
+	}
+
+	target := filepath.Join(cache, lib.Name, lib.Version)
+	if rel, err := filepath.Rel(cache, target); err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("invalid library '%s@%s', its directory is outside of the cache", lib.Name, lib.Version)
+	}
+	if err := os.RemoveAll(target); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(root, target); err != nil {
+		return nil, err
+	}
+	lib.Dir = target
+	return lib, nil
+}
+
+// archiveRoot returns the directory of the extracted archive that contains the manifest, which is either the
+// extraction directory or its only subdirectory, for archives that wrap the library in a directory.
+func archiveRoot(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+		return dir
+	}
+	items, err := ioutil.ReadDir(dir)
+	if err != nil || len(items) != 1 || !items[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, items[0].Name())
+}
+
+// loadCached loads a library from the cache by a reference in the form 'name' or 'name@version'.
+func loadCached(ref string) (*Library, error) {
+	name, version := ref, ""
+	if i := strings.LastIndex(ref, "@"); i > 0 {
+		name, version = ref[:i], ref[i+1:]
+	}
+	if validateSegment("name", name) != nil {
+		return nil, errors.New("no such file or directory")
+	}
+	if version != "" {
+		if err := validateSegment("version", version); err != nil {
+			return nil, err
+		}
+	}
+
+	dir := filepath.Join(CacheDir(), name)
+	if version == "" {
+		items, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("library not found in the cache '%s'", CacheDir())
+		}
+		var versions []string
+		for _, item := range items {
+			if item.IsDir() {
+				versions = append(versions, item.Name())
+			}
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("library not found in the cache '%s'", CacheDir())
+		}
+		sort.Slice(versions, func(i, j int) bool { return compareVersions(versions[i], versions[j]) < 0 })
+		version = versions[len(versions)-1]
+	}
+
+	dir = filepath.Join(dir, version)
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("version '%s' not found in the cache '%s'", version, CacheDir())
+	}
+	return loadDir(dir)
+}
+
+// validateSegment fails if the name or version of a library cannot be used as a directory of the cache, since it is
+// empty, '.' or '..', or contains a path separator.
+func validateSegment(kind, value string) error {
+	if value == "" || value == "." || value == ".." || strings.ContainsAny(value, "/\\"+string(filepath.Separator)) {
+		return fmt.Errorf("invalid library %s '%s'", kind, value)
+	}
+	return nil
+}
+
+// compareVersions compares two versions by their dot separated segments, numerically when both segments are numbers.
+func compareVersions(a, b string) int {
+	sa := strings.Split(strings.TrimPrefix(a, "v"), ".")
+	sb := strings.Split(strings.TrimPrefix(b, "v"), ".")
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		na, errA := strconv.Atoi(sa[i])
+		nb, errB := strconv.Atoi(sb[i])
+		switch {
+		case errA == nil && errB == nil && na != nb:
+			if na < nb {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && sa[i] != sb[i]:
+			return strings.Compare(sa[i], sb[i])
+		}
+	}
+	return len(sa) - len(sb)
+}
@@ -0,0 +1,126 @@
+package loader
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Glob returns the names of all files matching the pattern. In addition to the syntax supported by filepath.Glob, a
+// '**' path segment matches any number of directories, e.g: 'templates/**/*.tmpl'.
+func Glob(pattern string) ([]string, error) {
+	return GlobFS(nil, pattern)
+}
+
+// GlobFS returns the names of all files of the file system matching the pattern, using the syntax supported by Glob.
+// The OS file system is used if the file system is nil.
+func GlobFS(fsys fs.FS, pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		if fsys == nil {
+			return filepath.Glob(pattern)
+		}
+		return fs.Glob(fsys, pattern)
+	}
+	if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	err := walk(fsys, PatternBase(pattern), func(p string, _ fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if Match(pattern, p) {
+			ret = append(ret, p)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// Match reports whether the name matches the pattern, using the syntax supported by Glob.
+func Match(pattern, name string) bool {
+	return matchSegments(splitPath(pattern), splitPath(name))
+}
+
+// MatchPatterns reports whether the name matches any of the patterns and none of the exclusion patterns, the ones
+// starting with '!'.
+func MatchPatterns(patterns []string, name string) bool {
+	if excluded(name, patterns) {
+		return false
+	}
+	for _, p := range patterns {
+		if p != "" && !strings.HasPrefix(p, "!") && Match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// PatternBase returns the directory at the start of the pattern that contains no wildcards, which is the directory
+// where the search for the files matching the pattern starts.
+func PatternBase(pattern string) string {
+	var base []string
+	for _, segment := range strings.Split(filepath.ToSlash(pattern), "/") {
+		if strings.ContainsAny(segment, "*?[\\") {
+			break
+		}
+		base = append(base, segment)
+	}
+	if len(base) == 0 {
+		return "."
+	}
+	if ret := strings.Join(base, "/"); ret != "" {
+		return filepath.FromSlash(ret)
+	}
+	return "/"
+}
+
+func splitPath(p string) []string {
+	p = filepath.ToSlash(filepath.Clean(p))
+	if p == "." {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// excluded indicates whether the file matches any of the exclusion patterns, the ones starting with '!'. Exclusion
+// patterns without a path separator are matched against the file name.
+func excluded(file string, patterns []string) bool {
+	for _, p := range patterns {
+		if !strings.HasPrefix(p, "!") {
+			continue
+		}
+		p = strings.TrimPrefix(p, "!")
+		if Match(p, file) || (!strings.ContainsAny(p, "/\\") && Match(p, filepath.Base(file))) {
+			return true
+		}
+	}
+	return false
+}
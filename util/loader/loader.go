@@ -1,52 +1,208 @@
 package loader
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
-	"github.com/jucardi/infuse/util/log"
+	log "github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
-// LoadTemplates loads multiple template files and returns a map of filename,value
+// LoadTemplates loads multiple template files and returns a map of filename,value. If two of the matched files have the
+// same name, the last one in lexical order is kept. The templates check the names of the definitions they load for
+// collisions instead, warning about them or failing if strict.
 func LoadTemplates(searchArg string) (map[string]string, error) {
-	log.Debug(" <-- loadtemplates entry")
-	ret := map[string]string{}
-	matches, err := filepath.Glob(searchArg)
-
+	files, err := LoadTemplateFiles(searchArg)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, f := range matches {
-		inf, err := os.Stat(f)
+	ret := map[string]string{}
+	for _, f := range sortedKeys(files) {
+		ret[filepath.Base(f)] = files[f]
+	}
+	return ret, nil
+}
 
-		if err != nil {
-			return nil, fmt.Errorf("unable to read '%s'", f)
-		} else if inf.IsDir() {
+// LoadTemplateFiles loads the template files that match the search patterns and returns a map of path,value. Patterns
+// support '**' to match any number of directories, and patterns starting with '!' exclude the files they match.
+func LoadTemplateFiles(patterns ...string) (map[string]string, error) {
+	return LoadTemplateFilesFS(nil, patterns...)
+}
+
+// LoadTemplateFilesFS loads the template files of the file system that match the search patterns and returns a map of
+// path,value. The OS file system is used if the file system is nil.
+func LoadTemplateFilesFS(fsys fs.FS, patterns ...string) (map[string]string, error) {
+	log.Debug(" <-- loadtemplates entry")
+	ret := map[string]string{}
+
+	for _, pattern := range patterns {
+		if pattern == "" || strings.HasPrefix(pattern, "!") {
 			continue
 		}
+		matches, err := GlobFS(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
 
-		if str, err := LoadTemplate(f); err != nil {
-			return nil, fmt.Errorf("failed to load '%s'", f)
-		} else {
-			ret[inf.Name()] = str
+		for _, f := range matches {
+			if excluded(f, patterns) {
+				continue
+			}
+			inf, err := Stat(fsys, f)
+
+			if err != nil {
+				return nil, fmt.Errorf("unable to read '%s'", f)
+			} else if inf.IsDir() {
+				continue
+			}
+
+			if str, err := LoadTemplateFS(fsys, f); err != nil {
+				return nil, fmt.Errorf("failed to load '%s'", f)
+			} else {
+				ret[f] = str
+			}
 		}
 	}
 
 	return ret, nil
 }
 
+// LoadTemplateDir loads every file inside the given directory recursively, and returns a map of path,value. Hidden
+// files and directories are skipped.
+func LoadTemplateDir(dir string) (map[string]string, error) {
+	return LoadTemplateDirFS(nil, dir)
+}
+
+// LoadTemplateDirFS loads every file inside the given directory of the file system recursively, and returns a map of
+// path,value. The OS file system is used if the file system is nil.
+func LoadTemplateDirFS(fsys fs.FS, dir string) (map[string]string, error) {
+	ret := map[string]string{}
+	err := walk(fsys, dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		str, err := LoadTemplateFS(fsys, path)
+		if err != nil {
+			return fmt.Errorf("failed to load '%s'", path)
+		}
+		ret[path] = str
+		return nil
+	})
+	return ret, err
+}
+
+// DefinitionArg splits a definition argument in the form 'name=path' into the alias and the path of the definition.
+// The alias is empty if the argument is a plain path, or if a file exists at the full argument.
+func DefinitionArg(arg string) (alias, path string) {
+	return DefinitionArgFS(nil, arg)
+}
+
+// DefinitionArgFS splits a definition argument in the form 'name=path' into the alias and the path of the definition,
+// looking up the full argument in the file system. The OS file system is used if the file system is nil.
+func DefinitionArgFS(fsys fs.FS, arg string) (alias, path string) {
+	i := strings.Index(arg, "=")
+	if i <= 0 {
+		return "", arg
+	}
+	if _, err := Stat(fsys, arg); err == nil {
+		return "", arg
+	}
+	return arg[:i], arg[i+1:]
+}
+
+// DefinitionName returns the name of a definition file, which is its path relative to the root directory using
+// forward slashes (e.g: 'global/mongo.tmpl'), or its base name if the root is not set or the file is outside of it.
+func DefinitionName(root, path string) string {
+	if root != "" {
+		absRoot, rootErr := filepath.Abs(root)
+		absPath, pathErr := filepath.Abs(path)
+		if rootErr == nil && pathErr == nil {
+			if rel, err := filepath.Rel(absRoot, absPath); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return filepath.ToSlash(rel)
+			}
+		}
+	}
+	split := strings.Split(path, "/")
+	split = strings.Split(split[len(split)-1], "\\")
+	return split[len(split)-1]
+}
+
+// sortedKeys returns the keys of the provided map, sorted.
+func sortedKeys(m map[string]string) []string {
+	var ret []string
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
 // LoadTemplate loads a file template
 func LoadTemplate(filename string) (string, error) {
+	return LoadTemplateFS(nil, filename)
+}
+
+// LoadTemplateFS loads a template file from the file system. The OS file system is used if the file system is nil.
+func LoadTemplateFS(fsys fs.FS, filename string) (string, error) {
 	log.Debug(" <-- loadtemplate entry")
-	bs, err := ioutil.ReadFile(filename)
+	var (
+		bs  []byte
+		err error
+	)
+	if fsys == nil {
+		bs, err = ioutil.ReadFile(filename)
+	} else {
+		bs, err = fs.ReadFile(fsys, filename)
+	}
 	return string(bs), err
 }
 
+// Stat returns the file info of the file from the file system. The OS file system is used if the file system is nil.
+func Stat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if fsys == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(fsys, name)
+}
+
+// ReadDir reads the directory from the file system and returns its entries sorted by name. The OS file system is used
+// if the file system is nil.
+func ReadDir(fsys fs.FS, name string) ([]fs.DirEntry, error) {
+	if fsys == nil {
+		return os.ReadDir(name)
+	}
+	return fs.ReadDir(fsys, name)
+}
+
+func walk(fsys fs.FS, root string, fn fs.WalkDirFunc) error {
+	if fsys == nil {
+		return filepath.WalkDir(root, fn)
+	}
+	return fs.WalkDir(fsys, root, fn)
+}
+
+// JSONNumbers indicates whether the numbers of the JSON data are decoded as json.Number instead of float64, which
+// preserves the precision of large integers and decimals.
+var JSONNumbers = false
+
 // LoadMarshaled attempts to unmarshal the byte data provided to a map[string]interface{}, first will try to unmarshal as JSON and if fails will attempt to unmarshall as YAML
 func LoadMarshaled(data []byte) (map[string]interface{}, error) {
 	ret, jsonErr := LoadJSON(data)
@@ -63,7 +219,16 @@ func LoadMarshaled(data []byte) (map[string]interface{}, error) {
 // LoadJSON attempts to unmarshall the byte data provided representing a JSON object to a map[string]interface{}
 func LoadJSON(data []byte) (ret map[string]interface{}, err error) {
 	ret = map[string]interface{}{}
-	err = json.Unmarshal(data, &ret)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if JSONNumbers {
+		decoder.UseNumber()
+	}
+	if err = decoder.Decode(&ret); err != nil {
+		return
+	}
+	if _, extra := decoder.Token(); extra != io.EOF {
+		err = fmt.Errorf("unexpected data after the top-level JSON value")
+	}
 	return
 }
 
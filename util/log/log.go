@@ -26,6 +26,16 @@ func Infof(format string, args ...interface{}) {
 	logrus.Infof(format, args...)
 }
 
+// Warn logs a message at the warning level in the standard logrus logger
+func Warn(args ...interface{}) {
+	logrus.Warn(args...)
+}
+
+// Warnf logs a message at the warning level in the standard logrus logger
+func Warnf(format string, args ...interface{}) {
+	logrus.Warnf(format, args...)
+}
+
 // Error logs a message at the error level in the standard logrus logger
 func Error(args ...interface{}) {
 	logrus.Error(args...)
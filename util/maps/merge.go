@@ -0,0 +1,94 @@
+package maps
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Merge deep merges the source map into the destination map. The values of the source replace the values of the
+// destination, except for nested maps present in both, which are merged, and null values, which remove the key from the
+// destination. Supports both map[string]interface{} and the map[interface{}]interface{} decoded from YAML.
+func Merge(dest interface{}, source interface{}) {
+	merge(dest, source, true)
+}
+
+// MergeMissing deep merges the source map into the destination map like Merge, but only sets the keys missing from the
+// destination, so the existing values are kept.
+func MergeMissing(dest interface{}, source interface{}) {
+	merge(dest, source, false)
+}
+
+// DeepCopy returns a deep copy of the maps and lists of the given value, so it can be merged with other values without
+// modifying the original.
+func DeepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			ret[k] = DeepCopy(item)
+		}
+		return ret
+	case map[interface{}]interface{}:
+		ret := make(map[interface{}]interface{}, len(v))
+		for k, item := range v {
+			ret[k] = DeepCopy(item)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for i, item := range v {
+			ret[i] = DeepCopy(item)
+		}
+		return ret
+	}
+	return value
+}
+
+func merge(dest interface{}, source interface{}, overwrite bool) {
+	dVal, ok := dest.(reflect.Value)
+	if !ok {
+		dVal = reflect.ValueOf(dest)
+	}
+	sVal, ok := source.(reflect.Value)
+	if !ok {
+		sVal = reflect.ValueOf(source)
+	}
+
+	if !sVal.IsValid() || !dVal.IsValid() || sVal.Type().Kind() != reflect.Map || dVal.Type().Kind() != reflect.Map {
+		return
+	}
+
+	for _, k := range sVal.MapKeys() {
+		val := reflect.ValueOf(sVal.MapIndex(k).Interface())
+		key := mapKey(k, dVal.Type().Key())
+		target := dVal.MapIndex(key)
+		if target.IsValid() {
+			target = reflect.ValueOf(target.Interface())
+		}
+		exists := target.IsValid()
+
+		if exists && (val.Kind() == reflect.Map || val.Kind() == reflect.Struct) {
+			merge(target, val, overwrite)
+		} else if !exists || overwrite {
+			if !val.IsValid() && !overwrite {
+				val = reflect.Zero(dVal.Type().Elem())
+			}
+			// Setting a null value when overwriting removes the key
+			dVal.SetMapIndex(key, val)
+		}
+	}
+}
+
+// mapKey converts the key of a map to the key type of another map, e.g. the keys of a YAML map to string.
+func mapKey(key reflect.Value, keyType reflect.Type) reflect.Value {
+	if key.Kind() == reflect.Interface {
+		key = key.Elem()
+	}
+	if key.Type().AssignableTo(keyType) {
+		return key
+	}
+	if keyType.Kind() == reflect.String {
+		return reflect.ValueOf(fmt.Sprint(key.Interface())).Convert(keyType)
+	}
+	return key.Convert(keyType)
+}
@@ -0,0 +1,160 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultInterval is the default polling interval
+	DefaultInterval = 500 * time.Millisecond
+
+	// DefaultDebounce is the default amount of time to wait for a burst of changes to settle before notifying.
+	DefaultDebounce = 200 * time.Millisecond
+)
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watcher monitors files, directories (recursively) and glob patterns for changes by polling the filesystem. Polling
+// does not depend on OS specific notification APIs, so it works the same way on every platform and filesystem.
+type Watcher struct {
+	// Interval is the time between each scan of the watched paths
+	Interval time.Duration
+
+	// Debounce is the time without new changes the watcher waits for before notifying, so bursts of events (e.g. an
+	// editor saving multiple files) result in a single notification.
+	Debounce time.Duration
+
+	// Ignore, if set, is used to discard paths that should not be considered as changes.
+	Ignore func(path string) bool
+
+	paths    []string
+	snapshot map[string]fileState
+}
+
+// New creates a new polling watcher with the default interval and debounce.
+func New(paths ...string) *Watcher {
+	w := &Watcher{
+		Interval: DefaultInterval,
+		Debounce: DefaultDebounce,
+	}
+	w.Add(paths...)
+	return w
+}
+
+// Add adds paths to be watched. A path may be a file, a directory, which is watched recursively, or a glob pattern,
+// in which case files that start matching the pattern are also detected.
+func (w *Watcher) Add(paths ...string) {
+	for _, p := range paths {
+		if p != "" {
+			w.paths = append(w.paths, p)
+		}
+	}
+	w.snapshot = nil
+}
+
+// Changes scans the watched paths and returns the sorted list of paths that were created, modified or removed since
+// the previous scan. The first invocation only takes the initial snapshot and reports no changes.
+func (w *Watcher) Changes() []string {
+	current := w.scan()
+	previous := w.snapshot
+	w.snapshot = current
+
+	if previous == nil {
+		return nil
+	}
+
+	var changed []string
+	for path, state := range current {
+		if prev, ok := previous[path]; !ok || !prev.modTime.Equal(state.modTime) || prev.size != state.size {
+			changed = append(changed, path)
+		}
+	}
+	for path := range previous {
+		if _, ok := current[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Watch polls the watched paths until the stop channel is closed, invoking the callback with the accumulated changes
+// once no new changes have been detected for the debounce period.
+func (w *Watcher) Watch(stop <-chan struct{}, callback func(changed []string)) {
+	w.Changes()
+
+	ticker := time.NewTicker(w.interval())
+	defer ticker.Stop()
+
+	pending := map[string]bool{}
+	var lastChange time.Time
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			for _, p := range w.Changes() {
+				pending[p] = true
+				lastChange = now
+			}
+			if len(pending) == 0 || now.Sub(lastChange) < w.Debounce {
+				continue
+			}
+			changed := make([]string, 0, len(pending))
+			for p := range pending {
+				changed = append(changed, p)
+			}
+			sort.Strings(changed)
+			pending = map[string]bool{}
+			callback(changed)
+		}
+	}
+}
+
+func (w *Watcher) interval() time.Duration {
+	if w.Interval <= 0 {
+		return DefaultInterval
+	}
+	return w.Interval
+}
+
+func (w *Watcher) scan() map[string]fileState {
+	ret := map[string]fileState{}
+	for _, p := range w.paths {
+		matches := []string{p}
+		if isPattern(p) {
+			matches, _ = filepath.Glob(p)
+		}
+		for _, m := range matches {
+			_ = filepath.Walk(m, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return nil
+				}
+				if w.Ignore != nil && w.Ignore(path) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if info.IsDir() {
+					return nil
+				}
+				ret[filepath.Clean(path)] = fileState{modTime: info.ModTime(), size: info.Size()}
+				return nil
+			})
+		}
+	}
+	return ret
+}
+
+func isPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
@@ -0,0 +1,108 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChanges(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data.yaml")
+	nested := filepath.Join(dir, "templates", "a.tmpl")
+	writeFile(t, file, "a: 1")
+	writeFile(t, nested, "{{ .a }}")
+
+	w := New(dir)
+	if changed := w.Changes(); changed != nil {
+		t.Fatalf("expected no changes on the initial scan, got %v", changed)
+	}
+	if changed := w.Changes(); len(changed) != 0 {
+		t.Fatalf("expected no changes without writes, got %v", changed)
+	}
+
+	writeFile(t, file, "a: 12")
+	created := filepath.Join(dir, "templates", "b.tmpl")
+	writeFile(t, created, "{{ .b }}")
+	if changed, expected := w.Changes(), []string{file, created}; !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("expected the modified and created files %v, got %v", expected, changed)
+	}
+
+	if err := os.Remove(nested); err != nil {
+		t.Fatal(err)
+	}
+	if changed, expected := w.Changes(), []string{nested}; !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("expected the removed file %v, got %v", expected, changed)
+	}
+}
+
+func TestChangesPatternAndIgnore(t *testing.T) {
+	dir := t.TempDir()
+	w := New(filepath.Join(dir, "*.yaml"))
+	w.Ignore = func(path string) bool { return strings.HasSuffix(path, "ignored.yaml") }
+	w.Changes()
+
+	matching := filepath.Join(dir, "new.yaml")
+	writeFile(t, matching, "a: 1")
+	writeFile(t, filepath.Join(dir, "ignored.yaml"), "a: 1")
+	writeFile(t, filepath.Join(dir, "other.json"), "{}")
+	if changed, expected := w.Changes(), []string{matching}; !reflect.DeepEqual(changed, expected) {
+		t.Fatalf("expected only the file matching the pattern %v, got %v", expected, changed)
+	}
+}
+
+func TestWatchDebounce(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.yaml"), filepath.Join(dir, "b.yaml")
+	writeFile(t, first, "a: 1")
+
+	w := New(dir)
+	w.Interval = 10 * time.Millisecond
+	w.Debounce = 100 * time.Millisecond
+
+	notifications := make(chan []string, 10)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		w.Watch(stop, func(changed []string) { notifications <- changed })
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	// Waits for the initial snapshot, then writes a burst of changes shorter than the debounce period
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, first, "a: 12")
+	time.Sleep(30 * time.Millisecond)
+	writeFile(t, second, "b: 1")
+
+	select {
+	case changed := <-notifications:
+		if expected := []string{first, second}; !reflect.DeepEqual(changed, expected) {
+			t.Fatalf("expected a single notification with %v, got %v", expected, changed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a notification after the changes")
+	}
+
+	select {
+	case changed := <-notifications:
+		t.Fatalf("expected no more notifications, got %v", changed)
+	case <-time.After(200 * time.Millisecond):
+	}
+}